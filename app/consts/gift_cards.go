@@ -0,0 +1,12 @@
+package consts
+
+const (
+	ProductTypeStandard = "standard"
+	ProductTypeGiftCard = "gift_card"
+)
+
+const (
+	GiftCardStatusActive = "active"
+	GiftCardStatusRedeemed = "redeemed"
+	GiftCardStatusDisabled = "disabled"
+)
@@ -0,0 +1,13 @@
+package consts
+
+const (
+	ImportJobStatusQueued    = "queued"
+	ImportJobStatusRunning   = "running"
+	ImportJobStatusCompleted = "completed"
+	ImportJobStatusFailed    = "failed"
+)
+
+const (
+	ImportActionCreate = "create"
+	ImportActionUpdate = "update"
+)
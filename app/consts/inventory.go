@@ -0,0 +1,18 @@
+package consts
+
+// alasan pergerakan stok di ledger (StockMovement.Reason)
+const (
+	StockReasonSale         = "sale"
+	StockReasonCancellation = "cancellation"
+	StockReasonReturn       = "return"
+	StockReasonAdjustment   = "adjustment"
+	StockReasonImport       = "import"
+	StockReasonTransfer     = "transfer"
+)
+
+// jenis referensi pergerakan stok (StockMovement.ReferenceType)
+const (
+	StockReferenceOrder         = "order"
+	StockReferenceImportJob     = "import_job"
+	StockReferenceStockTransfer = "stock_transfer"
+)
@@ -18,3 +18,15 @@ const (
 	PaymentStatusSettlement = "settlement"
 )
 
+const (
+	PaymentMethodMidtrans = "midtrans"
+	PaymentMethodCOD = "cod"
+	PaymentMethodWallet = "wallet"
+	PaymentMethodGiftCard = "gift_card"
+)
+
+const (
+	PaymentTypeCOD = "cod"
+	PaymentTypeWallet = "wallet"
+	PaymentTypeGiftCard = "gift_card"
+)
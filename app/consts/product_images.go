@@ -0,0 +1,8 @@
+package consts
+
+const (
+	ProductImageStatusPending    = "pending"
+	ProductImageStatusProcessing = "processing"
+	ProductImageStatusReady      = "ready"
+	ProductImageStatusFailed     = "failed"
+)
@@ -0,0 +1,8 @@
+package consts
+
+// Product.Status; nilai 0 adalah draft supaya produk baru tidak langsung tampil
+const (
+	ProductStatusDraft     = 0
+	ProductStatusPublished = 1
+	ProductStatusArchived  = 2
+)
@@ -0,0 +1,11 @@
+package consts
+
+const (
+	ShipmentStatusLabelCreated   = "label_created"
+	ShipmentStatusPickedUp       = "picked_up"
+	ShipmentStatusInTransit      = "in_transit"
+	ShipmentStatusOutForDelivery = "out_for_delivery"
+	ShipmentStatusDelivered      = "delivered"
+	ShipmentStatusFailedDelivery = "failed_delivery"
+	ShipmentStatusReturned       = "returned"
+)
@@ -0,0 +1,15 @@
+package consts
+
+const (
+	ShippingModeAPI   = "api"
+	ShippingModeRules = "rules"
+	ShippingModeBoth  = "both"
+)
+
+const (
+	ShippingMethodTypeFlat        = "flat"
+	ShippingMethodTypeWeightTable = "weight_table"
+	ShippingMethodTypeFree        = "free"
+	ShippingMethodTypeLocal       = "local"
+	ShippingMethodTypeSurcharge   = "surcharge"
+)
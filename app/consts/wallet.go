@@ -0,0 +1,10 @@
+package consts
+
+const (
+	WalletReasonRefund = "refund"
+	WalletReasonGoodwill = "goodwill"
+	WalletReasonPromotion = "promotion"
+	WalletReasonCheckout = "checkout"
+	WalletReasonAdjustment = "adjustment"
+	WalletReasonOrderCancelled = "order_cancelled"
+)
@@ -3,12 +3,11 @@ package controllers
 import (
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"io"
 	"net/http"
 	"os"
-	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -16,8 +15,6 @@ import (
 	"github.com/codeuiprogramming/e-commerce/app/consts"
 	"github.com/codeuiprogramming/e-commerce/app/helpers"
 	"github.com/codeuiprogramming/e-commerce/app/models"
-	"github.com/disintegration/imaging"
-	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/shopspring/decimal"
 	"github.com/unrolled/render"
@@ -78,7 +75,7 @@ func (server *Server) AdminProducts(w http.ResponseWriter, r *http.Request) {
     render := newAdminRender()
 
     var products []models.Product
-    server.DB.Preload("ProductImages").Where("is_temporary = ?", false).Order("created_at desc").Find(&products)
+    server.DB.Preload("ProductImages", models.OrderedProductImages).Where("is_temporary = ?", false).Order("created_at desc").Find(&products)
 
     data := server.DefaultRenderData(w, r, map[string]interface{}{"products": products})
     _ = render.HTML(w, http.StatusOK, "admin/products", data)
@@ -87,7 +84,7 @@ func (server *Server) AdminProducts(w http.ResponseWriter, r *http.Request) {
 // AdminProductNew shows form
 func (server *Server) AdminProductNew(w http.ResponseWriter, r *http.Request) {
     render := newAdminRender()
-    data := server.DefaultRenderData(w, r, map[string]interface{}{})
+    data := server.DefaultRenderData(w, r, map[string]interface{}{"productStatuses": models.ProductStatusNames})
     _ = render.HTML(w, http.StatusOK, "admin/product_form", data)
 }
 
@@ -110,14 +107,30 @@ func (server *Server) AdminProductCreate(w http.ResponseWriter, r *http.Request)
     price, _ := strconv.ParseFloat(priceStr, 64)
     stock, _ := strconv.Atoi(stockStr)
 
+    // produk baru default draft
+    status, publishAt, unpublishAt, err := productPublishingFromForm(r, &models.Product{})
+    if err != nil {
+        SetFlash(w, r, "error", err.Error())
+        http.Redirect(w, r, "/admin/products/new", http.StatusSeeOther)
+        return
+    }
+
     p := models.Product{
         ID:               "",
         Name:             name,
+        Slug:             r.FormValue("slug"),
         Price:            decimal.NewFromFloat(price),
         Stock:            stock,
         ShortDescription: r.FormValue("short_description"),
         Description:      r.FormValue("description"),
     }
+    measurements := productMeasurementsFromForm(r)
+    p.Weight = measurements["weight"]
+    p.Length = measurements["length"]
+    p.Width = measurements["width"]
+    p.Height = measurements["height"]
+    p.Position, _ = strconv.Atoi(r.FormValue("position"))
+    p.LowStockThreshold = lowStockThresholdFromForm(r)
 
     // If no user is associated, assign the first available user as owner
     if p.UserID == "" {
@@ -131,7 +144,7 @@ func (server *Server) AdminProductCreate(w http.ResponseWriter, r *http.Request)
     }
 
     // create
-    if err := server.DB.Create(&p).Error; err != nil {
+    if err := models.CreateProduct(server.DB, &p); err != nil {
         fmt.Println("AdminProductCreate create error:", err)
         // persist error for offline inspection
         persistError(err)
@@ -139,6 +152,18 @@ func (server *Server) AdminProductCreate(w http.ResponseWriter, r *http.Request)
         http.Redirect(w, r, "/admin/products/new", http.StatusSeeOther)
         return
     }
+    initial := models.StockChange{Reason: consts.StockReasonAdjustment}
+    if admin := server.CurrentUser(w, r); admin != nil {
+        initial.Actor = admin.ID
+    }
+    if err := models.RecordInitialStock(server.DB, &p, initial); err != nil {
+        persistError(err)
+    }
+    if err := p.UpdatePublishing(server.DB, status, publishAt, unpublishAt); err != nil {
+        SetFlash(w, r, "error", "Produk dibuat sebagai draft, jadwal gagal disimpan: "+err.Error())
+        http.Redirect(w, r, "/admin/products/"+p.ID+"/edit", http.StatusSeeOther)
+        return
+    }
 
     SetFlash(w, r, "success", "Produk berhasil dibuat")
     http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
@@ -155,7 +180,7 @@ func (server *Server) AdminProductEdit(w http.ResponseWriter, r *http.Request) {
         http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
         return
     }
-    data := server.DefaultRenderData(w, r, map[string]interface{}{"product": p})
+    data := server.DefaultRenderData(w, r, map[string]interface{}{"product": &p, "productStatuses": models.ProductStatusNames})
     _ = render.HTML(w, http.StatusOK, "admin/product_form", data)
 }
 
@@ -174,17 +199,80 @@ func (server *Server) AdminProductUpdate(w http.ResponseWriter, r *http.Request)
     price, _ := strconv.ParseFloat(priceStr, 64)
     stock, _ := strconv.Atoi(stockStr)
 
-    updates := map[string]interface{}{"name": name, "stock": stock}
+    updates := map[string]interface{}{"name": name}
     updates["price"] = decimal.NewFromFloat(price)
+    updates["low_stock_threshold"] = lowStockThresholdFromForm(r)
+    for column, value := range productMeasurementsFromForm(r) {
+        updates[column] = value
+    }
+    if position, err := strconv.Atoi(r.FormValue("position")); err == nil {
+        updates["position"] = position
+    }
 
     if err := server.DB.Model(&models.Product{}).Where("id = ?", id).Updates(updates).Error; err != nil {
         SetFlash(w, r, "error", "Gagal memperbarui produk")
+    } else if err := server.changeProductSlug(id, r.FormValue("slug")); err != nil {
+        SetFlash(w, r, "error", "Gagal mengubah slug: "+err.Error())
+    } else if err := server.changeProductStock(w, r, id, stockStr, stock); err != nil {
+        SetFlash(w, r, "error", "Gagal mengubah stok: "+err.Error())
+    } else if err := server.changeProductPublishing(r, id); err != nil {
+        SetFlash(w, r, "error", "Gagal mengubah status: "+err.Error())
     } else {
         SetFlash(w, r, "success", "Produk diperbarui")
     }
     http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
 }
 
+// changeProductSlug mengganti slug produk bila diisi; slug kosong dibiarkan.
+func (server *Server) changeProductSlug(id string, newSlug string) error {
+    if strings.TrimSpace(newSlug) == "" {
+        return nil
+    }
+    productModel := models.Product{}
+    product, err := productModel.FindByID(server.DB, id)
+    if err != nil {
+        return err
+    }
+    return product.ChangeSlug(server.DB, newSlug)
+}
+
+// changeProductStock mencatat perubahan stok dari form sebagai penyesuaian di
+// ledger; stok kosong dibiarkan.
+func (server *Server) changeProductStock(w http.ResponseWriter, r *http.Request, id string, stockStr string, stock int) error {
+    if strings.TrimSpace(stockStr) == "" {
+        return nil
+    }
+    change := models.StockChange{ProductID: id, Reason: consts.StockReasonAdjustment, Note: "edit produk"}
+    if admin := server.CurrentUser(w, r); admin != nil {
+        change.Actor = admin.ID
+    }
+    _, err := models.SetProductStock(server.DB, stock, change)
+    return err
+}
+
+// lowStockThresholdFromForm membaca low_stock_threshold; kosong berarti default.
+func lowStockThresholdFromForm(r *http.Request) *int {
+    value, err := strconv.Atoi(strings.TrimSpace(r.FormValue("low_stock_threshold")))
+    if err != nil || value < 0 {
+        return nil
+    }
+    return &value
+}
+
+// changeProductPublishing menyimpan status dan jadwal dari form edit produk.
+func (server *Server) changeProductPublishing(r *http.Request, id string) error {
+    productModel := models.Product{}
+    product, err := productModel.FindByID(server.DB, id)
+    if err != nil {
+        return err
+    }
+    status, publishAt, unpublishAt, err := productPublishingFromForm(r, &product)
+    if err != nil {
+        return err
+    }
+    return product.UpdatePublishing(server.DB, status, publishAt, unpublishAt)
+}
+
 // AdminProductDelete deletes a product
 func (server *Server) AdminProductDelete(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
@@ -216,13 +304,17 @@ func (server *Server) AdminOrderDetail(w http.ResponseWriter, r *http.Request) {
     render := newAdminRender()
     vars := mux.Vars(r)
     id := vars["id"]
-    var ord models.Order
-    if _, err := ord.FindByID(server.DB, id); err != nil {
+    orderModel := models.Order{}
+    ord, err := orderModel.FindByID(server.DB, id)
+    if err != nil {
         SetFlash(w, r, "error", "Order tidak ditemukan")
         http.Redirect(w, r, "/admin/orders", http.StatusSeeOther)
         return
     }
-    data := server.DefaultRenderData(w, r, map[string]interface{}{"order": ord})
+    data := server.DefaultRenderData(w, r, map[string]interface{}{
+        "order":          ord,
+        "shippingRegion": ord.OrderCustomer.RegionNames(server.DB),
+    })
     _ = render.HTML(w, http.StatusOK, "admin/order_detail", data)
 }
 
@@ -286,18 +378,42 @@ func (server *Server) APIAdminProducts(w http.ResponseWriter, r *http.Request) {
     ren := newAdminRender()
     switch r.Method {
     case "GET":
+        // filter ?status=draft|published|archived dan ?scheduled=1 (punya jadwal publish/unpublish)
+        query := server.DB.Preload("ProductImages", models.OrderedProductImages).Where("is_temporary = ?", false)
+        if value := r.URL.Query().Get("status"); value != "" {
+            status, err := models.ParseProductStatus(value)
+            if err != nil {
+                _ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+                return
+            }
+            query = query.Where("status = ?", status)
+        }
+        if isTruthy(r.URL.Query().Get("scheduled")) {
+            query = query.Where("publish_at IS NOT NULL OR unpublish_at IS NOT NULL")
+        }
         var products []models.Product
-        server.DB.Preload("ProductImages").Where("is_temporary = ?", false).Order("created_at desc").Find(&products)
+        query.Order("created_at desc").Find(&products)
         _ = ren.JSON(w, http.StatusOK, products)
         return
     case "POST":
         // create product from JSON body
         var payload struct {
             Name             string  `json:"name"`
+            Slug             string  `json:"slug"`
             Price            float64 `json:"price"`
             Stock            int     `json:"stock"`
             ShortDescription string  `json:"short_description"`
             Description      string  `json:"description"`
+            Type             string  `json:"type"`
+            Weight           float64 `json:"weight"`
+            Length           float64 `json:"length"`
+            Width            float64 `json:"width"`
+            Height           float64 `json:"height"`
+            Position         int     `json:"position"`
+            LowStockThreshold *int   `json:"low_stock_threshold"`
+            Status           string     `json:"status"`
+            PublishAt        *time.Time `json:"publish_at"`
+            UnpublishAt      *time.Time `json:"unpublish_at"`
         }
         if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
             http.Error(w, "invalid json", http.StatusBadRequest)
@@ -305,10 +421,35 @@ func (server *Server) APIAdminProducts(w http.ResponseWriter, r *http.Request) {
         }
         p := models.Product{
             Name:             payload.Name,
+            Slug:             payload.Slug,
             Price:            decimal.NewFromFloat(payload.Price),
             Stock:            payload.Stock,
             ShortDescription: payload.ShortDescription,
             Description:      payload.Description,
+            Type:             payload.Type,
+            Weight:           decimal.NewFromFloat(payload.Weight),
+            Length:           decimal.NewFromFloat(payload.Length),
+            Width:            decimal.NewFromFloat(payload.Width),
+            Height:           decimal.NewFromFloat(payload.Height),
+            Position:         payload.Position,
+        }
+        p.LowStockThreshold = payload.LowStockThreshold
+        if p.Type == "" {
+            p.Type = consts.ProductTypeStandard
+        }
+        // produk baru default draft
+        status := consts.ProductStatusDraft
+        if payload.Status != "" {
+            parsed, err := models.ParseProductStatus(payload.Status)
+            if err != nil {
+                _ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+                return
+            }
+            status = parsed
+        }
+        if payload.PublishAt != nil && payload.UnpublishAt != nil && !payload.UnpublishAt.After(*payload.PublishAt) {
+            _ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": models.ErrInvalidSchedule.Error()})
+            return
         }
         // attach an existing user as owner to satisfy foreign key constraints
         // use a small struct to read the id column reliably
@@ -323,7 +464,7 @@ func (server *Server) APIAdminProducts(w http.ResponseWriter, r *http.Request) {
         } else {
             fmt.Println("APIAdminProducts: no user id found via raw struct query")
         }
-        if err := server.DB.Create(&p).Error; err != nil {
+        if err := models.CreateProduct(server.DB, &p); err != nil {
             fmt.Println("APIAdminProducts create error:", err)
             // persist error to logfile for debugging
             persistError(err)
@@ -331,6 +472,17 @@ func (server *Server) APIAdminProducts(w http.ResponseWriter, r *http.Request) {
             _ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
             return
         }
+        initial := models.StockChange{Reason: consts.StockReasonAdjustment}
+        if admin := server.CurrentUser(w, r); admin != nil {
+            initial.Actor = admin.ID
+        }
+        if err := models.RecordInitialStock(server.DB, &p, initial); err != nil {
+            persistError(err)
+        }
+        if err := p.UpdatePublishing(server.DB, status, payload.PublishAt, payload.UnpublishAt); err != nil {
+            _ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+            return
+        }
         _ = ren.JSON(w, http.StatusCreated, p)
         return
     default:
@@ -346,7 +498,7 @@ func (server *Server) APIAdminProduct(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     id := vars["id"]
     var p models.Product
-    if err := server.DB.Preload("ProductImages").Where("id = ?", id).First(&p).Error; err != nil {
+    if err := server.DB.Preload("ProductImages", models.OrderedProductImages).Where("id = ?", id).First(&p).Error; err != nil {
         http.Error(w, "not found", http.StatusNotFound)
         return
     }
@@ -366,10 +518,59 @@ func (server *Server) APIAdminProduct(w http.ResponseWriter, r *http.Request) {
                 payload["price"] = decimal.NewFromFloat(f)
             }
         }
-        if err := server.DB.Model(&models.Product{}).Where("id = ?", id).Updates(payload).Error; err != nil {
-            http.Error(w, "failed to update", http.StatusInternalServerError)
+        // slug diganti lewat ChangeSlug supaya slug lama masuk riwayat redirect
+        slugVal, hasSlug := payload["slug"].(string)
+        delete(payload, "slug")
+        // stok lewat ledger (SetProductStock) supaya perubahan tercatat
+        stockVal, hasStock := payload["stock"]
+        delete(payload, "stock")
+        newStock, stockIsInt := stockVal.(float64)
+        if hasStock && (!stockIsInt || newStock != float64(int(newStock))) {
+            _ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": "stock must be an integer"})
             return
         }
+        // status dan jadwal lewat UpdatePublishing supaya published_at ikut terisi
+        publishingChanged, newStatus, publishAt, unpublishAt, err := productPublishingFromPayload(payload, &p)
+        if err != nil {
+            _ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+            return
+        }
+        if len(payload) > 0 {
+            if err := server.DB.Model(&models.Product{}).Where("id = ?", id).Updates(payload).Error; err != nil {
+                http.Error(w, "failed to update", http.StatusInternalServerError)
+                return
+            }
+        }
+        if hasSlug {
+            if err := server.changeProductSlug(id, slugVal); err != nil {
+                status := http.StatusInternalServerError
+                if errors.Is(err, models.ErrSlugTaken) {
+                    status = http.StatusConflict
+                }
+                _ = ren.JSON(w, status, map[string]string{"error": err.Error()})
+                return
+            }
+        }
+        if hasStock {
+            change := models.StockChange{ProductID: id, Reason: consts.StockReasonAdjustment, Note: "edit produk"}
+            if admin := server.CurrentUser(w, r); admin != nil {
+                change.Actor = admin.ID
+            }
+            if _, err := models.SetProductStock(server.DB, int(newStock), change); err != nil {
+                _ = ren.JSON(w, stockErrorStatus(err), map[string]string{"error": err.Error()})
+                return
+            }
+        }
+        if publishingChanged {
+            if err := p.UpdatePublishing(server.DB, newStatus, publishAt, unpublishAt); err != nil {
+                status := http.StatusInternalServerError
+                if errors.Is(err, models.ErrInvalidSchedule) || errors.Is(err, models.ErrInvalidProductStatus) {
+                    status = http.StatusBadRequest
+                }
+                _ = ren.JSON(w, status, map[string]string{"error": err.Error()})
+                return
+            }
+        }
         server.DB.Where("id = ?", id).First(&p)
         _ = ren.JSON(w, http.StatusOK, p)
         return
@@ -760,7 +961,7 @@ func (server *Server) APIAdminOrder(w http.ResponseWriter, r *http.Request) {
     vars := mux.Vars(r)
     id := vars["id"]
     var ord models.Order
-    if err := server.DB.Preload("OrderItems").Preload("User").Where("id = ?", id).First(&ord).Error; err != nil {
+    if err := server.DB.Preload("OrderItems").Preload("OrderCustomer").Preload("User").Where("id = ?", id).First(&ord).Error; err != nil {
         http.Error(w, "not found", http.StatusNotFound)
         return
     }
@@ -787,6 +988,7 @@ func (server *Server) APIAdminOrder(w http.ResponseWriter, r *http.Request) {
         Items      []itemView  `json:"order_items"`
         ItemsCount int         `json:"items_count"`
         GrandTotal string      `json:"grand_total"`
+        Shipping   map[string]interface{} `json:"shipping_address"`
     }
 
     ov := orderView{ID: ord.ID, Code: ord.Code, OrderDate: ord.OrderDate, GrandTotal: ord.GrandTotal.String()}
@@ -799,126 +1001,20 @@ func (server *Server) APIAdminOrder(w http.ResponseWriter, r *http.Request) {
         ov.Items = append(ov.Items, iv)
     }
     ov.ItemsCount = len(ov.Items)
-
-    _ = ren.JSON(w, http.StatusOK, ov)
-}
-
-// APIAdminProductImageUpload handles image uploads for a product (multipart/form-data)
-func (server *Server) APIAdminProductImageUpload(w http.ResponseWriter, r *http.Request) {
-    // require POST
-    if r.Method != "POST" {
-        http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
-        return
-    }
-    vars := mux.Vars(r)
-    id := vars["id"]
-    // ensure product exists
-    var p models.Product
-    if err := server.DB.Where("id = ?", id).First(&p).Error; err != nil {
-        http.Error(w, "product not found", http.StatusNotFound)
-        return
-    }
-
-    // parse multipart
-    if err := r.ParseMultipartForm(10 << 20); err != nil {
-        http.Error(w, "failed to parse form", http.StatusBadRequest)
-        return
-    }
-    file, header, err := r.FormFile("image")
-    if err != nil {
-        http.Error(w, "image file required", http.StatusBadRequest)
-        return
-    }
-    defer file.Close()
-
-    // ensure upload dir
-    uploadDir := "public/uploads/products/" + id
-    if err := ensureDir(uploadDir); err != nil {
-        http.Error(w, "failed to create upload dir", http.StatusInternalServerError)
-        return
+    ov.Shipping = map[string]interface{}{
+        "name":        strings.TrimSpace(ord.OrderCustomer.FirstName + " " + ord.OrderCustomer.LastName),
+        "address1":    ord.OrderCustomer.Address1,
+        "address2":    ord.OrderCustomer.Address2,
+        "post_code":   ord.OrderCustomer.PostCode,
+        "phone":       ord.OrderCustomer.Phone,
+        "province_id": ord.OrderCustomer.ProvinceID,
+        "city_id":     ord.OrderCustomer.CityID,
+        "region":      ord.OrderCustomer.RegionNames(server.DB),
     }
 
-    // build filename
-    ext := ""
-    for i := len(header.Filename) - 1; i >= 0; i-- {
-        if header.Filename[i] == '.' {
-            ext = header.Filename[i:]
-            break
-        }
-    }
-    fname := fmt.Sprintf("img_%d%s", time.Now().UnixNano(), ext)
-    outPath := uploadDir + "/" + fname
-
-    out, err := createFile(outPath)
-    if err != nil {
-        http.Error(w, "failed to save file", http.StatusInternalServerError)
-        return
-    }
-    if _, err := io.Copy(out, file); err != nil {
-        out.Close()
-        http.Error(w, "failed to write file", http.StatusInternalServerError)
-        return
-    }
-    // close file before image processing to avoid file locking on Windows
-    _ = out.Close()
-
-    relPath := "/uploads/products/" + id + "/" + fname
-    img := models.ProductImage{ID: uuid.New().String(), ProductID: id, Path: relPath}
-
-    // attempt to create resized variants (extra_large, large, medium, small)
-    // use the saved file on disk as source
-    srcPath := outPath
-    // derive name without ext
-    nameNoExt := fname
-    if ext != "" {
-        nameNoExt = strings.TrimSuffix(fname, ext)
-    }
-    if srcImg, err := imaging.Open(srcPath); err == nil {
-        sizes := map[string]int{"ExtraLarge": 1600, "Large": 1024, "Medium": 512, "Small": 128}
-        for k, s := range sizes {
-            newName := fmt.Sprintf("%s_%s%s", nameNoExt, strings.ToLower(k), ext)
-            newPath := filepath.Join(uploadDir, newName)
-            dst := imaging.Resize(srcImg, s, 0, imaging.Lanczos)
-            if err := imaging.Save(dst, newPath); err == nil {
-                rel := "/uploads/products/" + id + "/" + newName
-                switch k {
-                case "ExtraLarge":
-                    img.ExtraLarge = rel
-                case "Large":
-                    img.Large = rel
-                case "Medium":
-                    img.Medium = rel
-                case "Small":
-                    img.Small = rel
-                }
-            }
-            if err := imaging.Save(dst, newPath); err != nil {
-                fmt.Println("imaging save error:", err, "path:", newPath)
-            }
-        }
-    } else {
-        fmt.Println("imaging open error:", err, "path:", srcPath)
-    }
-
-    if err := server.DB.Create(&img).Error; err != nil {
-        http.Error(w, "failed to create image record", http.StatusInternalServerError)
-        return
-    }
-
-    ren := newAdminRender()
-    _ = ren.JSON(w, http.StatusCreated, img)
-}
-
-// helper small wrappers to avoid importing many packages at top of file
-func ensureDir(path string) error {
-    return os.MkdirAll(path, 0755)
-}
-
-func createFile(path string) (*os.File, error) {
-    return os.Create(path)
+    _ = ren.JSON(w, http.StatusOK, ov)
 }
 
-
 // APIAdminUser handles GET/PUT/DELETE for a single user (admin-only)
 func (server *Server) APIAdminUser(w http.ResponseWriter, r *http.Request) {
     w.Header().Set("Content-Type", "application/json")
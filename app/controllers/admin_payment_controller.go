@@ -0,0 +1,210 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+)
+
+type paymentView struct {
+	ID                string                       `json:"id"`
+	Number            string                       `json:"number"`
+	OrderID           string                       `json:"order_id"`
+	OrderCode         string                       `json:"order_code"`
+	Amount            string                       `json:"amount"`
+	TransactionID     string                       `json:"transaction_id"`
+	TransactionStatus string                       `json:"transaction_status"`
+	PaymentType       string                       `json:"payment_type"`
+	CreatedAt         time.Time                    `json:"created_at"`
+	Notification      *models.MidtransNotification `json:"notification,omitempty"`
+	SignatureValid    *bool                        `json:"signature_valid,omitempty"`
+	IsSuccess         *bool                        `json:"is_success,omitempty"`
+	PayloadError      string                       `json:"payload_error,omitempty"`
+}
+
+func newPaymentView(p models.Payment, withPayload bool) paymentView {
+	view := paymentView{
+		ID:                p.ID,
+		Number:            p.Number,
+		OrderID:           p.OrderID,
+		OrderCode:         p.Order.Code,
+		Amount:            p.Amount.String(),
+		TransactionID:     p.TransactionID,
+		TransactionStatus: p.TransactionStatus,
+		PaymentType:       p.PaymentType,
+		CreatedAt:         p.CreatedAt,
+	}
+
+	if !withPayload {
+		return view
+	}
+
+	notification, err := p.Notification()
+	if err != nil {
+		view.PayloadError = err.Error()
+		return view
+	}
+
+	signatureValid := validateSignatureKey(notification, os.Getenv("API_MIDTRANS_SERVER_KEY")) == nil
+	isSuccess := isPaymentSuccess(notification)
+	view.Notification = notification
+	view.SignatureValid = &signatureValid
+	view.IsSuccess = &isSuccess
+
+	return view
+}
+
+// APIAdminPayments returns the payment ledger filtered by date, status, type, order code and transaction ID
+func (server *Server) APIAdminPayments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	qs := r.URL.Query()
+	perPage := 20
+	page := 1
+	if pp := qs.Get("per_page"); pp != "" {
+		if v, e := strconv.Atoi(pp); e == nil && v > 0 {
+			perPage = v
+		}
+	}
+	if pg := qs.Get("page"); pg != "" {
+		if v, e := strconv.Atoi(pg); e == nil && v > 0 {
+			page = v
+		}
+	}
+
+	filter := models.PaymentFilter{
+		TransactionStatus: qs.Get("status"),
+		PaymentType:       qs.Get("type"),
+		OrderCode:         qs.Get("order_code"),
+		TransactionID:     qs.Get("transaction_id"),
+	}
+	if startStr := qs.Get("start"); startStr != "" {
+		t, err := time.Parse("2006-01-02", startStr)
+		if err != nil {
+			http.Error(w, "invalid start date", http.StatusBadRequest)
+			return
+		}
+		filter.Start = t
+	}
+	if endStr := qs.Get("end"); endStr != "" {
+		t, err := time.Parse("2006-01-02", endStr)
+		if err != nil {
+			http.Error(w, "invalid end date", http.StatusBadRequest)
+			return
+		}
+		// make end exclusive
+		filter.End = t.Add(24 * time.Hour)
+	}
+
+	paymentModel := models.Payment{}
+	payments, totalCount, err := paymentModel.GetPayments(server.DB, filter, perPage, page)
+	if err != nil {
+		http.Error(w, "failed to load payments", http.StatusInternalServerError)
+		return
+	}
+
+	out := []paymentView{}
+	for _, p := range payments {
+		out = append(out, newPaymentView(p, false))
+	}
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{"payments": out, "meta": map[string]interface{}{"total_count": totalCount, "page": page, "per_page": perPage}})
+}
+
+// APIAdminOrderPayments returns the decoded notification history for an order
+func (server *Server) APIAdminOrderPayments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	var order models.Order
+	if err := server.DB.Where("id = ? OR code = ?", vars["id"], vars["id"]).First(&order).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	paymentModel := models.Payment{}
+	payments, err := paymentModel.GetPaymentsByOrderID(server.DB, order.ID)
+	if err != nil {
+		http.Error(w, "failed to load payments", http.StatusInternalServerError)
+		return
+	}
+
+	out := []paymentView{}
+	for _, p := range payments {
+		p.Order = order
+		out = append(out, newPaymentView(p, true))
+	}
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+		"order": map[string]interface{}{
+			"id":             order.ID,
+			"code":           order.Code,
+			"status":         order.GetStatusLabel(),
+			"payment_status": order.PaymentStatus,
+			"payment_method": order.PaymentMethod,
+			"grand_total":    order.GrandTotal.String(),
+		},
+		"payments": out,
+	})
+}
+
+// APIAdminPaymentReprocess re-runs processing of a stored Midtrans notification
+// without storing it again, and reports the order state before and after.
+func (server *Server) APIAdminPaymentReprocess(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	paymentModel := models.Payment{}
+	payment, err := paymentModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	notification, err := payment.Notification()
+	if err != nil || notification.OrderID == "" {
+		_ = ren.JSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "stored payload is not a Midtrans notification"})
+		return
+	}
+
+	if err := validateSignatureKey(notification, os.Getenv("API_MIDTRANS_SERVER_KEY")); err != nil {
+		_ = ren.JSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+
+	before := payment.Order.PaymentStatus
+	result := map[string]interface{}{
+		"payment_id":            payment.ID,
+		"order_id":              notification.OrderID,
+		"payment_status_before": before,
+		"is_success":            isPaymentSuccess(notification),
+	}
+
+	order, err := server.processPaymentNotification(notification, false)
+	if err != nil {
+		result["error"] = err.Error()
+		status := http.StatusInternalServerError
+		var notifErr *paymentNotificationError
+		if errors.As(err, &notifErr) {
+			status = notifErr.Code
+		}
+		_ = ren.JSON(w, status, result)
+		return
+	}
+
+	var refreshed models.Order
+	if err := server.DB.Where("id = ?", order.ID).First(&refreshed).Error; err == nil {
+		result["payment_status_after"] = refreshed.PaymentStatus
+		result["status_after"] = refreshed.GetStatusLabel()
+	}
+
+	_ = ren.JSON(w, http.StatusOK, result)
+}
@@ -6,6 +6,8 @@ import (
 	"net/http"
 	"os"
 	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
 )
 
 type ctxKey string
@@ -69,7 +71,7 @@ func (server *Server) RequireAdminAuth(next http.Handler) http.Handler {
         // cek session + peran admin
         if IsLoggedIn(r) {
             user := server.CurrentUser(w, r)
-            if user != nil && (user.Role == "admin" || user.Role == "superadmin") {
+            if isAdminUser(user) {
                 next.ServeHTTP(w, r)
                 return
             }
@@ -83,6 +85,11 @@ func (server *Server) RequireAdminAuth(next http.Handler) http.Handler {
     })
 }
 
+// isAdminUser mengecek peran admin atau superadmin.
+func isAdminUser(user *models.User) bool {
+    return user != nil && (user.Role == "admin" || user.Role == "superadmin")
+}
+
 // GetAuthTokenFromContext mengembalikan token Authorization yang disimpan oleh middleware.
 func GetAuthTokenFromContext(r *http.Request) string {
     v := r.Context().Value(ctxKeyAuthToken)
@@ -4,20 +4,24 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"log"
 	"math"
 	"net/http"
 	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/postgres"
 
+	"github.com/codeuiprogramming/e-commerce/app/consts"
 	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/shipping"
+	"github.com/codeuiprogramming/e-commerce/app/storage"
 	"github.com/codeuiprogramming/e-commerce/database/seeders"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/sessions"
@@ -66,6 +70,8 @@ type PaginationParams struct {
 	TotalRows int32
 	PerPage int32
 	CurrentPage int32
+	// Query ikut disertakan di setiap link halaman (mis. q pencarian)
+	Query url.Values
 }
 
 type Result struct {
@@ -78,6 +84,13 @@ var store *sessions.CookieStore
 var sessionShoppingCart = "shopping-cart-session"
 var sessionFlash = "flash-session"
 var sessionUser = "user-session"
+var sessionCurrency = "currency-session"
+
+var shippingClient *shipping.Client
+var shippingClientOnce sync.Once
+
+// pesan yang ditampilkan ketika RajaOngkir sedang tidak bisa dihubungi
+var shippingUnavailableMessage = "Layanan ongkos kirim sedang tidak tersedia. Silakan coba beberapa saat lagi."
 
 func (server *Server) Initialize(appConfig AppConfig, dbConfig DBConfig) {
 
@@ -108,6 +121,9 @@ func (server *Server) Initialize(appConfig AppConfig, dbConfig DBConfig) {
 	// run DB migrations automatically in development so new profile fields exist
 	server.dbMigrate()
 	server.initializeRoutes()
+	server.StartShipmentPoller()
+	server.StartProductScheduler()
+	server.StartProductImageWorker()
 } 
 
 func (server *Server) Run (addr string) {
@@ -121,7 +137,7 @@ func (server *Server) initializeDB(dbConfig DBConfig) {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=Asia/Shanghai", 
 	dbConfig.DBHost, dbConfig.DBUser, dbConfig.DBPassword, dbConfig.DBName, dbConfig.DBPort)
 	// Membuka koneksi ke database menggunakan GORM dengan driver PostgreSQL.
-	server.DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	server.DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	 // Jika terjadi error saat menghubungkan database, hentikan server dan tampilkan panic.
 	if err != nil {
 		panic("Failed on connecting to the database server")
@@ -133,6 +149,21 @@ func (server *Server) initializeAppConfig(appConfig AppConfig) {
 }
 
 func (server *Server) dbMigrate() {
+	// kolom published_at belum ada berarti produk dibuat sebelum ada status publish
+	publishLegacy := !server.DB.Migrator().HasColumn(&models.Product{}, "published_at")
+	// kolom position belum ada berarti urutan unggulan masih memakai aturan lama
+	positionLegacy := !server.DB.Migrator().HasColumn(&models.Product{}, "position")
+	// tabel ledger belum ada berarti stok produk yang ada perlu saldo awal
+	openLedger := !server.DB.Migrator().HasTable(&models.StockMovement{})
+	// slug kosong atau ganda harus dirapikan dulu sebelum unique index dibuat
+	if server.DB.Migrator().HasTable(&models.Product{}) && !server.DB.Migrator().HasIndex(&models.Product{}, "Slug") {
+		fixed, err := models.BackfillProductSlugs(server.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Fixed %d product slugs\n", fixed)
+	}
+
 	for _, model := range models.RegisterModels() {
 		err := server.DB.Debug().AutoMigrate(model.Model)
 
@@ -141,11 +172,41 @@ func (server *Server) dbMigrate() {
 		}
 	}
 
+	// tsvector + indeks GIN untuk pencarian produk
+	if err := models.SetupProductSearch(server.DB); err != nil {
+		log.Fatal(err)
+	}
+
+	if publishLegacy {
+		published, err := models.PublishLegacyProducts(server.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Published %d existing products\n", published)
+	}
+
+	if positionLegacy {
+		positioned, err := models.PositionLegacyProducts(server.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Positioned %d existing products\n", positioned)
+	}
+
+	if openLedger {
+		recorded, err := models.RecordOpeningBalances(server.DB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Recorded opening stock for %d products\n", recorded)
+	}
+
 	fmt.Println("Database migrate successfully")
 }
 
 func (server *Server) InitCommands(config AppConfig, dbConfig DBConfig) {
 	server.initializeDB(dbConfig)
+	server.initializeAppConfig(config)
 
 	cmdApp := cli.NewApp()
 	cmdApp.Commands = []cli.Command{
@@ -167,6 +228,163 @@ func (server *Server) InitCommands(config AppConfig, dbConfig DBConfig) {
 				return nil
 			},
 		},
+		{
+			Name: "currency:import",
+			Action: func(c *cli.Context) error {
+				imported, err := server.ImportExchangeRates()
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fmt.Printf("Imported %d exchange rates\n", imported)
+				return nil
+			},
+		},
+		{
+			Name:  "regions:import",
+			Usage: "import province/city/district/postcode data from a CSV/JSON file, a URL, or the RajaOngkir API",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "source", Value: "api", Usage: "path or URL to a .csv/.json dataset, or \"api\" (resumable: provinces and cities already imported are skipped)"},
+			},
+			Action: func(c *cli.Context) error {
+				imported, err := server.ImportRegions(c.String("source"))
+				if err != nil {
+					log.Fatalf("imported %d region rows before error (run again to resume): %v", imported, err)
+				}
+
+				fmt.Printf("Imported %d region rows\n", imported)
+				return nil
+			},
+		},
+		{
+			Name:  "products:backfill-slugs",
+			Usage: "generate unique slugs for products that have none or share one with another product",
+			Action: func(c *cli.Context) error {
+				filled, err := models.BackfillProductSlugs(server.DB)
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fmt.Printf("Backfilled %d product slugs\n", filled)
+				return nil
+			},
+		},
+		{
+			Name:  "products:publish-scheduled",
+			Usage: "publish and unpublish products whose schedule has passed",
+			Action: func(c *cli.Context) error {
+				published, unpublished, err := models.ProcessProductSchedules(server.DB, time.Now())
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fmt.Printf("Published %d products, unpublished %d products\n", published, unpublished)
+				return nil
+			},
+		},
+		{
+			Name:  "images:process",
+			Usage: "generate size and WebP variants for pending product images",
+			Flags: []cli.Flag{
+				cli.BoolFlag{Name: "failed", Usage: "also retry images that failed before"},
+			},
+			Action: func(c *cli.Context) error {
+				if _, err := models.RequeueProductImages(server.DB, c.Bool("failed")); err != nil {
+					log.Fatal(err)
+				}
+
+				processed, failed := 0, 0
+				for {
+					ids, err := models.PendingProductImageIDs(server.DB, 100)
+					if err != nil {
+						log.Fatal(err)
+					}
+					if len(ids) == 0 {
+						break
+					}
+					for _, id := range ids {
+						if err := server.ProcessProductImage(id); err != nil {
+							log.Printf("image %s: %v\n", id, err)
+							failed++
+							continue
+						}
+						processed++
+					}
+				}
+
+				fmt.Printf("Processed %d product images, %d failed\n", processed, failed)
+				return nil
+			},
+		},
+		{
+			Name:  "shipments:poll",
+			Usage: "poll courier tracking once for all open shipments",
+			Action: func(c *cli.Context) error {
+				polled, err := server.PollShipments(server.ShippingClient())
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fmt.Printf("Polled %d shipments\n", polled)
+				return nil
+			},
+		},
+		{
+			Name:  "storage:migrate",
+			Usage: "copy existing uploads from local folders to the configured storage backend",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "dirs", Value: "public,assets", Usage: "comma separated folders whose uploads/ subfolder is copied"},
+				cli.BoolFlag{Name: "delete", Usage: "delete local files after they are copied"},
+				cli.BoolFlag{Name: "dry-run", Usage: "only list files that would be copied"},
+			},
+			Action: func(c *cli.Context) error {
+				var dirs []string
+				for _, dir := range strings.Split(c.String("dirs"), ",") {
+					if dir = strings.TrimSpace(dir); dir != "" {
+						dirs = append(dirs, dir)
+					}
+				}
+
+				copied, skipped, err := server.MigrateUploads(dirs, c.Bool("delete"), c.Bool("dry-run"))
+				if err != nil {
+					log.Fatal(err)
+				}
+
+				fmt.Printf("Copied %d files, %d already in storage\n", copied, skipped)
+				return nil
+			},
+		},
+		{
+			Name:  "storage:stub",
+			Usage: "run a local S3-compatible stub server for development",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "addr", Value: ":9100"},
+				cli.StringFlag{Name: "dir", Value: "tmp/s3-stub", Usage: "folder where objects are stored"},
+			},
+			Action: func(c *cli.Context) error {
+				accessKey := os.Getenv("STORAGE_S3_ACCESS_KEY")
+				secretKey := os.Getenv("STORAGE_S3_SECRET_KEY")
+				if accessKey == "" || secretKey == "" {
+					accessKey, secretKey = "minioadmin", "minioadmin"
+				}
+				fmt.Printf("S3 stub listening on %s (set STORAGE_DRIVER=s3 STORAGE_S3_ENDPOINT=http://localhost%s STORAGE_S3_BUCKET=uploads STORAGE_S3_ACCESS_KEY=%s STORAGE_S3_SECRET_KEY=%s)\n", c.String("addr"), c.String("addr"), accessKey, secretKey)
+				log.Fatal(http.ListenAndServe(c.String("addr"), storage.NewStubServer(c.String("dir"), os.Getenv("STORAGE_S3_REGION"), accessKey, secretKey)))
+				return nil
+			},
+		},
+		{
+			Name:  "shipping:stub",
+			Usage: "run a local RajaOngkir stub server for development",
+			Flags: []cli.Flag{
+				cli.StringFlag{Name: "addr", Value: ":9090"},
+				cli.DurationFlag{Name: "step", Value: time.Minute, Usage: "time between tracking milestones"},
+			},
+			Action: func(c *cli.Context) error {
+				fmt.Printf("RajaOngkir stub listening on %s (set API_ONGKIR_BASE_URL=http://localhost%s/)\n", c.String("addr"), c.String("addr"))
+				log.Fatal(http.ListenAndServe(c.String("addr"), shipping.NewStubServer(c.Duration("step"))))
+				return nil
+			},
+		},
 	}
 
 	err := cmdApp.Run(os.Args)
@@ -184,7 +402,7 @@ func GetPaginationLinks(config *AppConfig, params PaginationParams) (PaginationL
 	for i :=1; int32(i) <= totalPages; i++ {
 		links = append(links, PageLink{
 			Page: int32(i),
-			Url: fmt.Sprintf("%s/%s?page=%s", config.AppURL, params.Path, fmt.Sprint(i)),
+			Url: paginationURL(config, params, int32(i)),
 			IsCurrentPage: int32(i) == params.CurrentPage,
 		})
 	}
@@ -204,118 +422,209 @@ func GetPaginationLinks(config *AppConfig, params PaginationParams) (PaginationL
 	}
 
 	return PaginationLinks{
-		CurrentPage: fmt.Sprintf("%s/%s?page=%s", config.AppURL, params.Path, fmt.Sprint(params.CurrentPage)),
-		NextPage:    fmt.Sprintf("%s/%s?page=%s", config.AppURL, params.Path, fmt.Sprint(nextPage)),
-		PrevPage:    fmt.Sprintf("%s/%s?page=%s", config.AppURL, params.Path, fmt.Sprint(prevPage)),
+		CurrentPage: paginationURL(config, params, params.CurrentPage),
+		NextPage:    paginationURL(config, params, nextPage),
+		PrevPage:    paginationURL(config, params, prevPage),
 		TotalRows:   params.TotalRows,
 		TotalPages:  totalPages,
 		Links:       links,
 	}, nil
 }
 
-func (server *Server) GetProvince() ([]models.Province, error) {
-	req, err := http.NewRequest("GET", os.Getenv("API_ONGKIR_BASE_URL")+"destination/province", nil)
-	if err != nil {
-		return nil, err
+func paginationURL(config *AppConfig, params PaginationParams, page int32) string {
+	query := url.Values{}
+	for key, values := range params.Query {
+		if key == "page" {
+			continue
+		}
+		query[key] = values
 	}
-	req.Header.Add("Key", os.Getenv("API_ONGKIR_KEY"))
+	query.Set("page", fmt.Sprint(page))
 
-	client := &http.Client{}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer res.Body.Close()
+	return fmt.Sprintf("%s/%s?%s", config.AppURL, params.Path, query.Encode())
+}
 
-	body, _ := io.ReadAll(res.Body)
+// ShippingClient mengembalikan client RajaOngkir bersama (dibuat sekali dari env)
+// supaya cache wilayah dan ongkir dipakai ulang oleh semua request.
+func (server *Server) ShippingClient() *shipping.Client {
+	shippingClientOnce.Do(func() {
+		shippingClient = shipping.NewClientFromEnv()
+	})
 
-	var result models.ProvinceResponse
-	if err := json.Unmarshal(body, &result); err != nil {
-		return nil, err
+	return shippingClient
+}
+
+func (server *Server) GetProvince() ([]models.Province, error) {
+	if server.regionsFromDB() {
+		return server.regionProvinces()
 	}
 
-	return result.Data, nil
+	return server.ShippingClient().Provinces()
 }
 
 func (server *Server) GetCitiesByProvinceID(provinceID string) ([]models.City, error) {
-    url := os.Getenv("API_ONGKIR_BASE_URL") + "destination/city/" + provinceID
-
-    req, err := http.NewRequest("GET", url, nil)
-    if err != nil {
-        return nil, err
-    }
-    req.Header.Add("Key", os.Getenv("API_ONGKIR_KEY"))
-
-    client := &http.Client{}
-    res, err := client.Do(req)
-    if err != nil {
-        return nil, err
-    }
-    defer res.Body.Close()
-
-    body, _ := io.ReadAll(res.Body)
-    fmt.Println("RAW RESPONSE:", string(body)) // debug body
-
-    var result models.CityResponse
-    if err := json.Unmarshal(body, &result); err != nil {
-        return nil, err
-    }
-
-    return result.Data, nil
+	if server.regionsFromDB() {
+		return server.regionCities(provinceID)
+	}
+
+	return server.ShippingClient().Cities(provinceID)
 }
 
+// CalculateShippingFee menghitung opsi ongkir untuk satu kode kurir. Kode milik
+// ShippingMethod dihitung dari aturan internal, selain itu dari RajaOngkir.
+// Surcharge yang cocok dengan tujuan ditambahkan ke semua opsi. Bila Parcel diisi,
+// berat yang dikirim adalah berat yang ditagih (aktual + kardus atau volume).
 func (server *Server) CalculateShippingFee(shippingParams models.ShippingFeeParams) ([]models.ShippingFeeOption, error) {
-	if shippingParams.Origin == "" || shippingParams.Destination == "" || shippingParams.Weight <= 0 || shippingParams.Courier == "" {
-		return nil, errors.New("invalid params")
-	}
+	shippingParams.Weight = chargeableWeight(shippingParams.Parcel, shippingParams.Courier, shippingParams.Weight)
 
-	params := url.Values{}
-	params.Add("origin", strings.TrimSpace(shippingParams.Origin))
-	params.Add("destination", strings.TrimSpace(shippingParams.Destination))
-	params.Add("weight", strconv.Itoa(shippingParams.Weight))
-	params.Add("courier", strings.TrimSpace(shippingParams.Courier))
+	methods := server.activeShippingMethods()
+	if method := findShippingMethod(methods, shippingParams.Courier); method != nil {
+		option, ok := shippingMethodOption(method, shippingParams)
+		if !ok {
+			return nil, errors.New("shipping method is not available for this destination")
+		}
+		return applyShippingSurcharges(methods, shippingParams, []models.ShippingFeeOption{option}), nil
+	}
 
-	endpoint := os.Getenv("API_ONGKIR_BASE_URL") + "calculate/domestic-cost"
-	API_KEY := os.Getenv("API_ONGKIR_KEY")
+	if shippingMode() == consts.ShippingModeRules {
+		return nil, errors.New("courier is not enabled")
+	}
 
-	req, err := http.NewRequest("POST", endpoint, strings.NewReader(params.Encode()))
+	results, err := server.ShippingClient().Cost(shippingParams)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("Key", API_KEY)
+	var shippingFeeOptions []models.ShippingFeeOption
+	for _, data := range results {
+		courier := data.Code
+		if courier == "" {
+			courier = shippingParams.Courier
+		}
+		shippingFeeOptions = append(shippingFeeOptions, models.ShippingFeeOption{
+			Courier:     strings.ToLower(courier),
+			ServiceCode: data.Service,
+			Service:     data.Service + " (" + data.Description + ")",
+			Description: data.Description,
+			Fee:         data.Cost,
+			Etd:         data.Etd,
+		})
+	}
+
+	return applyShippingSurcharges(methods, shippingParams, shippingFeeOptions), nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
+// EnabledCouriers membaca SHIPPING_COURIERS (dipisah koma), default jne,jnt,sicepat,pos,
+// ditambah kode ShippingMethod yang aktif. SHIPPING_MODE=api|rules|both (default both)
+// menentukan sumber mana yang dipakai.
+func (server *Server) EnabledCouriers() []string {
+	var couriers []string
+	mode := shippingMode()
+
+	if mode != consts.ShippingModeRules {
+		couriers = splitEnvList("SHIPPING_COURIERS")
+		if len(couriers) == 0 {
+			couriers = []string{"jne", "jnt", "sicepat", "pos"}
+		}
+		for i := range couriers {
+			couriers[i] = strings.ToLower(couriers[i])
+		}
 	}
-	defer resp.Body.Close()
 
-	body, _ := ioutil.ReadAll(resp.Body)
+	if mode != consts.ShippingModeAPI {
+		for _, method := range server.activeShippingMethods() {
+			if method.Type != consts.ShippingMethodTypeSurcharge {
+				couriers = append(couriers, method.Code)
+			}
+		}
+	}
 
-	log.Println(" Raw Ongkir Response:", string(body))
+	return couriers
+}
 
-	var ongkirResponse models.OngkirResponse
-	if err := json.Unmarshal(body, &ongkirResponse); err != nil {
-		return nil, err
+// CalculateShippingFees meminta ongkir ke semua kurir secara paralel. Setiap
+// kurir dibatasi SHIPPING_RATE_TIMEOUT detik (default 8); kurir yang gagal
+// dilaporkan di map error tanpa menggagalkan kurir lain.
+func (server *Server) CalculateShippingFees(shippingParams models.ShippingFeeParams, couriers []string) ([]models.ShippingFeeOption, map[string]string) {
+	timeout := 8 * time.Second
+	if v, err := strconv.Atoi(os.Getenv("SHIPPING_RATE_TIMEOUT")); err == nil && v > 0 {
+		timeout = time.Duration(v) * time.Second
 	}
 
-	if ongkirResponse.Meta.Code != 200 {
-		return nil, fmt.Errorf("API error: %s", ongkirResponse.Meta.Message)
+	type courierResult struct {
+		courier string
+		options []models.ShippingFeeOption
+		err     error
 	}
 
-	var shippingFeeOptions []models.ShippingFeeOption
-	for _, data := range ongkirResponse.Data {
-		shippingFeeOptions = append(shippingFeeOptions, models.ShippingFeeOption{
-			Service: data.Service + " (" + data.Description + ")",
-			Fee:     data.Cost,
-		})
+	results := make(chan courierResult, len(couriers))
+	for _, courier := range couriers {
+		go func(courier string) {
+			done := make(chan courierResult, 1)
+			go func() {
+				params := shippingParams
+				params.Courier = courier
+				options, err := server.CalculateShippingFee(params)
+				done <- courierResult{courier: courier, options: options, err: err}
+			}()
+
+			select {
+			case result := <-done:
+				results <- result
+			case <-time.After(timeout):
+				results <- courierResult{courier: courier, err: fmt.Errorf("timeout after %s", timeout)}
+			}
+		}(courier)
+	}
+
+	var options []models.ShippingFeeOption
+	errs := map[string]string{}
+	for range couriers {
+		result := <-results
+		if result.err != nil {
+			log.Printf("shipping rate %s: %v\n", result.courier, result.err)
+			errs[result.courier] = shippingErrorMessage(result.err)
+			continue
+		}
+		options = append(options, result.options...)
 	}
 
-	return shippingFeeOptions, nil
+	return options, errs
+}
+
+func shippingErrorMessage(err error) string {
+	if shipping.IsUnavailable(err) {
+		return shippingUnavailableMessage
+	}
+
+	var apiErr *shipping.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Message
+	}
+
+	return err.Error()
+}
+
+// SortShippingFeeOptions mengurutkan opsi berdasarkan "price" (default) atau "etd".
+// Opsi tanpa estimasi ditaruh di akhir saat diurutkan berdasarkan etd.
+func SortShippingFeeOptions(options []models.ShippingFeeOption, by string) {
+	sort.SliceStable(options, func(i, j int) bool {
+		if by == "etd" {
+			iMin, iMax := options[i].EtdDays()
+			jMin, jMax := options[j].EtdDays()
+			if (iMin < 0) != (jMin < 0) {
+				return jMin < 0
+			}
+			if iMin != jMin {
+				return iMin < jMin
+			}
+			if iMax != jMax {
+				return iMax < jMax
+			}
+		}
+
+		return options[i].Fee < options[j].Fee
+	})
 }
 
 func SetFlash(w http.ResponseWriter, r *http.Request, name string, value string) {
@@ -405,6 +714,15 @@ func (server *Server) DefaultRenderData(w http.ResponseWriter, r *http.Request,
 	// Tambahkan user saat ini (masih menggunakan session lama jika ada)
 	data["user"] = server.CurrentUser(w, r)
 
+	// Mata uang tampilan pengunjung dan daftar pilihan untuk switcher
+	// (keduanya dari cache navigasi, bukan query per halaman)
+	data["currency"] = server.VisitorCurrency(w, r)
+	currencies, navSections := server.navData()
+	data["currencies"] = append([]models.Currency{*models.BaseCurrency()}, currencies...)
+
+	// Section untuk navigasi katalog
+	data["navSections"] = navSections
+
 	return data
 }
 
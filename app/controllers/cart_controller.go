@@ -3,15 +3,12 @@ package controllers
 import (
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
-	"io/ioutil"
 	"log"
-	"math"
 	"net/http"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -19,6 +16,7 @@ import (
 
 	"github.com/codeuiprogramming/e-commerce/app/helpers"
 	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/shipping"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/shopspring/decimal"
@@ -60,21 +58,15 @@ func GetShoppingCart(db *gorm.DB, cartID string) (*models.Cart, error) {
 
 	updatedCart, _ := cart.GetCart(db, cartID)
 
-	totalWeight := 0
-	productModel := models.Product{}
-	for _, cartItem := range updatedCart.CartItems {
-		product, _ := productModel.FindByID(db, cartItem.ProductID)
+	// produk sudah di-preload oleh GetCart; berat dibulatkan per kiriman, bukan per unit
+	updatedCart.Parcel = cartParcel(db, updatedCart)
+	updatedCart.TotalWeight = updatedCart.Parcel.GrossWeight
 
-		productWeight, _ := product.Weight.Float64()
-		ceilWeight := math.Ceil(productWeight)
-
-		itemWeight := cartItem.Qty * int(ceilWeight)
-
-		totalWeight += itemWeight
+	// quote ongkir hanya berlaku untuk berat yang ditagih saat quote dibuat
+	if updatedCart.ShippingQuoteExpiresAt != nil && updatedCart.ShippingWeight != chargeableWeight(updatedCart.Parcel, updatedCart.ShippingCourier, updatedCart.TotalWeight) {
+		_ = updatedCart.ClearShippingQuote(db)
 	}
 
-	updatedCart.TotalWeight = totalWeight
-
 	return updatedCart, nil
 }
 
@@ -95,18 +87,79 @@ func (server *Server) GetCart(w http.ResponseWriter, r *http.Request) {
     cartID := GetShoppingCartID(w, r)
     cart, _ = GetShoppingCart(server.DB, cartID)
 
+    // RajaOngkir yang mati tidak boleh menjatuhkan halaman keranjang
+    shippingNotice := ""
     provinces, err := server.GetProvince()
     if err != nil {
-        log.Fatal(err)
+        log.Println("GetCart: failed to load provinces:", err)
+        shippingNotice = shippingUnavailableMessage
+    }
+
+    data := server.DefaultRenderData(w, r, map[string]interface{}{
+        "cart":           cart,
+        "items":          cart.CartItems,
+        "provinces":      provinces,
+        "shippingNotice": shippingNotice,
+        "success":        GetFlash(w, r, "success"),
+        "error":          GetFlash(w, r, "error"),
+    })
+    if user, ok := data["user"].(*models.User); ok && user != nil {
+        walletModel := models.Wallet{}
+        data["walletBalance"] = walletModel.GetBalance(server.DB, user.ID)
+    }
+
+    _ = render.HTML(w, http.StatusOK, "cart", data)
+}
+
+// APICart mengembalikan isi keranjang dalam JSON beserta rincian berat paket
+// (aktual, kardus, volume dan berat yang ditagih per kurir).
+func (server *Server) APICart(w http.ResponseWriter, r *http.Request) {
+    cartID := GetShoppingCartID(w, r)
+    cart, err := GetShoppingCart(server.DB, cartID)
+    if err != nil || cart == nil {
+        http.Error(w, "cart not found", http.StatusNotFound)
+        return
+    }
+
+    items := []map[string]interface{}{}
+    for _, item := range cart.CartItems {
+        items = append(items, map[string]interface{}{
+            "id":         item.ID,
+            "product_id": item.ProductID,
+            "name":       item.Product.Name,
+            "sku":        item.Product.Sku,
+            "qty":        item.Qty,
+            "sub_total":  item.SubTotal,
+            "weight":     item.Product.Weight,
+            "length":     item.Product.Length,
+            "width":      item.Product.Width,
+            "height":     item.Product.Height,
+        })
+    }
+
+    var quote interface{}
+    if cart.ShippingQuoteExpiresAt != nil {
+        quote = map[string]interface{}{
+            "courier":    cart.ShippingCourier,
+            "service":    cart.ShippingService,
+            "fee":        cart.ShippingFee,
+            "weight":     cart.ShippingWeight,
+            "expires_at": cart.ShippingQuoteExpiresAt,
+        }
     }
 
-    _ = render.HTML(w, http.StatusOK, "cart", server.DefaultRenderData(w, r, map[string]interface{}{
-        "cart":      cart,
-        "items":     cart.CartItems,
-        "provinces": provinces,
-        "success":   GetFlash(w, r, "success"),
-        "error":     GetFlash(w, r, "error"),
-    }))
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(Result{Code: 200, Message: "Success", Data: map[string]interface{}{
+        "id":               cart.ID,
+        "items":            items,
+        "base_total_price": cart.BaseTotalPrice,
+        "tax_amount":       cart.TaxAmount,
+        "discount_amount":  cart.DiscountAmount,
+        "grand_total":      cart.GrandTotal,
+        "total_weight":     cart.TotalWeight,
+        "weight":           server.parcelBreakdown(cart.Parcel),
+        "shipping_quote":   quote,
+    }})
 }
 
 func (server *Server) AddItemToCart(w http.ResponseWriter, r *http.Request) {
@@ -115,6 +168,10 @@ func (server *Server) AddItemToCart(w http.ResponseWriter, r *http.Request) {
 
     productModel := models.Product{}
     product, err := productModel.FindByID(server.DB, productID)
+    // produk custom sementara tidak pernah dipublikasikan
+    if err == nil && !product.IsTemporary && !product.IsPublished() {
+        err = gorm.ErrRecordNotFound
+    }
     if err != nil {
         SetFlash(w, r, "error", "Produk tidak ditemukan")
         http.Redirect(w, r, "/products", http.StatusSeeOther)
@@ -187,8 +244,8 @@ func (server *Server) GetCitiesByProvince(w http.ResponseWriter, r *http.Request
 
 	cities, err := server.GetCitiesByProvinceID(provinceID)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-    	return
+		writeShippingError(w, err)
+		return
 	}
 
 	res := Result{Code: 200, Data: cities, Message: "Success"}
@@ -207,7 +264,7 @@ func (server *Server) GetCitiesByProvince(w http.ResponseWriter, r *http.Request
 func (server *Server) GetProvinces(w http.ResponseWriter, r *http.Request) {
     provinces, err := server.GetProvince()
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+        writeShippingError(w, err)
         return
     }
 
@@ -230,45 +287,22 @@ func (server *Server) GetDistrictsByCity(w http.ResponseWriter, r *http.Request)
         http.Error(w, "city_id is required", http.StatusBadRequest)
         return
     }
-    // Try fetching districts from external API configured by API_ONGKIR_BASE_URL
-    endpoint := os.Getenv("API_ONGKIR_BASE_URL") + "destination/district/" + cityID
-    req, err := http.NewRequest("GET", endpoint, nil)
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
+    var districts interface{}
+    var err error
+    if server.regionsFromDB() {
+        regionModel := models.RegionDistrict{}
+        districts, err = regionModel.GetDistrictsByCityID(server.DB, cityID)
+    } else {
+        districts, err = server.ShippingClient().Districts(cityID)
     }
-    req.Header.Add("Key", os.Getenv("API_ONGKIR_KEY"))
-
-    client := &http.Client{Timeout: 6 * time.Second}
-    resp, err := client.Do(req)
     if err != nil {
-        // fallback: return empty list
-        res := Result{Code: 200, Data: []interface{}{}, Message: "Success"}
-        b, _ := json.Marshal(res)
-        w.Header().Set("Content-Type", "application/json")
-        w.WriteHeader(http.StatusOK)
-        w.Write(b)
-        return
-    }
-    defer resp.Body.Close()
-
-    body, _ := ioutil.ReadAll(resp.Body)
-    fmt.Println("DEBUG GetDistrictsByCity: raw response:", string(body))
-    var parsed struct {
-        Meta models.Meta                 `json:"meta"`
-        Data []map[string]interface{} `json:"data"`
-    }
-    if err := json.Unmarshal(body, &parsed); err != nil {
-        // if parsing fails, return empty list
-        res := Result{Code: 200, Data: []interface{}{}, Message: "Success"}
-        b, _ := json.Marshal(res)
-        w.Header().Set("Content-Type", "application/json")
-        w.WriteHeader(http.StatusOK)
-        w.Write(b)
+        // kecamatan bersifat opsional, jadi form tetap bisa dipakai dengan daftar kosong
+        log.Println("GetDistrictsByCity:", err)
+        writeEmptyDestinationList(w, err)
         return
     }
 
-    res := Result{Code: 200, Data: parsed.Data, Message: "Success"}
+    res := Result{Code: 200, Data: districts, Message: "Success"}
     result, err := json.Marshal(res)
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -286,61 +320,21 @@ func (server *Server) GetPostcodesByCity(w http.ResponseWriter, r *http.Request)
         http.Error(w, "city_id is required", http.StatusBadRequest)
         return
     }
-    // Try fetching postcodes from external API configured by API_ONGKIR_BASE_URL
-    // try path-style endpoint first
-    endpoint := os.Getenv("API_ONGKIR_BASE_URL") + "destination/postcode/" + cityID
-    req, err := http.NewRequest("GET", endpoint, nil)
-    if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
+    var postcodes interface{}
+    var err error
+    if server.regionsFromDB() {
+        regionModel := models.RegionPostcode{}
+        postcodes, err = regionModel.GetPostcodesByCityID(server.DB, cityID)
+    } else {
+        postcodes, err = server.ShippingClient().Postcodes(cityID)
     }
-    req.Header.Add("Key", os.Getenv("API_ONGKIR_KEY"))
-
-    client := &http.Client{Timeout: 6 * time.Second}
-    resp, err := client.Do(req)
     if err != nil {
-        res := Result{Code: 200, Data: []interface{}{}, Message: "Success"}
-        b, _ := json.Marshal(res)
-        w.Header().Set("Content-Type", "application/json")
-        w.WriteHeader(http.StatusOK)
-        w.Write(b)
-        return
-    }
-    defer resp.Body.Close()
-    body, _ := ioutil.ReadAll(resp.Body)
-    fmt.Println("DEBUG GetPostcodesByCity: raw response:", string(body))
-
-    // If we received 404 (path not supported), try query param form
-    if resp.StatusCode == http.StatusNotFound {
-        alt := os.Getenv("API_ONGKIR_BASE_URL") + "destination/postcode?city_id=" + cityID
-        fmt.Println("GetPostcodesByCity: trying alternative endpoint", alt)
-        req2, err2 := http.NewRequest("GET", alt, nil)
-        if err2 == nil {
-            req2.Header.Add("Key", os.Getenv("API_ONGKIR_KEY"))
-            resp2, err2 := client.Do(req2)
-            if err2 == nil {
-                defer resp2.Body.Close()
-                body2, _ := ioutil.ReadAll(resp2.Body)
-                fmt.Println("DEBUG GetPostcodesByCity (alt): raw response:", string(body2))
-                body = body2
-            }
-        }
-    }
-
-    var parsed struct {
-        Meta models.Meta                 `json:"meta"`
-        Data []map[string]interface{} `json:"data"`
-    }
-    if err := json.Unmarshal(body, &parsed); err != nil {
-        res := Result{Code: 200, Data: []interface{}{}, Message: "Success"}
-        b, _ := json.Marshal(res)
-        w.Header().Set("Content-Type", "application/json")
-        w.WriteHeader(http.StatusOK)
-        w.Write(b)
+        log.Println("GetPostcodesByCity:", err)
+        writeEmptyDestinationList(w, err)
         return
     }
 
-    res := Result{Code: 200, Data: parsed.Data, Message: "Success"}
+    res := Result{Code: 200, Data: postcodes, Message: "Success"}
     result, err := json.Marshal(res)
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -351,11 +345,43 @@ func (server *Server) GetPostcodesByCity(w http.ResponseWriter, r *http.Request)
     w.Write(result)
 }
 
+// writeShippingError menulis error RajaOngkir sebagai JSON: 503 jika API sedang
+// tidak bisa dihubungi (frontend menampilkan pesan degradasi), 422 untuk lainnya.
+func writeShippingError(w http.ResponseWriter, err error) {
+    code := http.StatusUnprocessableEntity
+    message := "Gagal menghitung ongkos kirim"
+    if shipping.IsUnavailable(err) {
+        code = http.StatusServiceUnavailable
+        message = shippingUnavailableMessage
+    } else if errors.Is(err, models.ErrNoFulfilmentWarehouse) {
+        message = err.Error()
+    }
+    log.Println("shipping error:", err)
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(code)
+    json.NewEncoder(w).Encode(Result{Code: code, Data: []interface{}{}, Message: message})
+}
+
+func writeEmptyDestinationList(w http.ResponseWriter, err error) {
+    message := "Success"
+    if shipping.IsUnavailable(err) {
+        message = shippingUnavailableMessage
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(http.StatusOK)
+    json.NewEncoder(w).Encode(Result{Code: 200, Data: []interface{}{}, Message: message})
+}
+
 type ShippingRequest struct {
     CityID  string `json:"city_id"`
     Courier string `json:"courier"`
+    Sort    string `json:"sort"`
 }
 
+// CalculateShipping menghitung ongkir untuk satu kurir, atau untuk semua kurir
+// yang aktif (paralel) jika courier kosong atau "all".
 func (server *Server) CalculateShipping(w http.ResponseWriter, r *http.Request) {
     body, _ := io.ReadAll(r.Body)
     log.Println("RAW BODY:", string(body)) // debug
@@ -366,30 +392,54 @@ func (server *Server) CalculateShipping(w http.ResponseWriter, r *http.Request)
         return
     }
 
-    origin := os.Getenv("API_ONGKIR_ORIGIN")
     destination := req.CityID
-    courier := req.Courier
 
     if destination == "" {
         http.Error(w, "invalid destination", http.StatusBadRequest)
         return
     }
 
+    couriers := server.EnabledCouriers()
+    if req.Courier != "" && req.Courier != "all" {
+        couriers = []string{strings.ToLower(req.Courier)}
+    }
+
     cartID := GetShoppingCartID(w, r)
     cart, _ := GetShoppingCart(server.DB, cartID)
+    if cart.ShippingCityID != "" && cart.ShippingCityID != destination {
+        _ = cart.ClearShippingQuote(server.DB)
+    }
 
-    shippingFeeOptions, err := server.CalculateShippingFee(models.ShippingFeeParams{
-        Origin:      origin,
-        Destination: destination,
-        Weight:      cart.TotalWeight,
-        Courier:     courier,
-    })
-
+    params, err := server.shippingParams(cart, destination)
     if err != nil {
-        http.Error(w, "calculation failed", http.StatusInternalServerError)
+        writeShippingError(w, err)
+        return
+    }
+
+    shippingFeeOptions, courierErrors := server.CalculateShippingFees(params, couriers)
+
+    if len(shippingFeeOptions) == 0 && len(courierErrors) > 0 {
+        code := http.StatusUnprocessableEntity
+        message := "Gagal menghitung ongkos kirim"
+        for _, msg := range courierErrors {
+            if msg == shippingUnavailableMessage {
+                code = http.StatusServiceUnavailable
+                message = shippingUnavailableMessage
+            }
+        }
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(code)
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "meta":   map[string]interface{}{"message": message, "code": code, "status": "error"},
+            "message": message,
+            "data":   []interface{}{},
+            "errors": courierErrors,
+        })
         return
     }
 
+    SortShippingFeeOptions(shippingFeeOptions, req.Sort)
+
     w.Header().Set("Content-Type", "application/json")
     json.NewEncoder(w).Encode(map[string]interface{}{
         "meta": map[string]interface{}{
@@ -398,6 +448,10 @@ func (server *Server) CalculateShipping(w http.ResponseWriter, r *http.Request)
             "status":  "success",
         },
         "data": shippingFeeOptions,
+        "errors": courierErrors,
+        "origin": server.shippingOrigin(params),
+        "weight": server.parcelBreakdown(cart.Parcel),
+        "display": displayShippingOptions(server.VisitorCurrency(w, r), shippingFeeOptions),
     })
 }
 
@@ -415,7 +469,6 @@ func (server *Server) ApplyShipping(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    origin := os.Getenv("API_ONGKIR_ORIGIN")
     destination := req.CityID
     courier := req.Courier
     shippingPackage := req.ShippingPackage
@@ -428,24 +481,39 @@ func (server *Server) ApplyShipping(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    shippingFeeOptions, err := server.CalculateShippingFee(models.ShippingFeeParams{
-        Origin:      origin,
-        Destination: destination,
-        Weight:      cart.TotalWeight,
-        Courier:     courier,
-    })
+    params, err := server.shippingParams(cart, destination)
     if err != nil {
-        http.Error(w, "invalid shipping calculation", http.StatusInternalServerError)
+        writeShippingError(w, err)
+        return
+    }
+    params.Courier = courier
+    shippingFeeOptions, err := server.CalculateShippingFee(params)
+    if err != nil {
+        writeShippingError(w, err)
         return
     }
 
-    var selectedShipping models.ShippingFeeOption
-    for _, shippingOption := range shippingFeeOptions {
-        if shippingOption.Service == shippingPackage {
-            selectedShipping = shippingOption
+    var selectedShipping *models.ShippingFeeOption
+    for i, shippingOption := range shippingFeeOptions {
+        if shippingOption.Service == shippingPackage && strings.EqualFold(shippingOption.Courier, courier) {
+            selectedShipping = &shippingFeeOptions[i]
             break
         }
     }
+    if selectedShipping == nil {
+        _ = cart.ClearShippingQuote(server.DB)
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusUnprocessableEntity)
+        json.NewEncoder(w).Encode(Result{Code: http.StatusUnprocessableEntity, Message: "Paket pengiriman tidak ditemukan, silakan hitung ulang ongkir"})
+        return
+    }
+
+    expiresAt := time.Now().Add(envMinutes("SHIPPING_QUOTE_TTL", 30*time.Minute))
+    err = cart.SaveShippingQuote(server.DB, selectedShipping.Courier, selectedShipping.Service, decimal.NewFromInt(selectedShipping.Fee), destination, params.ProvinceID, params.WarehouseID, chargeableWeight(cart.Parcel, selectedShipping.Courier, cart.TotalWeight), expiresAt)
+    if err != nil {
+        http.Error(w, "failed to save shipping quote", http.StatusInternalServerError)
+        return
+    }
 
     cartGrandTotal, _ := cart.GrandTotal.Float64()
     grandTotal := cartGrandTotal + float64(selectedShipping.Fee)
@@ -455,6 +523,15 @@ func (server *Server) ApplyShipping(w http.ResponseWriter, r *http.Request) {
         "shipping_fee": selectedShipping.Fee,
         "grand_total":  grandTotal,
         "total_weight": cart.TotalWeight,
+        "chargeable_weight": chargeableWeight(cart.Parcel, selectedShipping.Courier, cart.TotalWeight),
+        "courier":      selectedShipping.Courier,
+        "service":      selectedShipping.Service,
+        "expires_at":   expiresAt,
+        "display": displayAmounts(server.VisitorCurrency(w, r), map[string]decimal.Decimal{
+            "total_order":  cart.GrandTotal,
+            "shipping_fee": decimal.NewFromInt(selectedShipping.Fee),
+            "grand_total":  decimal.NewFromFloat(grandTotal),
+        }),
     }, Message: "Success"}
 
     w.Header().Set("Content-Type", "application/json")
@@ -562,13 +639,9 @@ func (server *Server) AddCustomToCart(w http.ResponseWriter, r *http.Request) {
             raw := payload.Design[idx+1:]
             data, err := base64.StdEncoding.DecodeString(raw)
             if err == nil {
-                // ensure directory
-                dir := "public/uploads/custom"
-                _ = os.MkdirAll(dir, 0755)
-                filename := prod.ID + ".png"
-                path := filepath.Join(dir, filename)
-                if err := ioutil.WriteFile(path, data, 0644); err == nil {
-                    designPath = "uploads/custom/" + filename
+                key := "uploads/custom/" + prod.ID + ".png"
+                if err := server.putUpload(key, data); err == nil {
+                    designPath = key
                     // create product image record
                     img := models.ProductImage{ProductID: prod.ID, Path: designPath}
                     _ = server.DB.Create(&img)
@@ -0,0 +1,464 @@
+package controllers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/helpers"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+	"github.com/unrolled/render"
+)
+
+type Breadcrumb struct {
+	Name string
+	URL  string
+}
+
+func categoryURL(category models.Category) string {
+	return "/categories/" + category.Slug
+}
+
+func sectionURL(section models.Section) string {
+	return "/sections/" + section.Slug
+}
+
+// categoryBreadcrumbs menyusun Home > Section > induk... > kategori.
+func (server *Server) categoryBreadcrumbs(category *models.Category) []Breadcrumb {
+	crumbs := []Breadcrumb{{Name: "Home", URL: "/"}}
+	if category.Section.ID != "" {
+		crumbs = append(crumbs, Breadcrumb{Name: category.Section.Name, URL: sectionURL(category.Section)})
+	}
+	for _, ancestor := range category.Ancestors(server.DB) {
+		crumbs = append(crumbs, Breadcrumb{Name: ancestor.Name, URL: categoryURL(ancestor)})
+	}
+
+	return append(crumbs, Breadcrumb{Name: category.Name})
+}
+
+func pageFromQuery(r *http.Request) int {
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page <= 0 {
+		page = 1
+	}
+
+	return page
+}
+
+// CategoryProducts menampilkan produk di kategori beserta semua subkategorinya.
+func (server *Server) CategoryProducts(w http.ResponseWriter, r *http.Request) {
+	render := render.New(render.Options{
+		Layout:     "layout",
+		Extensions: []string{".html", ".tmpl"},
+		Funcs: []template.FuncMap{
+			{
+				"FormatPrice": helpers.FormatPrice,
+			},
+		},
+	})
+
+	vars := mux.Vars(r)
+	categoryModel := models.Category{}
+	category, err := categoryModel.FindBySlug(server.DB, vars["slug"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	page := pageFromQuery(r)
+	perPage := 9
+
+	productModel := models.Product{}
+	products, totalRows, err := productModel.GetProductsByCategoryIDs(server.DB, category.DescendantIDs(server.DB), perPage, page)
+	if err != nil {
+		http.Error(w, "failed to load products", http.StatusInternalServerError)
+		return
+	}
+
+	pagination, _ := GetPaginationLinks(server.AppConfig, PaginationParams{
+		Path:        "categories/" + category.Slug,
+		TotalRows:   int32(totalRows),
+		PerPage:     int32(perPage),
+		CurrentPage: int32(page),
+	})
+
+	var children []Breadcrumb
+	for _, child := range category.GetChildren(server.DB) {
+		children = append(children, Breadcrumb{Name: child.Name, URL: categoryURL(child)})
+	}
+
+	_ = render.HTML(w, http.StatusOK, "category", server.DefaultRenderData(w, r, map[string]interface{}{
+		"title":       category.Name,
+		"breadcrumbs": server.categoryBreadcrumbs(category),
+		"children":    children,
+		"products":    products,
+		"pagination":  pagination,
+	}))
+}
+
+// SectionProducts menampilkan produk dari semua kategori di section.
+func (server *Server) SectionProducts(w http.ResponseWriter, r *http.Request) {
+	render := render.New(render.Options{
+		Layout:     "layout",
+		Extensions: []string{".html", ".tmpl"},
+		Funcs: []template.FuncMap{
+			{
+				"FormatPrice": helpers.FormatPrice,
+			},
+		},
+	})
+
+	vars := mux.Vars(r)
+	sectionModel := models.Section{}
+	section, err := sectionModel.FindBySlug(server.DB, vars["slug"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	page := pageFromQuery(r)
+	perPage := 9
+
+	productModel := models.Product{}
+	products, totalRows, err := productModel.GetProductsByCategoryIDs(server.DB, section.CategoryIDs(server.DB), perPage, page)
+	if err != nil {
+		http.Error(w, "failed to load products", http.StatusInternalServerError)
+		return
+	}
+
+	pagination, _ := GetPaginationLinks(server.AppConfig, PaginationParams{
+		Path:        "sections/" + section.Slug,
+		TotalRows:   int32(totalRows),
+		PerPage:     int32(perPage),
+		CurrentPage: int32(page),
+	})
+
+	// hanya kategori teratas yang ditampilkan sebagai navigasi
+	var children []Breadcrumb
+	for _, category := range models.BuildCategoryTree(section.Categories) {
+		children = append(children, Breadcrumb{Name: category.Name, URL: categoryURL(category)})
+	}
+
+	_ = render.HTML(w, http.StatusOK, "category", server.DefaultRenderData(w, r, map[string]interface{}{
+		"title":       section.Name,
+		"breadcrumbs": []Breadcrumb{{Name: "Home", URL: "/"}, {Name: section.Name}},
+		"children":    children,
+		"products":    products,
+		"pagination":  pagination,
+	}))
+}
+
+type sectionPayload struct {
+	Name     *string `json:"name"`
+	Slug     *string `json:"slug"`
+	Position *int    `json:"position"`
+}
+
+// APIAdminSections handles JSON list (with category tree) and create for sections
+func (server *Server) APIAdminSections(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	switch r.Method {
+	case "GET":
+		sectionModel := models.Section{}
+		sections, err := sectionModel.GetSections(server.DB)
+		if err != nil {
+			http.Error(w, "failed to load sections", http.StatusInternalServerError)
+			return
+		}
+		_ = ren.JSON(w, http.StatusOK, sections)
+		return
+	case "POST":
+		var payload sectionPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if payload.Name == nil || strings.TrimSpace(*payload.Name) == "" {
+			http.Error(w, "name is required", http.StatusBadRequest)
+			return
+		}
+		section := models.Section{Name: strings.TrimSpace(*payload.Name)}
+		if payload.Position != nil {
+			section.Position = *payload.Position
+		}
+		slugSource := section.Name
+		if payload.Slug != nil && strings.TrimSpace(*payload.Slug) != "" {
+			slugSource = *payload.Slug
+		}
+		newSlug, err := models.UniqueSlug(server.DB, &models.Section{}, slugSource, "")
+		if err != nil {
+			persistError(err)
+			_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		section.Slug = newSlug
+		if err := server.DB.Create(&section).Error; err != nil {
+			persistError(err)
+			_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		invalidateNavCache()
+		_ = ren.JSON(w, http.StatusCreated, section)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminSection handles GET/PUT/DELETE for a single section
+func (server *Server) APIAdminSection(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	sectionModel := models.Section{}
+	section, err := sectionModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		_ = ren.JSON(w, http.StatusOK, section)
+		return
+	case "PUT":
+		var payload sectionPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if payload.Name != nil && strings.TrimSpace(*payload.Name) != "" {
+			section.Name = strings.TrimSpace(*payload.Name)
+		}
+		if payload.Slug != nil && strings.TrimSpace(*payload.Slug) != "" {
+			newSlug, err := models.UniqueSlug(server.DB, &models.Section{}, *payload.Slug, section.ID)
+			if err != nil {
+				http.Error(w, "failed to update", http.StatusInternalServerError)
+				return
+			}
+			section.Slug = newSlug
+		}
+		if payload.Position != nil {
+			section.Position = *payload.Position
+		}
+		if err := server.DB.Save(section).Error; err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		invalidateNavCache()
+		_ = ren.JSON(w, http.StatusOK, section)
+		return
+	case "DELETE":
+		// kategori harus dipindah ke section lain atau dihapus dulu
+		if len(section.CategoryIDs(server.DB)) > 0 {
+			_ = ren.JSON(w, http.StatusConflict, map[string]string{"error": "section still has categories"})
+			return
+		}
+		if err := server.DB.Delete(&models.Section{}, "id = ?", section.ID).Error; err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		invalidateNavCache()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+type categoryPayload struct {
+	Name      *string `json:"name"`
+	Slug      *string `json:"slug"`
+	ParentID  *string `json:"parent_id"`
+	SectionID *string `json:"section_id"`
+	Position  *int    `json:"position"`
+}
+
+// applyCategoryPayload mengisi field kategori dari payload. Kategori teratas wajib
+// punya section; subkategori selalu mengikuti section induknya.
+func (server *Server) applyCategoryPayload(category *models.Category, payload categoryPayload) (int, string) {
+	if payload.Name != nil && strings.TrimSpace(*payload.Name) != "" {
+		category.Name = strings.TrimSpace(*payload.Name)
+	}
+	if category.Name == "" {
+		return http.StatusBadRequest, "name is required"
+	}
+	if payload.Position != nil {
+		category.Position = *payload.Position
+	}
+	if payload.SectionID != nil {
+		category.SectionID = *payload.SectionID
+	}
+	if payload.ParentID != nil {
+		if err := category.ValidateParent(server.DB, *payload.ParentID); err != nil {
+			return http.StatusUnprocessableEntity, err.Error()
+		}
+		category.ParentID = *payload.ParentID
+	}
+
+	if category.ParentID != "" {
+		categoryModel := models.Category{}
+		parent, err := categoryModel.FindByID(server.DB, category.ParentID)
+		if err != nil {
+			return http.StatusUnprocessableEntity, "parent category not found"
+		}
+		category.SectionID = parent.SectionID
+	}
+	if category.SectionID == "" {
+		return http.StatusBadRequest, "section_id is required"
+	}
+	sectionModel := models.Section{}
+	if _, err := sectionModel.FindByID(server.DB, category.SectionID); err != nil {
+		return http.StatusUnprocessableEntity, "section not found"
+	}
+
+	slugSource := ""
+	if payload.Slug != nil && strings.TrimSpace(*payload.Slug) != "" {
+		slugSource = *payload.Slug
+	} else if category.Slug == "" {
+		slugSource = category.Name
+	}
+	if slugSource != "" {
+		newSlug, err := models.UniqueSlug(server.DB, &models.Category{}, slugSource, category.ID)
+		if err != nil {
+			persistError(err)
+			return http.StatusInternalServerError, "failed to generate slug"
+		}
+		category.Slug = newSlug
+	}
+
+	return 0, ""
+}
+
+// APIAdminCategories returns the category tree (or a flat list with ?flat=1) and creates categories
+func (server *Server) APIAdminCategories(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	switch r.Method {
+	case "GET":
+		categoryModel := models.Category{}
+		categories, err := categoryModel.GetCategories(server.DB)
+		if err != nil {
+			http.Error(w, "failed to load categories", http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Query().Get("flat") == "" {
+			categories = models.BuildCategoryTree(categories)
+		}
+		_ = ren.JSON(w, http.StatusOK, categories)
+		return
+	case "POST":
+		var payload categoryPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		category := models.Category{}
+		if status, msg := server.applyCategoryPayload(&category, payload); status != 0 {
+			http.Error(w, msg, status)
+			return
+		}
+		if err := server.DB.Omit("Section").Create(&category).Error; err != nil {
+			persistError(err)
+			_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		_ = ren.JSON(w, http.StatusCreated, category)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminCategory handles GET/PUT/DELETE for a single category
+func (server *Server) APIAdminCategory(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	categoryModel := models.Category{}
+	category, err := categoryModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		category.Children = category.GetChildren(server.DB)
+		_ = ren.JSON(w, http.StatusOK, category)
+		return
+	case "PUT":
+		var payload categoryPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		previousSection := category.SectionID
+		if status, msg := server.applyCategoryPayload(category, payload); status != 0 {
+			http.Error(w, msg, status)
+			return
+		}
+		if err := server.DB.Omit("Section").Save(category).Error; err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		// turunan ikut pindah section bersama induknya
+		if category.SectionID != previousSection {
+			server.DB.Model(&models.Category{}).Where("id IN ?", category.DescendantIDs(server.DB)).Update("section_id", category.SectionID)
+		}
+		_ = ren.JSON(w, http.StatusOK, category)
+		return
+	case "DELETE":
+		if err := category.Delete(server.DB); err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminProductCategories menampilkan (GET) atau mengganti (PUT {category_ids})
+// kategori sebuah produk.
+func (server *Server) APIAdminProductCategories(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	var product models.Product
+	if err := server.DB.Where("id = ?", vars["id"]).First(&product).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	if r.Method == "PUT" {
+		var payload struct {
+			CategoryIDs []string `json:"category_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if err := product.SetCategories(server.DB, payload.CategoryIDs); err != nil {
+			persistError(err)
+			http.Error(w, "failed to update categories", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	var categories []models.Category
+	server.DB.Model(&product).Association("Categories").Find(&categories)
+	_ = ren.JSON(w, http.StatusOK, categories)
+}
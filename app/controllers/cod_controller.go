@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// CODRule membaca aturan COD dari environment:
+// COD_ENABLED, COD_MAX_ORDER_VALUE, COD_FEE, COD_ALLOWED_COURIERS,
+// COD_ALLOWED_PROVINCES dan COD_ALLOWED_CITIES (daftar dipisah koma).
+func (server *Server) CODRule() *models.CODRule {
+	maxOrderValue, _ := decimal.NewFromString(os.Getenv("COD_MAX_ORDER_VALUE"))
+	fee, _ := decimal.NewFromString(os.Getenv("COD_FEE"))
+
+	return &models.CODRule{
+		Enabled:          os.Getenv("COD_ENABLED") == "true",
+		MaxOrderValue:    maxOrderValue,
+		Fee:              fee,
+		AllowedCouriers:  splitEnvList("COD_ALLOWED_COURIERS"),
+		AllowedProvinces: splitEnvList("COD_ALLOWED_PROVINCES"),
+		AllowedCities:    splitEnvList("COD_ALLOWED_CITIES"),
+	}
+}
+
+func splitEnvList(key string) []string {
+	var values []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+
+	return values
+}
+
+// APIAdminOrderCODConfirm dipanggil admin ketika kurir sudah menyerahkan uang COD untuk satu order.
+func (server *Server) APIAdminOrderCODConfirm(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	orderModel := models.Order{}
+	order, err := orderModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	var payload struct {
+		Amount    string `json:"amount"`
+		Reference string `json:"reference"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+
+	amount := order.AmountDue()
+	if payload.Amount != "" {
+		if amount, err = decimal.NewFromString(payload.Amount); err != nil {
+			http.Error(w, "invalid amount", http.StatusBadRequest)
+			return
+		}
+	}
+	if amount.LessThan(order.AmountDue()) {
+		_ = ren.JSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "amount is less than amount due " + order.AmountDue().StringFixed(0)})
+		return
+	}
+
+	payment, err := order.ConfirmCODPayment(server.DB, amount, payload.Reference, body)
+	if err != nil {
+		_ = ren.JSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		return
+	}
+	server.handleOrderPaid(order)
+
+	_ = ren.JSON(w, http.StatusOK, payment)
+}
+
+// APIAdminCODRemittanceImport menerima file CSV setoran kurir dengan kolom
+// order_code, amount, reference dan mengonfirmasi setiap order COD di dalamnya.
+func (server *Server) APIAdminCODRemittanceImport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		http.Error(w, "failed to parse form", http.StatusBadRequest)
+		return
+	}
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	rows, err := reader.ReadAll()
+	if err != nil {
+		http.Error(w, "invalid csv: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type rowResult struct {
+		Row       int    `json:"row"`
+		OrderCode string `json:"order_code"`
+		PaymentID string `json:"payment_id,omitempty"`
+		Error     string `json:"error,omitempty"`
+	}
+
+	var results []rowResult
+	confirmed := 0
+	for i, row := range rows {
+		if i == 0 && len(row) > 0 && strings.EqualFold(strings.TrimSpace(row[0]), "order_code") {
+			continue
+		}
+
+		res := rowResult{Row: i + 1}
+		if len(row) < 2 {
+			res.Error = "expected order_code, amount, reference"
+			results = append(results, res)
+			continue
+		}
+		res.OrderCode = strings.TrimSpace(row[0])
+
+		amount, err := decimal.NewFromString(strings.TrimSpace(row[1]))
+		if err != nil {
+			res.Error = "invalid amount"
+			results = append(results, res)
+			continue
+		}
+
+		reference := ""
+		if len(row) > 2 {
+			reference = strings.TrimSpace(row[2])
+		}
+
+		var order models.Order
+		if err := server.DB.Where("code = ? OR id = ?", res.OrderCode, res.OrderCode).First(&order).Error; err != nil {
+			res.Error = "order not found"
+			results = append(results, res)
+			continue
+		}
+
+		if amount.LessThan(order.AmountDue()) {
+			res.Error = "amount is less than amount due " + order.AmountDue().StringFixed(0)
+			results = append(results, res)
+			continue
+		}
+
+		rowPayload, _ := json.Marshal(map[string]string{
+			"source":     "remittance_import",
+			"order_code": res.OrderCode,
+			"amount":     amount.String(),
+			"reference":  reference,
+		})
+		payment, err := order.ConfirmCODPayment(server.DB, amount, reference, rowPayload)
+		if err != nil {
+			res.Error = err.Error()
+			results = append(results, res)
+			continue
+		}
+
+		server.handleOrderPaid(&order)
+		res.PaymentID = payment.ID
+		confirmed++
+		results = append(results, res)
+	}
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+		"confirmed": confirmed,
+		"rows":      results,
+	})
+}
@@ -0,0 +1,270 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// VisitorCurrency mengembalikan mata uang tampilan pengunjung. Query `?currency=`
+// (dipakai API JSON) didahulukan, lalu pilihan yang tersimpan di session, lalu IDR.
+func (server *Server) VisitorCurrency(w http.ResponseWriter, r *http.Request) *models.Currency {
+	code := r.URL.Query().Get("currency")
+	if code == "" {
+		session, _ := store.Get(r, sessionCurrency)
+		if v, ok := session.Values["code"].(string); ok {
+			code = v
+		}
+	}
+
+	if code == "" || strings.EqualFold(code, models.BaseCurrencyCode) {
+		return models.BaseCurrency()
+	}
+
+	currency, ok := server.activeCurrency(code)
+	if !ok {
+		return models.BaseCurrency()
+	}
+
+	return currency
+}
+
+// SetCurrency menyimpan pilihan mata uang pengunjung di session lalu kembali ke halaman sebelumnya.
+func (server *Server) SetCurrency(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	code := strings.ToUpper(vars["code"])
+
+	if code != models.BaseCurrencyCode {
+		if _, ok := server.activeCurrency(code); !ok {
+			code = models.BaseCurrencyCode
+		}
+	}
+
+	session, _ := store.Get(r, sessionCurrency)
+	session.Values["code"] = code
+	session.Save(r, w)
+
+	back := r.Referer()
+	if back == "" {
+		back = "/"
+	}
+	http.Redirect(w, r, back, http.StatusSeeOther)
+}
+
+// displayAmounts mengonversi beberapa nominal IDR untuk respons JSON.
+func displayAmounts(currency *models.Currency, amounts map[string]decimal.Decimal) map[string]interface{} {
+	converted := map[string]interface{}{}
+	for key, amount := range amounts {
+		converted[key] = currency.Convert(amount)
+	}
+
+	return map[string]interface{}{
+		"currency": currency.Code,
+		"rate":     currency.Rate,
+		"amounts":  converted,
+	}
+}
+
+func displayShippingOptions(currency *models.Currency, options []models.ShippingFeeOption) map[string]interface{} {
+	fees := []decimal.Decimal{}
+	for _, option := range options {
+		fees = append(fees, currency.Convert(decimal.NewFromInt(option.Fee)))
+	}
+
+	return map[string]interface{}{
+		"currency": currency.Code,
+		"rate":     currency.Rate,
+		"fees":     fees,
+	}
+}
+
+const decimalsError = "decimals must be between 0 and 4"
+
+// validCurrencyDecimals membatasi jumlah desimal tampilan mata uang ke 0-4.
+func validCurrencyDecimals(decimals int) bool {
+	return decimals >= 0 && decimals <= 4
+}
+
+// APIAdminCurrencies handles JSON list and create/update for currencies
+func (server *Server) APIAdminCurrencies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	switch r.Method {
+	case "GET":
+		var currencies []models.Currency
+		server.DB.Order("code asc").Find(&currencies)
+		_ = ren.JSON(w, http.StatusOK, currencies)
+		return
+	case "POST":
+		// mata uang baru aktif kecuali is_active dikirim false
+		currency := models.Currency{IsActive: true}
+		if err := json.NewDecoder(r.Body).Decode(&currency); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		currency.Code = strings.ToUpper(strings.TrimSpace(currency.Code))
+		if len(currency.Code) != 3 || currency.Code == models.BaseCurrencyCode {
+			http.Error(w, "invalid currency code", http.StatusBadRequest)
+			return
+		}
+		if !currency.Rate.IsPositive() {
+			http.Error(w, "rate must be positive", http.StatusBadRequest)
+			return
+		}
+		if !validCurrencyDecimals(currency.Decimals) {
+			http.Error(w, decimalsError, http.StatusBadRequest)
+			return
+		}
+		if currency.Symbol == "" {
+			currency.Symbol = currency.Code
+		}
+		currency.Source = "manual"
+		if err := server.DB.Save(&currency).Error; err != nil {
+			persistError(err)
+			_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		invalidateNavCache()
+		_ = ren.JSON(w, http.StatusCreated, currency)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminCurrency handles PUT/DELETE for a single currency
+func (server *Server) APIAdminCurrency(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	currencyModel := models.Currency{}
+	currency, err := currencyModel.FindByCode(server.DB, vars["code"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "PUT":
+		var payload struct {
+			Name     *string          `json:"name"`
+			Symbol   *string          `json:"symbol"`
+			Rate     *decimal.Decimal `json:"rate"`
+			Decimals *int             `json:"decimals"`
+			IsActive *bool            `json:"is_active"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		updates := map[string]interface{}{}
+		if payload.Name != nil {
+			updates["name"] = *payload.Name
+		}
+		if payload.Symbol != nil {
+			updates["symbol"] = *payload.Symbol
+		}
+		if payload.Rate != nil {
+			if !payload.Rate.IsPositive() {
+				http.Error(w, "rate must be positive", http.StatusBadRequest)
+				return
+			}
+			updates["rate"] = *payload.Rate
+			updates["source"] = "manual"
+		}
+		if payload.Decimals != nil {
+			if !validCurrencyDecimals(*payload.Decimals) {
+				http.Error(w, decimalsError, http.StatusBadRequest)
+				return
+			}
+			updates["decimals"] = *payload.Decimals
+		}
+		if payload.IsActive != nil {
+			updates["is_active"] = *payload.IsActive
+		}
+		if err := server.DB.Model(&models.Currency{}).Where("code = ?", currency.Code).Updates(updates).Error; err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		invalidateNavCache()
+		currency, _ = currencyModel.FindByCode(server.DB, currency.Code)
+		_ = ren.JSON(w, http.StatusOK, currency)
+		return
+	case "DELETE":
+		if err := server.DB.Where("code = ?", currency.Code).Delete(&models.Currency{}).Error; err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		invalidateNavCache()
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// ImportExchangeRates mengambil kurs dari EXCHANGE_RATE_API_URL. Endpoint diharapkan
+// mengembalikan `{"rates": {"MYR": 0.00029, ...}}` dengan basis 1 IDR.
+func (server *Server) ImportExchangeRates() (int, error) {
+	endpoint := os.Getenv("EXCHANGE_RATE_API_URL")
+	if endpoint == "" {
+		return 0, fmt.Errorf("EXCHANGE_RATE_API_URL is not set")
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(endpoint)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("exchange rate API returned %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	var parsed struct {
+		Rates map[string]decimal.Decimal `json:"rates"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	for code, perIDR := range parsed.Rates {
+		code = strings.ToUpper(code)
+		if code == models.BaseCurrencyCode || !perIDR.IsPositive() {
+			continue
+		}
+
+		// simpan hanya mata uang yang sudah didaftarkan admin
+		rate := decimal.NewFromInt(1).DivRound(perIDR, 8)
+		result := server.DB.Model(&models.Currency{}).
+			Where("code = ?", code).
+			Updates(map[string]interface{}{"rate": rate, "source": "import"})
+		if result.Error != nil {
+			return imported, result.Error
+		}
+		if result.RowsAffected > 0 {
+			imported++
+		}
+	}
+	invalidateNavCache()
+
+	return imported, nil
+}
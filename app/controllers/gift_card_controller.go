@@ -0,0 +1,239 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/codeuiprogramming/e-commerce/app/helpers"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// giftCardExpiry menghitung tanggal kedaluwarsa dari GIFT_CARD_VALIDITY_DAYS (default 365 hari).
+func giftCardExpiry(now time.Time) *time.Time {
+	days := 365
+	if v, err := strconv.Atoi(os.Getenv("GIFT_CARD_VALIDITY_DAYS")); err == nil {
+		days = v
+	}
+	if days <= 0 {
+		return nil
+	}
+
+	expiresAt := now.AddDate(0, 0, days)
+	return &expiresAt
+}
+
+// issueGiftCardsForOrder membuat satu gift card untuk setiap qty item bertipe gift card
+// pada order yang sudah lunas, lalu mengirim kodenya lewat email. Aman dipanggil ulang:
+// kartu yang sudah dibuat untuk sebuah item tidak dibuat lagi.
+func (server *Server) issueGiftCardsForOrder(order *models.Order) error {
+	var items []models.OrderItem
+	if err := server.DB.Preload("Product").Where("order_id = ?", order.ID).Find(&items).Error; err != nil {
+		return err
+	}
+
+	recipient := ""
+	var customer models.OrderCustomer
+	if err := server.DB.Where("order_id = ?", order.ID).First(&customer).Error; err == nil {
+		recipient = customer.Email
+	}
+	if recipient == "" {
+		var user models.User
+		if err := server.DB.Where("id = ?", order.UserID).First(&user).Error; err == nil {
+			recipient = user.Email
+		}
+	}
+
+	giftCardModel := models.GiftCard{}
+	for _, item := range items {
+		if !item.Product.IsGiftCard() {
+			continue
+		}
+
+		// kunci baris order item supaya notifikasi paralel tidak menerbitkan kartu dua kali
+		var created []*models.GiftCard
+		err := server.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("id = ?", item.ID).
+				First(&models.OrderItem{}).Error; err != nil {
+				return err
+			}
+
+			var issued int64
+			if err := tx.Model(&models.GiftCard{}).Where("order_item_id = ?", item.ID).Count(&issued).Error; err != nil {
+				return err
+			}
+
+			for i := int(issued); i < item.Qty; i++ {
+				giftCard, err := giftCardModel.CreateGiftCard(tx, &models.GiftCard{
+					InitialAmount:  item.BasePrice,
+					ExpiresAt:      giftCardExpiry(time.Now()),
+					OrderID:        order.ID,
+					OrderItemID:    item.ID,
+					PurchaserID:    order.UserID,
+					RecipientEmail: recipient,
+				})
+				if err != nil {
+					return err
+				}
+				created = append(created, giftCard)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, giftCard := range created {
+			server.deliverGiftCard(giftCard)
+		}
+	}
+
+	return nil
+}
+
+func (server *Server) deliverGiftCard(giftCard *models.GiftCard) {
+	if giftCard.RecipientEmail == "" {
+		return
+	}
+
+	expiry := "tanpa batas waktu"
+	if giftCard.ExpiresAt != nil {
+		expiry = giftCard.ExpiresAt.Format("02 Jan 2006")
+	}
+
+	body := fmt.Sprintf("Terima kasih! Berikut gift card Anda dari %s.\n\nKode: %s\nNilai: %s\nBerlaku sampai: %s\n\nMasukkan kode ini di halaman keranjang saat checkout.",
+		server.AppConfig.AppName, giftCard.Code, helpers.FormatPrice(giftCard.InitialAmount), expiry)
+
+	// delivered_at hanya diisi bila email benar-benar terkirim
+	if err := helpers.SendMail(giftCard.RecipientEmail, "Gift card Anda", body); err != nil {
+		if !errors.Is(err, helpers.ErrMailDisabled) {
+			persistError(err)
+		}
+		return
+	}
+
+	now := time.Now()
+	server.DB.Model(&models.GiftCard{}).Where("id = ?", giftCard.ID).Update("delivered_at", &now)
+}
+
+// CheckGiftCard returns the remaining balance of a gift card code for the cart page
+func (server *Server) CheckGiftCard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	giftCardModel := models.GiftCard{}
+	giftCard, err := giftCardModel.FindByCode(server.DB, r.URL.Query().Get("code"))
+	if err != nil || !giftCard.IsRedeemable(time.Now()) {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(Result{Code: http.StatusNotFound, Message: models.ErrGiftCardNotRedeemable.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(Result{Code: http.StatusOK, Data: map[string]interface{}{
+		"code":       giftCard.Code,
+		"balance":    giftCard.Balance,
+		"expires_at": giftCard.ExpiresAt,
+	}, Message: "Success"})
+}
+
+// APIAdminGiftCards lists gift cards (GET) or issues a new one (POST)
+func (server *Server) APIAdminGiftCards(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	switch r.Method {
+	case "GET":
+		qs := r.URL.Query()
+		q := server.DB.Order("created_at desc")
+		if status := qs.Get("status"); status != "" {
+			q = q.Where("status = ?", status)
+		}
+		if code := qs.Get("code"); code != "" {
+			q = q.Where("code = ?", models.NormalizeGiftCardCode(code))
+		}
+		var giftCards []models.GiftCard
+		q.Limit(500).Find(&giftCards)
+		_ = ren.JSON(w, http.StatusOK, giftCards)
+		return
+	case "POST":
+		var payload struct {
+			Amount         decimal.Decimal `json:"amount"`
+			ExpiresAt      *time.Time      `json:"expires_at"`
+			RecipientEmail string          `json:"recipient_email"`
+			Note           string          `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if !payload.Amount.IsPositive() {
+			http.Error(w, "amount must be positive", http.StatusBadRequest)
+			return
+		}
+
+		giftCard := &models.GiftCard{
+			InitialAmount:  payload.Amount,
+			ExpiresAt:      payload.ExpiresAt,
+			RecipientEmail: payload.RecipientEmail,
+			Note:           payload.Note,
+		}
+		if giftCard.ExpiresAt == nil {
+			giftCard.ExpiresAt = giftCardExpiry(time.Now())
+		}
+		if admin := server.CurrentUser(w, r); admin != nil {
+			giftCard.IssuedBy = admin.ID
+		}
+
+		giftCardModel := models.GiftCard{}
+		giftCard, err := giftCardModel.CreateGiftCard(server.DB, giftCard)
+		if err != nil {
+			persistError(err)
+			_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		server.deliverGiftCard(giftCard)
+
+		_ = ren.JSON(w, http.StatusCreated, giftCard)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminGiftCardLiability reports the outstanding gift card balance
+func (server *Server) APIAdminGiftCardLiability(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	giftCardModel := models.GiftCard{}
+	liability, err := giftCardModel.GetLiability(server.DB, time.Now())
+	if err != nil {
+		http.Error(w, "failed to compute liability", http.StatusInternalServerError)
+		return
+	}
+
+	var byStatus []struct {
+		Status  string `json:"status"`
+		Count   int64  `json:"count"`
+		Balance string `json:"balance"`
+	}
+	server.DB.Model(&models.GiftCard{}).
+		Select("status, COUNT(*) AS count, COALESCE(SUM(balance)::text, '0') AS balance").
+		Group("status").
+		Scan(&byStatus)
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+		"liability":     liability,
+		"by_status":     byStatus,
+		"active_status": consts.GiftCardStatusActive,
+	})
+}
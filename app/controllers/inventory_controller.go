@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// lowStockThreshold membaca ambang default stok menipis dari LOW_STOCK_THRESHOLD.
+func lowStockThreshold() int {
+	if v, err := strconv.Atoi(os.Getenv("LOW_STOCK_THRESHOLD")); err == nil && v >= 0 {
+		return v
+	}
+
+	return models.DefaultLowStockThreshold
+}
+
+// stockErrorStatus memetakan error ledger ke status HTTP.
+func stockErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrInvalidStockChange), errors.Is(err, models.ErrWarehouseRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, models.ErrInsufficientStock), errors.Is(err, models.ErrInsufficientWarehouseStock):
+		return http.StatusUnprocessableEntity
+	}
+
+	return http.StatusInternalServerError
+}
+
+// APIAdminProductStockMovements menampilkan ledger stok produk (GET) atau
+// menyesuaikan stok (POST {qty: delta | set: jumlah akhir, warehouse_id,
+// reason: adjustment|return, note}).
+func (server *Server) APIAdminProductStockMovements(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	var product models.Product
+	if err := server.DB.Where("id = ?", vars["id"]).First(&product).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		page := pageFromQuery(r)
+		movements, totalRows, err := models.GetStockMovements(server.DB, product.ID, 50, page)
+		if err != nil {
+			http.Error(w, "failed to load stock movements", http.StatusInternalServerError)
+			return
+		}
+		_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+			"product_id": product.ID,
+			"stock":      product.Stock,
+			"movements":  movements,
+			"total":      totalRows,
+			"page":       page,
+		})
+		return
+	case "POST":
+		var payload struct {
+			Qty         *int   `json:"qty"`
+			Set         *int   `json:"set"`
+			WarehouseID string `json:"warehouse_id"`
+			Reason      string `json:"reason"`
+			Note        string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if (payload.Qty == nil) == (payload.Set == nil) {
+			_ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": "send either qty (delta) or set (new level)"})
+			return
+		}
+
+		reason := consts.StockReasonAdjustment
+		switch payload.Reason {
+		case "", consts.StockReasonAdjustment:
+		case consts.StockReasonReturn:
+			reason = consts.StockReasonReturn
+		default:
+			_ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": "reason must be adjustment or return"})
+			return
+		}
+
+		change := models.StockChange{
+			ProductID: product.ID,
+			Reason:    reason,
+			Note:      payload.Note,
+		}
+		if admin := server.CurrentUser(w, r); admin != nil {
+			change.Actor = admin.ID
+		}
+		var warehouse *models.Warehouse
+		if payload.WarehouseID != "" {
+			warehouseModel := models.Warehouse{}
+			found, err := warehouseModel.FindByID(server.DB, payload.WarehouseID)
+			if err != nil {
+				http.Error(w, "warehouse not found", http.StatusNotFound)
+				return
+			}
+			warehouse = found
+			change.WarehouseID = warehouse.ID
+		}
+
+		var err error
+		switch {
+		case payload.Qty != nil:
+			change.Qty = *payload.Qty
+			_, err = models.AdjustStock(server.DB, change)
+		case warehouse != nil:
+			// jumlah akhir per gudang sama dengan stock opname
+			_, err = warehouse.SetStock(server.DB, product.ID, *payload.Set, change.Actor)
+		default:
+			_, err = models.SetProductStock(server.DB, *payload.Set, change)
+		}
+		if err != nil {
+			_ = ren.JSON(w, stockErrorStatus(err), map[string]string{"error": err.Error()})
+			return
+		}
+
+		server.DB.Select("stock").Where("id = ?", product.ID).First(&product)
+		movements, _, _ := models.GetStockMovements(server.DB, product.ID, 1, 1)
+		_ = ren.JSON(w, http.StatusCreated, map[string]interface{}{
+			"product_id": product.ID,
+			"stock":      product.Stock,
+			"movements":  movements,
+		})
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminLowStock menampilkan produk yang stoknya di bawah ambang masing-masing
+// (Product.LowStockThreshold, default LOW_STOCK_THRESHOLD).
+func (server *Server) APIAdminLowStock(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	threshold := lowStockThreshold()
+	products, err := models.GetLowStockProducts(server.DB, threshold)
+	if err != nil {
+		http.Error(w, "failed to load low stock products", http.StatusInternalServerError)
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+		"default_threshold": threshold,
+		"products":          products,
+	})
+}
+
+// APIAdminOrderCancel membatalkan order yang belum terkirim dan mengembalikan
+// stoknya ({note}). Saldo wallet dikembalikan terpisah lewat
+// /api/admin/users/{id}/wallet.
+func (server *Server) APIAdminOrderCancel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	orderModel := models.Order{}
+	order, err := orderModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	var payload struct {
+		Note string `json:"note"`
+	}
+	if r.ContentLength > 0 {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+	}
+
+	actor := ""
+	if admin := server.CurrentUser(w, r); admin != nil {
+		actor = admin.ID
+	}
+	if err := order.Cancel(server.DB, actor, payload.Note); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrOrderNotCancellable) {
+			status = http.StatusConflict
+		}
+		_ = ren.JSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+		"id":     order.ID,
+		"code":   order.Code,
+		"status": order.GetStatusLabel(),
+	})
+}
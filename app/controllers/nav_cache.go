@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+)
+
+// navCacheTTL membatasi umur daftar mata uang dan section yang dipakai setiap
+// halaman, supaya perubahan dari proses lain (import kurs, instance lain) tetap
+// terlihat walau tidak lewat handler admin di proses ini.
+const navCacheTTL = time.Minute
+
+var navCache struct {
+	sync.Mutex
+	currencies []models.Currency
+	sections   []models.Section
+	expiresAt  time.Time
+}
+
+// navData mengembalikan mata uang aktif dan section navigasi dari cache,
+// memuat ulang dari database bila sudah kedaluwarsa. Slice yang dikembalikan
+// dipakai bersama dan tidak boleh diubah.
+func (server *Server) navData() ([]models.Currency, []models.Section) {
+	navCache.Lock()
+	defer navCache.Unlock()
+
+	if time.Now().Before(navCache.expiresAt) {
+		return navCache.currencies, navCache.sections
+	}
+
+	currencyModel := models.Currency{}
+	currencies, err := currencyModel.GetActiveCurrencies(server.DB)
+	if err != nil {
+		return navCache.currencies, navCache.sections
+	}
+	var sections []models.Section
+	if err := server.DB.Order("position asc, name asc").Find(&sections).Error; err != nil {
+		return navCache.currencies, navCache.sections
+	}
+
+	navCache.currencies, navCache.sections = currencies, sections
+	navCache.expiresAt = time.Now().Add(navCacheTTL)
+
+	return currencies, sections
+}
+
+// activeCurrency mencari mata uang aktif berdasarkan kode di cache navigasi.
+func (server *Server) activeCurrency(code string) (*models.Currency, bool) {
+	currencies, _ := server.navData()
+	for _, currency := range currencies {
+		if strings.EqualFold(currency.Code, code) {
+			return &currency, true
+		}
+	}
+
+	return nil, false
+}
+
+// invalidateNavCache dipanggil setelah admin mengubah mata uang atau section.
+func invalidateNavCache() {
+	navCache.Lock()
+	navCache.expiresAt = time.Time{}
+	navCache.Unlock()
+}
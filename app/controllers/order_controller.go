@@ -10,24 +10,34 @@ import (
 
 	"net/http"
 	"os"
-	"path/filepath"
+	"path"
 	"time"
 
 	"github.com/codeuiprogramming/e-commerce/app/consts"
 	"github.com/codeuiprogramming/e-commerce/app/helpers"
 	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/storage"
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
 	"github.com/midtrans/midtrans-go"
 	"github.com/midtrans/midtrans-go/snap"
 	"github.com/shopspring/decimal"
 	"github.com/unrolled/render"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
 )
 
 type CheckoutRequest struct {
 	Cart *models.Cart
 	ShippingFee *ShippingFee
 	ShippingAddress *ShippingAddress
+	PaymentMethod string
+	Currency *models.Currency
+	UseWallet bool
+	WalletAmount decimal.Decimal
+	GiftCardCode string
+	GiftCardAmount decimal.Decimal
+	Warehouse *models.Warehouse
 }
 
 type ShippingFee struct {
@@ -57,37 +67,73 @@ func (server *Server) Checkout(w http.ResponseWriter, r *http.Request) {
 
     user := server.CurrentUser(w, r)
 
-	shippingCost, err := server.getSelectedShippingCost(w,r)
+	cartID := GetShoppingCartID(w, r)
+	cart, _ := GetShoppingCart(server.DB, cartID)
+
+	shippingFee, err := server.getSelectedShippingFee(cart, r.FormValue("city_id"))
 	if err != nil {
-		SetFlash(w, r, "error", "Proses checkout gagal")
+		if errors.Is(err, models.ErrShippingQuoteMissing) || errors.Is(err, models.ErrShippingQuoteExpired) || errors.Is(err, models.ErrShippingQuoteChanged) {
+			SetFlash(w, r, "error", err.Error())
+		} else {
+			SetFlash(w, r, "error", "Proses checkout gagal")
+		}
 		http.Redirect(w, r, "/carts", http.StatusSeeOther)
 		return 
 	}
 
-	cartID := GetShoppingCartID(w, r)
-	cart, _ := GetShoppingCart(server.DB, cartID)
+	// provinsi diambil dari quote (diturunkan dari kota tujuan), bukan dari form,
+	// karena dipakai untuk order dan syarat COD
+	provinceID := cart.ShippingProvinceID
+
+	// gudang dipilih ulang saat checkout; jika berbeda dari saat ongkir dihitung, quote tidak berlaku
+	warehouse, err := server.fulfilmentWarehouse(cart, r.FormValue("city_id"), provinceID)
+	if err == nil && warehouseID(warehouse) != cart.ShippingWarehouseID {
+		err = models.ErrShippingQuoteChanged
+	}
+	if err != nil {
+		SetFlash(w, r, "error", err.Error())
+		http.Redirect(w, r, "/carts", http.StatusSeeOther)
+		return
+	}
 
 	checkoutRequest := &CheckoutRequest{
 		Cart: cart,
-		ShippingFee: &ShippingFee{
-			Courier:     r.FormValue("courier"),
-			PackageName: r.FormValue("shipping_fee"),
-			Fee:         shippingCost,
-		},
+		ShippingFee: shippingFee,
 		ShippingAddress: &ShippingAddress{
 			FirstName: r.FormValue("first_name"),
 			LastName:  r.FormValue("last_name"),
 			CityID:    r.FormValue("city_id"),
-			ProvinceID:r.FormValue("province_id"),
+			ProvinceID:provinceID,
 			Address1:  r.FormValue("address1"),
 			Address2:  r.FormValue("address2"),
 			Phone:     r.FormValue("phone"),
 			Email:     r.FormValue("email"),
 			PostCode:  r.FormValue("post_code"),
 		},
+		PaymentMethod: consts.PaymentMethodMidtrans,
+		Currency:      server.VisitorCurrency(w, r),
+		UseWallet:     r.FormValue("use_wallet") != "",
+		GiftCardCode:  models.NormalizeGiftCardCode(r.FormValue("gift_card_code")),
+		Warehouse:     warehouse,
+	}
+
+	if r.FormValue("payment_method") == consts.PaymentMethodCOD {
+		codRule := server.CODRule()
+		total := cart.GrandTotal.Add(decimal.NewFromFloat(shippingFee.Fee))
+		if err := codRule.Validate(total, checkoutRequest.ShippingFee.Courier, checkoutRequest.ShippingAddress.ProvinceID, checkoutRequest.ShippingAddress.CityID); err != nil {
+			SetFlash(w, r, "error", err.Error())
+			http.Redirect(w, r, "/carts", http.StatusSeeOther)
+			return
+		}
+		checkoutRequest.PaymentMethod = consts.PaymentMethodCOD
 	}
 
 	order, err := server.SaveOrder(user, checkoutRequest)
+	if errors.Is(err, models.ErrInsufficientWalletBalance) || errors.Is(err, models.ErrGiftCardNotRedeemable) || errors.Is(err, models.ErrInsufficientWarehouseStock) || errors.Is(err, models.ErrInsufficientStock) {
+		SetFlash(w, r, "error", err.Error())
+		http.Redirect(w, r, "/carts", http.StatusSeeOther)
+		return
+	}
 	if err != nil {
 		SetFlash(w, r, "error", "Proses checkout gagal")
 		http.Redirect(w, r, "/carts", http.StatusSeeOther)
@@ -126,6 +172,7 @@ func (server *Server) ShowOrder(w http.ResponseWriter, r *http.Request) {
 
 	if err := render.HTML(w, http.StatusOK, "show_order", server.DefaultRenderData(w, r, map[string]interface{}{
 		"order":   order,
+		"shippingRegion": order.OrderCustomer.RegionNames(server.DB),
 		"success": GetFlash(w, r, "success"),
 		"snapToken":  order.PaymentToken.String, // token dari Midtrans
 	})); err != nil {
@@ -133,49 +180,24 @@ func (server *Server) ShowOrder(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (server *Server) getSelectedShippingCost(w http.ResponseWriter, r *http.Request) (float64, error) {
-    origin := os.Getenv("API_ONGKIR_ORIGIN")
-    destination := r.FormValue("city_id")
-    courier := r.FormValue("courier")
-    shippingFeeSelected := r.FormValue("shipping_fee")
-
-    cartID := GetShoppingCartID(w, r)
-    cart, _ := GetShoppingCart(server.DB, cartID)
-
-    if destination == "" {
-        return 0, errors.New("invalid destination")
+// getSelectedShippingFee mengambil quote ongkir yang tersimpan di keranjang saat
+// paket dipilih. Nilai dari form tidak dipakai supaya ongkir tidak bisa diubah.
+func (server *Server) getSelectedShippingFee(cart *models.Cart, cityID string) (*ShippingFee, error) {
+    if cityID == "" {
+        return nil, errors.New("invalid destination")
     }
 
-    shippingFeeOptions, err := server.CalculateShippingFee(models.ShippingFeeParams{
-        Origin:      origin,
-        Destination: destination,
-        Weight:      cart.TotalWeight,
-        Courier:     courier,
-    })
-    if err != nil {
-        return 0, errors.New("failed shipping calculation")
+    if err := cart.ValidateShippingQuote(cityID, chargeableWeight(cart.Parcel, cart.ShippingCourier, cart.TotalWeight), time.Now()); err != nil {
+        return nil, err
     }
 
-    var shippingCost float64
-
-    fmt.Println(">> Dari Form:", shippingFeeSelected)
-
-    for _, option := range shippingFeeOptions {
-        // Gabungkan beberapa info untuk memastikan cocok
-		fullServiceName := fmt.Sprintf("%s - %d", option.Service, option.Fee)
-
-        // Jika value dari form mengandung nama service atau sama persis dengan format kita
-        if strings.Contains(shippingFeeSelected, option.Service) ||
-           strings.Contains(shippingFeeSelected, fullServiceName) {
+    fee, _ := cart.ShippingFee.Float64()
 
-            shippingCost = float64(option.Fee)
-            fmt.Println(">> Match ditemukan:", fullServiceName, "=>", shippingCost)
-            break
-        }
-    }
-
-    fmt.Println(">> Ongkir Terpilih Akhir:", shippingCost)
-    return shippingCost, nil
+    return &ShippingFee{
+        Courier:     cart.ShippingCourier,
+        PackageName: cart.ShippingService,
+        Fee:         fee,
+    }, nil
 }
 
 func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.Order, error) {
@@ -184,9 +206,64 @@ func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.
 
 	orderID := uuid.New().String()
 
-	paymentURL, err := server.createdPaymentURL(user, r, orderID)
-	if err != nil {
-		return nil, err
+	codFee := decimal.Zero
+	if r.PaymentMethod == consts.PaymentMethodCOD {
+		codFee = server.CODRule().Fee
+	}
+
+	orderTotal := r.Cart.BaseTotalPrice.
+		Add(r.Cart.TaxAmount).
+		Sub(r.Cart.DiscountAmount).
+		Add(decimal.NewFromFloat(r.ShippingFee.Fee))
+
+	// Gift card lalu saldo wallet dipakai lebih dulu; jika menutup seluruh total, gateway
+	// (dan biaya COD) dilewati. Saldo keduanya dicek ulang dengan row lock di dalam
+	// transaksi order sehingga tidak bisa dipakai ganda.
+	r.GiftCardAmount = decimal.Zero
+	if r.GiftCardCode != "" {
+		giftCardModel := models.GiftCard{}
+		giftCard, err := giftCardModel.FindByCode(server.DB, r.GiftCardCode)
+		if err != nil || !giftCard.IsRedeemable(time.Now()) {
+			return nil, models.ErrGiftCardNotRedeemable
+		}
+		r.GiftCardCode = giftCard.Code
+		r.GiftCardAmount = decimal.Min(giftCard.Balance, orderTotal.Add(codFee))
+	}
+
+	r.WalletAmount = decimal.Zero
+	if r.UseWallet {
+		walletModel := models.Wallet{}
+		remaining := orderTotal.Add(codFee).Sub(r.GiftCardAmount)
+		r.WalletAmount = decimal.Min(walletModel.GetBalance(server.DB, user.ID), remaining)
+	}
+
+	prepaid := r.GiftCardAmount.Add(r.WalletAmount)
+	if prepaid.IsPositive() && prepaid.GreaterThanOrEqual(orderTotal) {
+		r.PaymentMethod = consts.PaymentMethodWallet
+		if r.GiftCardAmount.IsPositive() {
+			r.PaymentMethod = consts.PaymentMethodGiftCard
+		}
+		r.GiftCardAmount = decimal.Min(r.GiftCardAmount, orderTotal)
+		r.WalletAmount = orderTotal.Sub(r.GiftCardAmount)
+		codFee = decimal.Zero
+	}
+
+	// Order COD, wallet dan gift card tidak membutuhkan Snap token; pembayaran COD dicatat saat kurir menyerahkan uang.
+	var paymentToken sql.NullString
+	orderStatus := consts.OrderStatusPending
+	paymentStatus := consts.OrderPaymentStatusUnpaid
+	switch r.PaymentMethod {
+	case consts.PaymentMethodCOD:
+		orderStatus = consts.OrderStatusReceived
+	case consts.PaymentMethodWallet, consts.PaymentMethodGiftCard:
+		orderStatus = consts.OrderStatusReceived
+		paymentStatus = consts.OrderPaymentStatusPaid
+	default:
+		paymentURL, err := server.createdPaymentURL(user, r, orderID)
+		if err != nil {
+			return nil, err
+		}
+		paymentToken = sql.NullString{String: paymentURL, Valid: true}
 	}
 
 	if len(r.Cart.CartItems) > 0 {
@@ -235,10 +312,10 @@ func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.
     UserID:              user.ID,
     OrderItems:          orderItems,
     OrderCustomer:       orderCustomer,
-    Status:              0,
+    Status:              orderStatus,
     OrderDate:           time.Now(),
     PaymentDue:          time.Now().AddDate(0, 0, 7),
-    PaymentStatus:       consts.OrderPaymentStatusUnpaid,
+    PaymentStatus:       paymentStatus,
     BaseTotalPrice:      r.Cart.BaseTotalPrice,
     TaxAmount:           r.Cart.TaxAmount,
     TaxPercent:          r.Cart.TaxPercent,
@@ -247,34 +324,111 @@ func (server *Server) SaveOrder(user *models.User, r *CheckoutRequest) (*models.
     ShippingCost:        decimal.NewFromFloat(r.ShippingFee.Fee),
     ShippingCourier:     r.ShippingFee.Courier,
     ShippingServiceName: r.ShippingFee.PackageName,
-    PaymentToken:        sql.NullString{String: paymentURL, Valid: true},
+    PaymentMethod:       r.PaymentMethod,
+    CODFee:              codFee,
+    WalletAmount:        r.WalletAmount,
+    GiftCardCode:        r.GiftCardCode,
+    GiftCardAmount:      r.GiftCardAmount,
+    PaymentToken:        paymentToken,
+    WarehouseID:         warehouseID(r.Warehouse),
+	}
+
+	// Settlement selalu IDR; mata uang tampilan hanya di-snapshot untuk referensi
+	orderData.DisplayCurrency = models.BaseCurrencyCode
+	orderData.DisplayRate = decimal.NewFromInt(1)
+	if r.Currency != nil {
+		orderData.DisplayCurrency = r.Currency.Code
+		orderData.DisplayRate = r.Currency.Rate
+	}
+
+	if orderData.PaymentMethod == "" {
+		orderData.PaymentMethod = consts.PaymentMethodMidtrans
 	}
 
 	orderData.GrandTotal = orderData.BaseTotalPrice.
     Add(orderData.TaxAmount).
     Sub(orderData.DiscountAmount).
-    Add(orderData.ShippingCost)
+    Add(orderData.ShippingCost).
+    Add(orderData.CODFee)
 
 	orderModel := models.Order{}
-	order, err := orderModel.CreateOrder(server.DB, orderData)
+	var order *models.Order
+	err := server.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = orderModel.CreateOrder(tx, orderData)
+		if err != nil {
+			return err
+		}
+
+		warehouseID := ""
+		if r.Warehouse != nil {
+			warehouseID = r.Warehouse.ID
+		}
+		if err := models.DeductOrderStock(tx, order, warehouseID, cartItemQuantities(r.Cart), user.ID); err != nil {
+			return err
+		}
+
+		paymentModel := models.Payment{}
+		if r.GiftCardAmount.IsPositive() {
+			giftCardModel := models.GiftCard{}
+			used, err := giftCardModel.Redeem(tx, r.GiftCardCode, r.GiftCardAmount, order.ID)
+			if err != nil {
+				return err
+			}
+			if !used.Equal(r.GiftCardAmount) {
+				return models.ErrGiftCardNotRedeemable
+			}
+
+			if _, err := paymentModel.CreatePayment(tx, &models.Payment{
+				OrderID:           order.ID,
+				Amount:            used,
+				TransactionID:     r.GiftCardCode,
+				TransactionStatus: consts.PaymentStatusSettlement,
+				Payload:           datatypes.JSON([]byte("{}")),
+				PaymentType:       consts.PaymentTypeGiftCard,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if r.WalletAmount.IsPositive() {
+			walletModel := models.Wallet{}
+			if _, err := walletModel.Debit(tx, user.ID, r.WalletAmount, consts.WalletReasonCheckout, order.ID, "Pembayaran order "+order.Code, user.ID); err != nil {
+				return err
+			}
+
+			if _, err := paymentModel.CreatePayment(tx, &models.Payment{
+				OrderID:           order.ID,
+				Amount:            r.WalletAmount,
+				TransactionID:     order.ID,
+				TransactionStatus: consts.PaymentStatusSettlement,
+				Payload:           datatypes.JSON([]byte("{}")),
+				PaymentType:       consts.PaymentTypeWallet,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if order.IsPaid() {
+		server.handleOrderPaid(order)
+	}
+
 	// Move design files into order-specific folder and update OrderItem.DesignPath
 	for _, oi := range order.OrderItems {
 		if oi.DesignPath == "" {
 			continue
 		}
-		src := filepath.Join("public", oi.DesignPath)
-		dir := filepath.Join("public", "uploads", "orders", order.ID)
-		_ = os.MkdirAll(dir, 0755)
-		filename := oi.ProductID + filepath.Ext(oi.DesignPath)
-		dest := filepath.Join(dir, filename)
-		if err := os.Rename(src, dest); err != nil {
-			log.Println("SaveOrder: failed move design file", src, "->", dest, "err:", err)
+		src := storage.KeyFromPath(oi.DesignPath)
+		newPath := "uploads/orders/" + order.ID + "/" + oi.ProductID + path.Ext(src)
+		if err := server.moveUpload(src, newPath); err != nil {
+			log.Println("SaveOrder: failed move design file", src, "->", newPath, "err:", err)
 		} else {
-			newPath := filepath.ToSlash(filepath.Join("uploads", "orders", order.ID, filename))
 			// update DB record for this order item
 			server.DB.Model(&models.OrderItem{}).Where("id = ?", oi.ID).Update("design_path", newPath)
 			log.Println("SaveOrder: moved design file to", newPath)
@@ -308,6 +462,8 @@ func (server *Server) createdPaymentURL(user *models.User, r *CheckoutRequest, o
 
 	// Tambahkan ShippingCost ke total pembayaran
 	totalWithShipping := r.Cart.GrandTotal.Add(decimal.NewFromFloat(r.ShippingFee.Fee))
+	// Bagian yang sudah dibayar dengan gift card atau saldo wallet tidak ditagih lewat Midtrans
+	totalWithShipping = totalWithShipping.Sub(r.GiftCardAmount).Sub(r.WalletAmount)
 
 	snapRequest := &snap.Request{
 		TransactionDetails: midtrans.TransactionDetails{
@@ -329,3 +485,11 @@ func (server *Server) createdPaymentURL(user *models.User, r *CheckoutRequest, o
 
 	return snapResponse.Token, nil
 }
+
+// handleOrderPaid dijalankan setiap kali order berubah menjadi lunas, baik lewat
+// notifikasi Midtrans, konfirmasi COD, maupun pembayaran penuh dengan wallet/gift card.
+func (server *Server) handleOrderPaid(order *models.Order) {
+	if err := server.issueGiftCardsForOrder(order); err != nil {
+		log.Println("handleOrderPaid: failed to issue gift cards for order", order.ID, "err:", err)
+	}
+}
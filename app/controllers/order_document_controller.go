@@ -0,0 +1,339 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/helpers"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/pdf"
+	"github.com/codeuiprogramming/e-commerce/app/storage"
+	"github.com/disintegration/imaging"
+	"github.com/gorilla/mux"
+)
+
+const (
+	orderDocumentAll   = "all"
+	orderDocumentSlip  = "slip"
+	orderDocumentLabel = "label"
+
+	// batas jumlah order dalam satu PDF batch
+	orderDocumentMaxBatch = 200
+)
+
+// storeSender adalah alamat pengirim untuk label, dari STORE_NAME, STORE_ADDRESS,
+// STORE_PHONE dan kota asal API_ONGKIR_ORIGIN, atau dari gudang asal order.
+type storeSender struct {
+	Name    string
+	Address string
+	City    string
+	Phone   string
+}
+
+func (server *Server) storeSender() storeSender {
+	sender := storeSender{
+		Name:    os.Getenv("STORE_NAME"),
+		Address: os.Getenv("STORE_ADDRESS"),
+		Phone:   os.Getenv("STORE_PHONE"),
+	}
+	if sender.Name == "" && server.AppConfig != nil {
+		sender.Name = server.AppConfig.AppName
+	}
+	if origin := os.Getenv("API_ONGKIR_ORIGIN"); origin != "" {
+		sender.City = models.ResolveRegionNames(server.DB, "", origin, "").City
+	}
+
+	return sender
+}
+
+func (server *Server) orderSender(order *models.Order, fallback storeSender) storeSender {
+	if order.WarehouseID == "" {
+		return fallback
+	}
+
+	warehouseModel := models.Warehouse{}
+	warehouse, err := warehouseModel.FindByID(server.DB, order.WarehouseID)
+	if err != nil {
+		return fallback
+	}
+
+	sender := storeSender{
+		Name:    fallback.Name + " - " + warehouse.Name,
+		Address: strings.TrimSpace(warehouse.Address + " " + warehouse.PostCode),
+		City:    models.ResolveRegionNames(server.DB, warehouse.ProvinceID, warehouse.CityID, "").String(),
+		Phone:   warehouse.Phone,
+	}
+	if sender.Phone == "" {
+		sender.Phone = fallback.Phone
+	}
+
+	return sender
+}
+
+// APIAdminOrderDocument mengunduh packing slip dan/atau label satu order sebagai PDF.
+// Query `type` berisi all (default), slip atau label.
+func (server *Server) APIAdminOrderDocument(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	server.writeOrderDocuments(w, []string{vars["id"]}, r.URL.Query().Get("type"))
+}
+
+// APIAdminOrderDocuments mengunduh PDF untuk beberapa order sekaligus. ID atau kode
+// order dikirim lewat `ids` (dipisah koma), baik di query maupun form POST.
+func (server *Server) APIAdminOrderDocuments(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "failed to parse form", http.StatusBadRequest)
+		return
+	}
+
+	var ids []string
+	for _, value := range r.Form["ids"] {
+		for _, id := range strings.Split(value, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		http.Error(w, "ids required", http.StatusBadRequest)
+		return
+	}
+	if len(ids) > orderDocumentMaxBatch {
+		http.Error(w, fmt.Sprintf("at most %d orders per batch", orderDocumentMaxBatch), http.StatusBadRequest)
+		return
+	}
+
+	server.writeOrderDocuments(w, ids, r.Form.Get("type"))
+}
+
+func (server *Server) writeOrderDocuments(w http.ResponseWriter, ids []string, docType string) {
+	if docType == "" {
+		docType = orderDocumentAll
+	}
+	if docType != orderDocumentAll && docType != orderDocumentSlip && docType != orderDocumentLabel {
+		http.Error(w, "invalid type", http.StatusBadRequest)
+		return
+	}
+
+	orderModel := models.Order{}
+	var orders []*models.Order
+	for _, id := range ids {
+		var found models.Order
+		if err := server.DB.Select("id").Where("id = ? OR code = ?", id, id).First(&found).Error; err != nil {
+			http.Error(w, "order not found: "+id, http.StatusNotFound)
+			return
+		}
+		order, err := orderModel.FindByID(server.DB, found.ID)
+		if err != nil {
+			http.Error(w, "order not found: "+id, http.StatusNotFound)
+			return
+		}
+		orders = append(orders, order)
+	}
+
+	doc := pdf.New()
+	sender := server.storeSender()
+	for _, order := range orders {
+		if docType != orderDocumentLabel {
+			server.addPackingSlip(doc, order)
+		}
+		if docType != orderDocumentSlip {
+			if err := server.addShippingLabel(doc, order, server.orderSender(order, sender)); err != nil {
+				persistError(err)
+				http.Error(w, "failed to render label for "+order.Code, http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		persistError(err)
+		http.Error(w, "failed to render pdf", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "orders-" + docType + ".pdf"
+	if len(orders) == 1 {
+		filename = orders[0].Code + "-" + docType + ".pdf"
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", `inline; filename="`+filename+`"`)
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+}
+
+// addPackingSlip menambah satu halaman A4 berisi daftar item order. Halaman baru
+// dibuat bila item tidak muat.
+func (server *Server) addPackingSlip(doc *pdf.Document, order *models.Order) {
+	const (
+		margin    = 40.0
+		rowHeight = 64.0
+		thumbSize = 56.0
+	)
+
+	newPage := func(first bool) (*pdf.Page, float64) {
+		page := doc.AddPage(pdf.A4Width, pdf.A4Height)
+		page.Text(margin, 56, 18, true, "PACKING SLIP")
+		page.Text(margin, 76, 10, false, "Order "+order.Code+"  |  "+order.OrderDate.Format("02 Jan 2006"))
+
+		y := 100.0
+		if first && order.OrderCustomer != nil {
+			customer := order.OrderCustomer
+			page.Text(margin, y, 10, true, "Kirim ke:")
+			y = page.TextWrap(margin, y+14, 300, 10, false, strings.TrimSpace(customer.FirstName+" "+customer.LastName))
+			y = page.TextWrap(margin, y, 300, 10, false, strings.TrimSpace(customer.Address1+" "+customer.Address2))
+			y = page.TextWrap(margin, y, 300, 10, false, customer.RegionNames(server.DB).String()+" "+customer.PostCode)
+			if order.ShippingCourier != "" {
+				page.Text(360, 114, 10, false, "Kurir: "+strings.ToUpper(order.ShippingCourier)+" "+order.ShippingServiceName)
+			}
+			y += 10
+		}
+
+		page.Line(margin, y, pdf.A4Width-margin, y, 0.8)
+		page.Text(margin, y+14, 9, true, "Desain")
+		page.Text(margin+70, y+14, 9, true, "SKU")
+		page.Text(margin+170, y+14, 9, true, "Produk")
+		page.Text(pdf.A4Width-margin-40, y+14, 9, true, "Qty")
+		page.Line(margin, y+22, pdf.A4Width-margin, y+22, 0.8)
+
+		return page, y + 30
+	}
+
+	page, y := newPage(true)
+	totalQty := 0
+	for _, item := range order.OrderItems {
+		if y+rowHeight > pdf.A4Height-margin-30 {
+			page, y = newPage(false)
+		}
+
+		if thumb := server.designThumbnail(doc, item.DesignPath); thumb != nil {
+			page.ImageFit(thumb, margin, y, thumbSize, thumbSize)
+		} else {
+			page.Rect(margin, y, thumbSize, thumbSize, false)
+			page.Text(margin+24, y+32, 9, false, "-")
+		}
+
+		name := item.Name
+		if item.CustomType != "" || item.CustomSize != "" {
+			name += " (" + strings.TrimSpace(item.CustomType+" "+item.CustomSize) + ")"
+		}
+		page.Text(margin+70, y+16, 10, false, item.Sku)
+		page.TextWrap(margin+170, y+16, 260, 10, false, name)
+		page.Text(pdf.A4Width-margin-40, y+16, 10, true, fmt.Sprintf("%d", item.Qty))
+
+		totalQty += item.Qty
+		y += rowHeight
+		page.Line(margin, y-4, pdf.A4Width-margin, y-4, 0.3)
+	}
+
+	page.Text(margin, y+14, 10, true, fmt.Sprintf("Total item: %d", totalQty))
+	if order.Note != "" {
+		page.TextWrap(margin, y+32, pdf.A4Width-margin*2, 9, false, "Catatan: "+order.Note)
+	}
+}
+
+// designThumbnail memuat file desain custom dari storage upload dan
+// memperkecilnya. File yang tidak ada atau tidak bisa dibaca dilewati.
+func (server *Server) designThumbnail(doc *pdf.Document, designPath string) *pdf.Image {
+	if designPath == "" {
+		return nil
+	}
+
+	body, err := server.Storage().Get(storage.KeyFromPath(designPath))
+	if err != nil {
+		return nil
+	}
+	img, err := imaging.Decode(body, imaging.AutoOrientation(true))
+	body.Close()
+	if err != nil {
+		return nil
+	}
+
+	thumb, err := doc.AddImage(imaging.Fit(img, 160, 160, imaging.Lanczos))
+	if err != nil {
+		return nil
+	}
+
+	return thumb
+}
+
+// addShippingLabel menambah satu halaman A6 berisi pengirim, penerima dan barcode
+// nomor resi (atau kode order bila belum ada resi).
+func (server *Server) addShippingLabel(doc *pdf.Document, order *models.Order, sender storeSender) error {
+	const margin = 16.0
+	width := pdf.A6Width - margin*2
+
+	page := doc.AddPage(pdf.A6Width, pdf.A6Height)
+
+	barcodeValue := order.Code
+	courier := strings.ToUpper(order.ShippingCourier)
+	service := order.ShippingServiceName
+	shipmentModel := models.Shipment{}
+	if shipment, err := shipmentModel.FindByOrderID(server.DB, order.ID); err == nil && shipment.TrackNumber != "" {
+		barcodeValue = shipment.TrackNumber
+		if shipment.Courier != "" {
+			courier = strings.ToUpper(shipment.Courier)
+		}
+		if shipment.Service != "" {
+			service = shipment.Service
+		}
+	}
+
+	page.Text(margin, 28, 14, true, courier)
+	page.Text(margin+90, 28, 10, false, service)
+	if order.IsCOD() {
+		page.Rect(pdf.A6Width-margin-64, 12, 64, 22, false)
+		page.Text(pdf.A6Width-margin-52, 28, 12, true, "COD")
+	}
+
+	if err := page.Barcode(margin, 42, width, 56, barcodeValue); err != nil {
+		return err
+	}
+	page.Text(margin+(width-pdf.TextWidth(barcodeValue, 10))/2, 112, 10, true, barcodeValue)
+	page.Line(margin, 122, pdf.A6Width-margin, 122, 0.8)
+
+	y := 138.0
+	page.Text(margin, y, 8, true, "PENERIMA")
+	y += 14
+	if customer := order.OrderCustomer; customer != nil {
+		y = page.TextWrap(margin, y, width, 11, true, strings.TrimSpace(customer.FirstName+" "+customer.LastName))
+		y = page.TextWrap(margin, y, width, 9, false, customer.Phone)
+		y = page.TextWrap(margin, y, width, 9, false, strings.TrimSpace(customer.Address1+" "+customer.Address2))
+		y = page.TextWrap(margin, y, width, 9, false, customer.RegionNames(server.DB).String())
+		y = page.TextWrap(margin, y, width, 9, true, customer.PostCode)
+	}
+
+	y += 6
+	page.Line(margin, y, pdf.A6Width-margin, y, 0.8)
+	y += 16
+	page.Text(margin, y, 8, true, "PENGIRIM")
+	y += 14
+	y = page.TextWrap(margin, y, width, 10, true, sender.Name)
+	y = page.TextWrap(margin, y, width, 9, false, sender.Phone)
+	y = page.TextWrap(margin, y, width, 9, false, sender.Address)
+	y = page.TextWrap(margin, y, width, 9, false, sender.City)
+
+	y += 6
+	page.Line(margin, y, pdf.A6Width-margin, y, 0.8)
+	y += 16
+
+	totalWeight := 0.0
+	totalQty := 0
+	for _, item := range order.OrderItems {
+		weight, _ := item.Weight.Float64()
+		totalWeight += weight * float64(item.Qty)
+		totalQty += item.Qty
+	}
+	page.Text(margin, y, 9, false, fmt.Sprintf("Order: %s", order.Code))
+	y += 12
+	page.Text(margin, y, 9, false, fmt.Sprintf("Berat: %.0f gram  |  Item: %d", totalWeight, totalQty))
+	if order.IsCOD() {
+		y += 16
+		page.Text(margin, y, 11, true, "Tagih COD: "+helpers.FormatPrice(order.AmountDue()))
+	}
+
+	return nil
+}
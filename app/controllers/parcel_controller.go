@@ -0,0 +1,231 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// volumetricDivisor membaca pembagi berat volume per kurir dari
+// SHIPPING_VOLUMETRIC_DIVISORS (mis. "jne:6000,lion:4000"), lalu
+// SHIPPING_VOLUMETRIC_DIVISOR, lalu 6000.
+func volumetricDivisor(courier string) int {
+	courier = strings.ToLower(strings.TrimSpace(courier))
+	for _, entry := range splitEnvList("SHIPPING_VOLUMETRIC_DIVISORS") {
+		parts := strings.SplitN(entry, ":", 2)
+		if len(parts) != 2 || strings.ToLower(strings.TrimSpace(parts[0])) != courier {
+			continue
+		}
+		if v, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil && v > 0 {
+			return v
+		}
+	}
+
+	if v, err := strconv.Atoi(os.Getenv("SHIPPING_VOLUMETRIC_DIVISOR")); err == nil && v > 0 {
+		return v
+	}
+
+	return models.DefaultVolumetricDivisor
+}
+
+// chargeableWeight adalah berat yang ditagih kurir untuk paket, atau fallback
+// bila paket belum dihitung.
+func chargeableWeight(parcel *models.Parcel, courier string, fallback int) int {
+	if parcel == nil {
+		return fallback
+	}
+
+	return parcel.ChargeableWeight(volumetricDivisor(courier))
+}
+
+// cartParcel menghitung paket dari item keranjang (produk sudah di-preload) dan
+// kardus yang aktif.
+func cartParcel(db *gorm.DB, cart *models.Cart) *models.Parcel {
+	var items []models.ParcelItem
+	for _, cartItem := range cart.CartItems {
+		items = append(items, models.ParcelItem{
+			Qty:    cartItem.Qty,
+			Weight: cartItem.Product.Weight,
+			Length: cartItem.Product.Length,
+			Width:  cartItem.Product.Width,
+			Height: cartItem.Product.Height,
+		})
+	}
+
+	boxModel := models.PackagingBox{}
+	boxes, _ := boxModel.GetActiveBoxes(db)
+
+	return models.BuildParcel(items, boxes)
+}
+
+// parcelBreakdown menyusun rincian berat untuk semua kurir aktif.
+func (server *Server) parcelBreakdown(parcel *models.Parcel) map[string]interface{} {
+	quotes := []models.ParcelQuote{}
+	if parcel != nil {
+		for _, courier := range server.EnabledCouriers() {
+			quotes = append(quotes, parcel.Quote(courier, volumetricDivisor(courier)))
+		}
+	}
+
+	return map[string]interface{}{
+		"parcel":   parcel,
+		"couriers": quotes,
+	}
+}
+
+// productMeasurementsFromForm membaca weight (gram) dan length/width/height (cm)
+// dari form admin. Field kosong atau tidak valid dilewati.
+func productMeasurementsFromForm(r *http.Request) map[string]decimal.Decimal {
+	measurements := map[string]decimal.Decimal{}
+	for _, field := range []string{"weight", "length", "width", "height"} {
+		value := strings.TrimSpace(r.FormValue(field))
+		if value == "" {
+			continue
+		}
+		if d, err := decimal.NewFromString(value); err == nil && !d.IsNegative() {
+			measurements[field] = d
+		}
+	}
+
+	return measurements
+}
+
+type packagingBoxPayload struct {
+	Code       *string  `json:"code"`
+	Name       *string  `json:"name"`
+	Length     *float64 `json:"length"`
+	Width      *float64 `json:"width"`
+	Height     *float64 `json:"height"`
+	TareWeight *int     `json:"tare_weight"`
+	MaxWeight  *int     `json:"max_weight"`
+	IsActive   *bool    `json:"is_active"`
+}
+
+func (p packagingBoxPayload) apply(box *models.PackagingBox) {
+	if p.Code != nil {
+		box.Code = strings.ToLower(strings.TrimSpace(*p.Code))
+	}
+	if p.Name != nil {
+		box.Name = *p.Name
+	}
+	if p.Length != nil {
+		box.Length = decimal.NewFromFloat(*p.Length)
+	}
+	if p.Width != nil {
+		box.Width = decimal.NewFromFloat(*p.Width)
+	}
+	if p.Height != nil {
+		box.Height = decimal.NewFromFloat(*p.Height)
+	}
+	if p.TareWeight != nil {
+		box.TareWeight = *p.TareWeight
+	}
+	if p.MaxWeight != nil {
+		box.MaxWeight = *p.MaxWeight
+	}
+	if p.IsActive != nil {
+		box.IsActive = *p.IsActive
+	}
+}
+
+func validatePackagingBox(box *models.PackagingBox) string {
+	if box.Code == "" {
+		return "code is required"
+	}
+	if !box.Length.IsPositive() || !box.Width.IsPositive() || !box.Height.IsPositive() {
+		return "length, width and height must be positive"
+	}
+	if box.TareWeight < 0 || box.MaxWeight < 0 {
+		return "weights must not be negative"
+	}
+
+	return ""
+}
+
+// APIAdminPackagingBoxes handles JSON list and create for packaging boxes
+func (server *Server) APIAdminPackagingBoxes(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	switch r.Method {
+	case "GET":
+		var boxes []models.PackagingBox
+		server.DB.Order("code asc").Find(&boxes)
+		_ = ren.JSON(w, http.StatusOK, boxes)
+		return
+	case "POST":
+		var payload packagingBoxPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		box := models.PackagingBox{IsActive: true}
+		payload.apply(&box)
+		if msg := validatePackagingBox(&box); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		if err := server.DB.Create(&box).Error; err != nil {
+			persistError(err)
+			_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		_ = ren.JSON(w, http.StatusCreated, box)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminPackagingBox handles GET/PUT/DELETE for a single packaging box
+func (server *Server) APIAdminPackagingBox(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	var box models.PackagingBox
+	if err := server.DB.Where("id = ? OR code = ?", vars["id"], vars["id"]).First(&box).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		_ = ren.JSON(w, http.StatusOK, box)
+		return
+	case "PUT":
+		var payload packagingBoxPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		payload.apply(&box)
+		if msg := validatePackagingBox(&box); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		if err := server.DB.Save(&box).Error; err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		_ = ren.JSON(w, http.StatusOK, box)
+		return
+	case "DELETE":
+		if err := server.DB.Delete(&box).Error; err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
@@ -38,59 +38,83 @@ func (server *Server) PaymentNotification(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if _, err := server.processPaymentNotification(&payload, true); err != nil {
+		var notifErr *paymentNotificationError
+		if errors.As(err, &notifErr) {
+			http.Error(w, notifErr.Message, notifErr.Code)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Response ke Midtrans
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Payment notification processed",
+		"order_id": payload.OrderID,
+		"status":   payload.TransactionStatus,
+	})
+}
+
+type paymentNotificationError struct {
+	Code    int
+	Message string
+}
+
+func (e *paymentNotificationError) Error() string {
+	return e.Message
+}
+
+// processPaymentNotification menerapkan notifikasi Midtrans ke order terkait.
+// Jika storePayment bernilai false, payload tidak disimpan ulang sebagai Payment baru
+// (dipakai saat admin memproses ulang notifikasi yang sudah tersimpan).
+func (server *Server) processPaymentNotification(payload *models.MidtransNotification, storePayment bool) (*models.Order, error) {
 	// Ambil order dari database
 	order := models.Order{}
 	found, err := order.FindByID(server.DB, payload.OrderID)
 	if err != nil {
-		http.Error(w, "Order tidak ditemukan", http.StatusNotFound)
-		return
+		return nil, &paymentNotificationError{Code: http.StatusNotFound, Message: "Order tidak ditemukan"}
 	}
 
 	// Hindari double payment
 	if found.IsPaid() {
-		http.Error(w, "Order sudah dibayar sebelumnya", http.StatusForbidden)
-		return
+		return found, &paymentNotificationError{Code: http.StatusForbidden, Message: "Order sudah dibayar sebelumnya"}
 	}
 
 	// Simpan log pembayaran
-	payment := models.Payment{}
-	amount, _ := decimal.NewFromString(payload.GrossAmount)
-	jsonPayload, _ := json.Marshal(payload)
-
-	if _, err := payment.CreatePayment(server.DB, &models.Payment{
-		OrderID:           found.ID,
-		Amount:            amount,
-		TransactionID:     payload.TransactionID,
-		TransactionStatus: payload.TransactionStatus,
-		Payload:           datatypes.JSON(jsonPayload),
-		PaymentType:       payload.PaymentType,
-	}); err != nil {
-		http.Error(w, "Gagal menyimpan pembayaran: "+err.Error(), http.StatusInternalServerError)
-		return
+	if storePayment {
+		payment := models.Payment{}
+		amount, _ := decimal.NewFromString(payload.GrossAmount)
+		jsonPayload, _ := json.Marshal(payload)
+
+		if _, err := payment.CreatePayment(server.DB, &models.Payment{
+			OrderID:           found.ID,
+			Amount:            amount,
+			TransactionID:     payload.TransactionID,
+			TransactionStatus: payload.TransactionStatus,
+			Payload:           datatypes.JSON(jsonPayload),
+			PaymentType:       payload.PaymentType,
+		}); err != nil {
+			return found, &paymentNotificationError{Code: http.StatusInternalServerError, Message: "Gagal menyimpan pembayaran: " + err.Error()}
+		}
 	}
 
 	// Update status order sesuai status Midtrans
-	if isPaymentSuccess(&payload) {
+	if isPaymentSuccess(payload) {
 		if err := found.MarkAsPaid(server.DB); err != nil {
-			http.Error(w, "Gagal update status order: "+err.Error(), http.StatusInternalServerError)
-			return
+			return found, &paymentNotificationError{Code: http.StatusInternalServerError, Message: "Gagal update status order: " + err.Error()}
 		}
 		fmt.Printf(" Order %s berhasil ditandai sebagai PAID\n", payload.OrderID)
+		server.handleOrderPaid(found)
 	} else {
-		if err := updateOrderStatus(server.DB, payload.OrderID, payload.TransactionStatus); err != nil {
-			http.Error(w, "Gagal update status order: "+err.Error(), http.StatusInternalServerError)
-			return
+		if err := updateOrderStatus(server.DB, found, payload.TransactionStatus); err != nil {
+			return found, &paymentNotificationError{Code: http.StatusInternalServerError, Message: "Gagal update status order: " + err.Error()}
 		}
 	}
 
-	// Response ke Midtrans
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message":  "Payment notification processed",
-		"order_id": payload.OrderID,
-		"status":   payload.TransactionStatus,
-	})
+	return found, nil
 }
 
 func PaymentNotificationHandler(w http.ResponseWriter, r *http.Request) {
@@ -152,8 +176,9 @@ func validateSignatureKey(payload *models.MidtransNotification, serverKey string
 	return nil
 }
 
-// Fungsi bantu update order
-func updateOrderStatus(db *gorm.DB, orderID, status string) error {
+// Fungsi bantu update order. Pembayaran yang ditolak, kedaluwarsa atau dibatalkan
+// ikut membatalkan order (stok, saldo wallet dan gift card dikembalikan).
+func updateOrderStatus(db *gorm.DB, order *models.Order, status string) error {
 	// Map status Midtrans ke status internal aplikasi
 	var mappedStatus string
 	switch status {
@@ -173,17 +198,27 @@ func updateOrderStatus(db *gorm.DB, orderID, status string) error {
 		mappedStatus = "unknown"
 	}
 
-	// Update ke tabel orders
-	// result := db.Exec(`UPDATE orders SET status = ? WHERE id = ?`, mappedStatus, orderID)
-	result := db.Exec(`UPDATE orders SET payment_status = ? WHERE id = ?`, mappedStatus, orderID)
-	if result.Error != nil {
-		return result.Error
-	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Update ke tabel orders
+		result := tx.Exec(`UPDATE orders SET payment_status = ? WHERE id = ?`, mappedStatus, order.ID)
+		if result.Error != nil {
+			return result.Error
+		}
 
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("order %s not found", orderID)
-	}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("order %s not found", order.ID)
+		}
+		order.PaymentStatus = mappedStatus
+
+		switch status {
+		case "deny", "expire", "cancel":
+			// order yang sudah dibatalkan atau dikirim dibiarkan
+			if err := order.Cancel(tx, "", "Pembayaran Midtrans "+status); err != nil && !errors.Is(err, models.ErrOrderNotCancellable) {
+				return err
+			}
+		}
 
-	fmt.Printf("Order %s diupdate ke status %s\n", orderID, mappedStatus)
-	return nil
+		fmt.Printf("Order %s diupdate ke status %s\n", order.ID, mappedStatus)
+		return nil
+	})
 }
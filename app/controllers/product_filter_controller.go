@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/shopspring/decimal"
+)
+
+var productSortLabels = map[string]string{
+	models.ProductSortFeatured:    "Unggulan",
+	models.ProductSortNewest:      "Terbaru",
+	models.ProductSortPriceAsc:    "Harga terendah",
+	models.ProductSortPriceDesc:   "Harga tertinggi",
+	models.ProductSortBestSelling: "Terlaris",
+}
+
+// priceBuckets membaca batas facet harga dari PRODUCT_PRICE_BUCKETS
+// (mis. "50000,100000,250000"), default models.DefaultPriceBuckets.
+func priceBuckets() []decimal.Decimal {
+	var buckets []decimal.Decimal
+	for _, entry := range splitEnvList("PRODUCT_PRICE_BUCKETS") {
+		if d, err := decimal.NewFromString(strings.TrimSpace(entry)); err == nil && d.IsPositive() {
+			buckets = append(buckets, d)
+		}
+	}
+	if len(buckets) == 0 {
+		return models.DefaultPriceBuckets
+	}
+
+	return buckets
+}
+
+// productFilterFromQuery membaca filter listing dari query string: category
+// (slug, termasuk subkategori), min_price, max_price, in_stock dan sort.
+// Nilai yang valid dikembalikan juga sebagai url.Values untuk link pagination.
+func (server *Server) productFilterFromQuery(q url.Values) (models.ProductFilter, url.Values) {
+	filter := models.ProductFilter{Sort: models.NormalizeProductSort(q.Get("sort"))}
+	active := url.Values{}
+
+	if slug := strings.TrimSpace(q.Get("category")); slug != "" {
+		categoryModel := models.Category{}
+		if category, err := categoryModel.FindBySlug(server.DB, slug); err == nil {
+			filter.CategoryIDs = category.DescendantIDs(server.DB)
+			active.Set("category", category.Slug)
+		}
+	}
+
+	if d, err := decimal.NewFromString(strings.TrimSpace(q.Get("min_price"))); err == nil && !d.IsNegative() {
+		filter.MinPrice = &d
+		active.Set("min_price", d.String())
+	}
+	if d, err := decimal.NewFromString(strings.TrimSpace(q.Get("max_price"))); err == nil && !d.IsNegative() {
+		filter.MaxPrice = &d
+		active.Set("max_price", d.String())
+	}
+
+	if isTruthy(q.Get("in_stock")) {
+		filter.InStock = true
+		active.Set("in_stock", "1")
+	}
+
+	if filter.Sort != models.ProductSortFeatured {
+		active.Set("sort", filter.Sort)
+	}
+
+	return filter, active
+}
+
+// productListing menjalankan filter, facet dan pagination untuk /products dan
+// /api/products.
+func (server *Server) productListing(r *http.Request, perPage int) (map[string]interface{}, error) {
+	page := pageFromQuery(r)
+	filter, active := server.productFilterFromQuery(r.URL.Query())
+
+	productModel := models.Product{}
+	products, totalRows, err := productModel.FilterProducts(server.DB, filter, perPage, page)
+	if err != nil {
+		return nil, err
+	}
+
+	facets, err := productModel.GetProductFacets(server.DB, filter, priceBuckets())
+	if err != nil {
+		return nil, err
+	}
+
+	pagination, _ := GetPaginationLinks(server.AppConfig, PaginationParams{
+		Path:        "products",
+		TotalRows:   int32(totalRows),
+		PerPage:     int32(perPage),
+		CurrentPage: int32(page),
+		Query:       active,
+	})
+
+	return map[string]interface{}{
+		"products":   products,
+		"total":      totalRows,
+		"page":       page,
+		"per_page":   perPage,
+		"pagination": pagination,
+		"facets":     facets,
+		"filters":    active,
+		"sort":       filter.Sort,
+		"sorts":      models.ProductSorts,
+	}, nil
+}
+
+// APIProducts handles GET /api/products dengan filter dan facet yang sama
+// seperti halaman /products.
+func (server *Server) APIProducts(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	listing, err := server.productListing(r, 9)
+	if err != nil {
+		_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to load products"})
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, listing)
+}
+
+// facetLink adalah satu pilihan facet di sidebar halaman produk.
+type facetLink struct {
+	Label  string
+	Count  int64
+	URL    string
+	Active bool
+}
+
+// listingURL membuat URL /products dengan filter aktif yang diganti oleh
+// overrides (nilai kosong berarti filter dihapus). Halaman selalu kembali ke 1.
+func listingURL(active url.Values, overrides map[string]string) string {
+	query := url.Values{}
+	for key, values := range active {
+		query[key] = values
+	}
+	for key, value := range overrides {
+		if value == "" {
+			query.Del(key)
+			continue
+		}
+		query.Set(key, value)
+	}
+	if len(query) == 0 {
+		return "/products"
+	}
+
+	return "/products?" + query.Encode()
+}
+
+// productFacetLinks menyusun link facet kategori dan harga untuk template.
+func productFacetLinks(active url.Values, facets *models.ProductFacets) ([]facetLink, []facetLink) {
+	categories := []facetLink{}
+	for _, category := range facets.Categories {
+		selected := active.Get("category") == category.Slug
+		link := facetLink{Label: category.Name, Count: category.Count, Active: selected}
+		if selected {
+			link.URL = listingURL(active, map[string]string{"category": ""})
+		} else {
+			link.URL = listingURL(active, map[string]string{"category": category.Slug})
+		}
+		categories = append(categories, link)
+	}
+
+	prices := []facetLink{}
+	for _, bucket := range facets.PriceBuckets {
+		// bucket [Min, Max) dipetakan ke min_price..max_price (inklusif), max dikurangi 1 sen
+		min := bucket.Min.String()
+		max := ""
+		label := "≥ " + bucket.Min.StringFixed(0)
+		if bucket.Max != nil {
+			max = bucket.Max.Sub(decimal.New(1, -2)).String()
+			label = bucket.Min.StringFixed(0) + " – " + bucket.Max.StringFixed(0)
+		}
+		if bucket.Min.IsZero() {
+			min = ""
+		}
+		selected := active.Get("min_price") == min && active.Get("max_price") == max
+		link := facetLink{Label: label, Count: bucket.Count, Active: selected}
+		if selected {
+			link.URL = listingURL(active, map[string]string{"min_price": "", "max_price": ""})
+		} else {
+			link.URL = listingURL(active, map[string]string{"min_price": min, "max_price": max})
+		}
+		prices = append(prices, link)
+	}
+
+	return categories, prices
+}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+)
+
+// format gambar produk yang diterima, dideteksi dari isi file (bukan ekstensi)
+var productImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// productImageMaxSize membaca batas ukuran upload dari PRODUCT_IMAGE_MAX_SIZE_MB
+// (default 10).
+func productImageMaxSize() int64 {
+	if v, err := strconv.Atoi(os.Getenv("PRODUCT_IMAGE_MAX_SIZE_MB")); err == nil && v > 0 {
+		return int64(v) << 20
+	}
+
+	return 10 << 20
+}
+
+// productImageMaxDimension membaca batas sisi terpanjang dari
+// PRODUCT_IMAGE_MAX_DIMENSION (default 8000 piksel), untuk menolak file kecil
+// yang didekode menjadi gambar raksasa.
+func productImageMaxDimension() int {
+	if v, err := strconv.Atoi(os.Getenv("PRODUCT_IMAGE_MAX_DIMENSION")); err == nil && v > 0 {
+		return v
+	}
+
+	return 8000
+}
+
+// storeProductImage memvalidasi upload lalu menyimpannya di storage dengan
+// key uploads/images/{2 karakter hash}/{hash}. File yang sama hanya disimpan
+// sekali walaupun dipakai beberapa produk.
+func (server *Server) storeProductImage(data []byte) (*models.ProductImage, int, error) {
+	mimeType := http.DetectContentType(data)
+	ext, ok := productImageTypes[mimeType]
+	if !ok {
+		return nil, http.StatusUnsupportedMediaType, errors.New("image must be JPG, PNG, GIF or WebP")
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("image could not be decoded")
+	}
+	maxDimension := productImageMaxDimension()
+	if config.Width > maxDimension || config.Height > maxDimension {
+		return nil, http.StatusBadRequest, fmt.Errorf("image must be at most %dx%d pixels", maxDimension, maxDimension)
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	key := "uploads/images/" + hash[:2] + "/" + hash + ext
+	exists, err := server.Storage().Exists(key)
+	if err != nil {
+		return nil, http.StatusInternalServerError, err
+	}
+	if !exists {
+		if err := server.Storage().Put(key, bytes.NewReader(data), mimeType); err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+	}
+
+	return &models.ProductImage{
+		Path:     key,
+		Hash:     hash,
+		MimeType: mimeType,
+		Size:     int64(len(data)),
+		Width:    config.Width,
+		Height:   config.Height,
+		Status:   consts.ProductImageStatusPending,
+	}, http.StatusOK, nil
+}
+
+// APIAdminProductImages menampilkan gambar produk sesuai urutan (GET) atau
+// mengunggah gambar baru (POST multipart: image, alt_text, is_primary).
+// Varian ukuran dibuat di background; upload file yang sama dikembalikan
+// sebagai gambar yang sudah ada.
+func (server *Server) APIAdminProductImages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	var product models.Product
+	if err := server.DB.Select("id").Where("id = ?", vars["id"]).First(&product).Error; err != nil {
+		http.Error(w, "product not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		images, err := models.GetProductImages(server.DB, product.ID)
+		if err != nil {
+			http.Error(w, "failed to load images", http.StatusInternalServerError)
+			return
+		}
+		_ = ren.JSON(w, http.StatusOK, images)
+		return
+	case "POST":
+		maxSize := productImageMaxSize()
+		// sisakan ruang untuk field form lain di luar file
+		r.Body = http.MaxBytesReader(w, r.Body, maxSize+(1<<20))
+		if err := r.ParseMultipartForm(maxSize); err != nil {
+			_ = ren.JSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("upload must be at most %dMB", maxSize>>20)})
+			return
+		}
+		file, _, err := r.FormFile("image")
+		if err != nil {
+			http.Error(w, "image file required", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, maxSize+1))
+		if err != nil {
+			http.Error(w, "failed to read image", http.StatusBadRequest)
+			return
+		}
+		if int64(len(data)) > maxSize {
+			_ = ren.JSON(w, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("image must be at most %dMB", maxSize>>20)})
+			return
+		}
+
+		img, status, err := server.storeProductImage(data)
+		if err != nil {
+			if status == http.StatusInternalServerError {
+				persistError(err)
+			}
+			_ = ren.JSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+
+		if existing, err := models.FindProductImageByHash(server.DB, product.ID, img.Hash); err == nil {
+			_ = ren.JSON(w, http.StatusOK, existing)
+			return
+		}
+
+		img.ProductID = product.ID
+		img.AltText = strings.TrimSpace(r.FormValue("alt_text"))
+		img.IsPrimary = isTruthy(r.FormValue("is_primary"))
+		if err := models.CreateProductImage(server.DB, img); err != nil {
+			persistError(err)
+			http.Error(w, "failed to create image record", http.StatusInternalServerError)
+			return
+		}
+		enqueueProductImage(img.ID)
+
+		_ = ren.JSON(w, http.StatusAccepted, img)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminProductImage menampilkan (GET), mengubah alt text / gambar utama
+// (PUT {alt_text, is_primary: true}) atau menghapus satu gambar beserta filenya
+// (DELETE).
+func (server *Server) APIAdminProductImage(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	img, err := models.FindProductImage(server.DB, vars["id"], vars["imageID"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		_ = ren.JSON(w, http.StatusOK, img)
+		return
+	case "PUT":
+		var payload struct {
+			AltText   *string `json:"alt_text"`
+			IsPrimary *bool   `json:"is_primary"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if payload.IsPrimary != nil && !*payload.IsPrimary && img.IsPrimary {
+			_ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": "set another image as primary instead"})
+			return
+		}
+
+		if payload.AltText != nil {
+			altText := strings.TrimSpace(*payload.AltText)
+			if len(altText) > 255 {
+				_ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": "alt_text must be at most 255 characters"})
+				return
+			}
+			if err := server.DB.Model(&models.ProductImage{}).Where("id = ?", img.ID).Update("alt_text", altText).Error; err != nil {
+				http.Error(w, "failed to update", http.StatusInternalServerError)
+				return
+			}
+			img.AltText = altText
+		}
+		if payload.IsPrimary != nil && *payload.IsPrimary && !img.IsPrimary {
+			if err := img.SetPrimary(server.DB); err != nil {
+				http.Error(w, "failed to update", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		_ = ren.JSON(w, http.StatusOK, img)
+		return
+	case "DELETE":
+		orphaned, err := img.Delete(server.DB)
+		if err != nil {
+			persistError(err)
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		if orphaned {
+			server.removeProductImageFiles(img)
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminProductImageOrder menyimpan urutan gambar produk ({ids: [...]}, berisi
+// semua gambar produk). Gambar utama tetap tampil pertama.
+func (server *Server) APIAdminProductImageOrder(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	var product models.Product
+	if err := server.DB.Select("id").Where("id = ?", vars["id"]).First(&product).Error; err != nil {
+		http.Error(w, "product not found", http.StatusNotFound)
+		return
+	}
+
+	var payload struct {
+		IDs []string `json:"ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	if err := models.ReorderProductImages(server.DB, product.ID, payload.IDs); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidImageOrder) {
+			status = http.StatusBadRequest
+		}
+		_ = ren.JSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	images, err := models.GetProductImages(server.DB, product.ID)
+	if err != nil {
+		http.Error(w, "failed to load images", http.StatusInternalServerError)
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, images)
+}
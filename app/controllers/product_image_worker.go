@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/storage"
+	"github.com/disintegration/imaging"
+	// imaging hanya membaca JPEG/PNG/GIF/BMP/TIFF; decoder WebP untuk upload .webp
+	_ "golang.org/x/image/webp"
+)
+
+// varian yang dibuat worker, lebar dalam piksel (tinggi mengikuti rasio)
+var productImageVariants = []struct {
+	Name  string
+	Width int
+}{
+	{"extra_large", 1600},
+	{"large", 1024},
+	{"medium", 512},
+	{"small", 128},
+}
+
+// antrian id gambar untuk worker; jika penuh, gambar tetap pending dan diambil
+// oleh sweep berikutnya
+var productImageQueue = make(chan string, 256)
+
+var cwebpOnce sync.Once
+var cwebpBin string
+
+func enqueueProductImage(id string) {
+	select {
+	case productImageQueue <- id:
+	default:
+		log.Println("product image worker: queue full, leaving", id, "for the next sweep")
+	}
+}
+
+// StartProductImageWorker menjalankan PRODUCT_IMAGE_WORKERS worker (default 2)
+// untuk membuat varian gambar produk, ditambah sweep setiap
+// PRODUCT_IMAGE_SWEEP_INTERVAL menit (default 5) untuk gambar pending yang
+// belum masuk antrian.
+func (server *Server) StartProductImageWorker() {
+	workers := 2
+	if v, err := strconv.Atoi(os.Getenv("PRODUCT_IMAGE_WORKERS")); err == nil && v > 0 {
+		workers = v
+	}
+
+	// gambar yang sedang diproses saat server berhenti diulang dari awal
+	if requeued, err := models.RequeueProductImages(server.DB, false); err != nil {
+		log.Println("product image worker:", err)
+	} else if requeued > 0 {
+		log.Printf("product image worker: requeued %d interrupted images\n", requeued)
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for id := range productImageQueue {
+				if err := server.ProcessProductImage(id); err != nil {
+					log.Printf("product image worker: %s: %v\n", id, err)
+				}
+			}
+		}()
+	}
+
+	interval := envMinutes("PRODUCT_IMAGE_SWEEP_INTERVAL", 5*time.Minute)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for ; ; <-ticker.C {
+			ids, err := models.PendingProductImageIDs(server.DB, cap(productImageQueue))
+			if err != nil {
+				log.Println("product image worker:", err)
+				continue
+			}
+			for _, id := range ids {
+				enqueueProductImage(id)
+			}
+		}
+	}()
+}
+
+// ProcessProductImage membuat varian ukuran (dan WebP jika tersedia) untuk satu
+// gambar pending. Gambar yang sudah diambil worker lain dilewati.
+func (server *Server) ProcessProductImage(id string) error {
+	img, err := models.ClaimProductImage(server.DB, id)
+	if err != nil || img == nil {
+		return err
+	}
+
+	// file yang sama (hash sama) sudah pernah diproses untuk produk lain
+	if img.Hash != "" {
+		if processed, err := models.FindProcessedImage(server.DB, img.Hash, img.ID); err == nil && server.productImageFilesExist(processed) {
+			img.CopyVariants(processed)
+			return img.FinishProcessing(server.DB, nil)
+		}
+	}
+
+	processErr := server.buildProductImageVariants(img)
+	if err := img.FinishProcessing(server.DB, processErr); err != nil {
+		return err
+	}
+
+	return processErr
+}
+
+func (server *Server) buildProductImageVariants(img *models.ProductImage) error {
+	key := storage.KeyFromPath(img.Path)
+	body, err := server.Storage().Get(key)
+	if err != nil {
+		return fmt.Errorf("original file: %w", err)
+	}
+	src, err := imaging.Decode(body, imaging.AutoOrientation(true))
+	body.Close()
+	if err != nil {
+		return err
+	}
+
+	// imaging tidak bisa menulis WebP, varian WebP/GIF disimpan sebagai JPEG/PNG
+	ext := strings.ToLower(path.Ext(key))
+	switch ext {
+	case ".webp", ".jpeg":
+		ext = ".jpg"
+	case ".gif":
+		ext = ".png"
+	}
+	format, err := imaging.FormatFromExtension(ext)
+	if err != nil {
+		return err
+	}
+	base := strings.TrimSuffix(key, path.Ext(key))
+
+	// varian WebP yang gagal dibuat dilaporkan lewat Warning, gambar tetap ready
+	var webpErr error
+	for _, variant := range productImageVariants {
+		var dst image.Image = src
+		// gambar kecil tidak diperbesar
+		if src.Bounds().Dx() > variant.Width {
+			dst = imaging.Resize(src, variant.Width, 0, imaging.Lanczos)
+		}
+
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, dst, format, imaging.JPEGQuality(85)); err != nil {
+			return fmt.Errorf("%s: %w", variant.Name, err)
+		}
+		rel := base + "_" + variant.Name + ext
+		if err := server.putUpload(rel, buf.Bytes()); err != nil {
+			return fmt.Errorf("%s: %w", variant.Name, err)
+		}
+
+		webp := ""
+		if data, err := encodeWebP(buf.Bytes(), ext); err == nil {
+			webp = base + "_" + variant.Name + ".webp"
+			if err := server.putUpload(webp, data); err != nil {
+				return fmt.Errorf("%s webp: %w", variant.Name, err)
+			}
+		} else if webpErr == nil {
+			webpErr = fmt.Errorf("%s: %w", variant.Name, err)
+			if !errors.Is(err, errWebPUnavailable) {
+				log.Printf("product image worker: %s webp: %v\n", img.ID, err)
+			}
+		}
+
+		switch variant.Name {
+		case "extra_large":
+			img.ExtraLarge, img.WebpExtraLarge = rel, webp
+		case "large":
+			img.Large, img.WebpLarge = rel, webp
+		case "medium":
+			img.Medium, img.WebpMedium = rel, webp
+		case "small":
+			img.Small, img.WebpSmall = rel, webp
+		}
+	}
+
+	img.Warning = ""
+	if errors.Is(webpErr, errWebPUnavailable) {
+		img.Warning = "WebP variants skipped: cwebp not found (install libwebp-tools or set CWEBP_PATH)"
+	} else if webpErr != nil {
+		img.Warning = "WebP variants incomplete: " + webpErr.Error()
+	}
+
+	return nil
+}
+
+var errWebPUnavailable = errors.New("webp encoder not available")
+
+// encodeWebP mengonversi varian ke WebP dengan cwebp (CWEBP_PATH atau cwebp di
+// PATH). Go dan imaging hanya bisa membaca WebP, jadi tanpa cwebp varian WebP
+// dilewati dan halaman memakai varian JPEG/PNG.
+func encodeWebP(data []byte, ext string) ([]byte, error) {
+	cwebpOnce.Do(func() {
+		bin := os.Getenv("CWEBP_PATH")
+		if bin == "" {
+			bin = "cwebp"
+		}
+		if found, err := exec.LookPath(bin); err == nil {
+			cwebpBin = found
+		} else {
+			log.Println("product image worker: cwebp not found, skipping WebP variants")
+		}
+	})
+	if cwebpBin == "" {
+		return nil, errWebPUnavailable
+	}
+
+	// cwebp hanya bekerja dengan file, varian ditulis ke direktori sementara
+	dir, err := os.MkdirTemp("", "product-image-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "variant"+ext)
+	dst := filepath.Join(dir, "variant.webp")
+	if err := os.WriteFile(src, data, 0600); err != nil {
+		return nil, err
+	}
+
+	quality := "80"
+	if v, err := strconv.Atoi(os.Getenv("PRODUCT_IMAGE_WEBP_QUALITY")); err == nil && v > 0 && v <= 100 {
+		quality = strconv.Itoa(v)
+	}
+	out, err := exec.Command(cwebpBin, "-quiet", "-q", quality, src, "-o", dst).CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(out)))
+	}
+
+	return os.ReadFile(dst)
+}
+
+func (server *Server) productImageFilesExist(img *models.ProductImage) bool {
+	for _, variant := range []string{img.ExtraLarge, img.Large, img.Medium, img.Small} {
+		if variant == "" {
+			return false
+		}
+		if exists, err := server.Storage().Exists(storage.KeyFromPath(variant)); err != nil || !exists {
+			return false
+		}
+	}
+
+	return true
+}
+
+// removeProductImageFiles menghapus file asli dan semua varian. Hanya file di
+// bawah uploads/ yang dihapus; gambar bawaan tema (img/...) dibiarkan.
+func (server *Server) removeProductImageFiles(img *models.ProductImage) {
+	paths := []string{
+		img.Path, img.ExtraLarge, img.Large, img.Medium, img.Small,
+		img.WebpExtraLarge, img.WebpLarge, img.WebpMedium, img.WebpSmall,
+	}
+	for _, p := range paths {
+		if !storage.IsUploadKey(p) {
+			continue
+		}
+		if err := server.Storage().Delete(storage.KeyFromPath(p)); err != nil {
+			log.Println("remove product image:", err)
+		}
+	}
+}
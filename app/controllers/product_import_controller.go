@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	// file dengan baris lebih banyak dari ini selalu diproses di background
+	productImportSyncLimit = 100
+	productImportMaxSize   = 20 << 20
+	importImageMaxSize     = 10 << 20
+)
+
+var importImageClient = &http.Client{Timeout: 30 * time.Second}
+
+// parseProductImportFile membaca CSV atau XLSX (sheet pertama) menjadi record
+// berdasarkan header. Kolom yang tidak dikenal diabaikan.
+func parseProductImportFile(filename string, data []byte) ([]models.ProductImportRecord, error) {
+	var rows [][]string
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".xlsx":
+		f, err := excelize.OpenReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx: %w", err)
+		}
+		defer f.Close()
+		sheets := f.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("xlsx has no sheets")
+		}
+		rows, err = f.GetRows(sheets[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid xlsx: %w", err)
+		}
+	case ".csv":
+		reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))))
+		reader.FieldsPerRecord = -1
+		var err error
+		rows, err = reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("invalid csv: %w", err)
+		}
+	default:
+		return nil, errors.New("file must be .csv or .xlsx")
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("file is empty")
+	}
+
+	known := map[string]bool{}
+	for _, column := range models.ProductImportColumns {
+		known[column] = true
+	}
+	header := make([]string, len(rows[0]))
+	hasSku := false
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if known[name] {
+			header[i] = name
+			hasSku = hasSku || name == "sku"
+		}
+	}
+	if !hasSku {
+		return nil, errors.New("header row must contain a sku column")
+	}
+
+	var records []models.ProductImportRecord
+	for i, row := range rows[1:] {
+		record := models.ProductImportRecord{Row: i + 2, Values: map[string]string{}}
+		empty := true
+		for j, cell := range row {
+			if j >= len(header) || header[j] == "" {
+				continue
+			}
+			record.Values[header[j]] = cell
+			empty = empty && strings.TrimSpace(cell) == ""
+		}
+		if !empty {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+// fetchImportImage menyimpan gambar dari URL ke storage upload
+// (uploads/products/{id}/...), atau memastikan path lokal ada di storage atau di
+// assets/ / public/ (path lokal disimpan apa adanya supaya hasil export bisa
+// di-import ulang tanpa duplikat).
+func (server *Server) fetchImportImage(productID string, source string) (string, error) {
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		key := storage.KeyFromPath(source)
+		if storage.IsUploadKey(key) {
+			if exists, err := server.Storage().Exists(key); err == nil && exists {
+				return source, nil
+			}
+		}
+		for _, dir := range []string{"assets", "public"} {
+			if _, err := os.Stat(filepath.Join(dir, key)); err == nil {
+				return source, nil
+			}
+		}
+		return "", errors.New("file not found")
+	}
+
+	resp, err := importImageClient.Get(source)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, importImageMaxSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > importImageMaxSize {
+		return "", errors.New("image is larger than 10MB")
+	}
+	ext, ok := reviewPhotoTypes[http.DetectContentType(data)]
+	if !ok {
+		return "", errors.New("not a JPG/PNG/GIF/WebP image")
+	}
+
+	key := "uploads/products/" + productID + "/import_" + uuid.New().String() + ext
+	if err := server.putUpload(key, data); err != nil {
+		return "", err
+	}
+
+	return key, nil
+}
+
+// runProductImportJob menjalankan import di background dan mencatat progres.
+func (server *Server) runProductImportJob(job *models.ProductImportJob, records []models.ProductImportRecord) {
+	now := time.Now()
+	job.Status = consts.ImportJobStatusRunning
+	job.StartedAt = &now
+	server.DB.Save(job)
+
+	summary, err := models.ImportProducts(server.DB, records, models.ProductImportOptions{
+		DryRun:     job.DryRun,
+		FetchImage: server.fetchImportImage,
+		JobID:      job.ID,
+		Actor:      job.CreatedBy,
+		Progress: func(done int, summary *models.ProductImportSummary) {
+			if done%25 == 0 {
+				_ = job.SaveProgress(server.DB, done, summary)
+			}
+		},
+	})
+	if err != nil {
+		log.Printf("product import %s: %v", job.ID, err)
+	}
+	if err := job.Finish(server.DB, summary, err); err != nil {
+		log.Printf("product import %s: failed to save report: %v", job.ID, err)
+	}
+}
+
+// APIAdminProductImport menerima file CSV/XLSX (field "file") untuk upsert
+// produk berdasarkan SKU. dry_run=1 hanya memvalidasi; async=1 atau file besar
+// dijalankan sebagai job (202 + id job).
+func (server *Server) APIAdminProductImport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	if err := r.ParseMultipartForm(productImportMaxSize); err != nil {
+		http.Error(w, "failed to parse form", http.StatusBadRequest)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "file required", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, productImportMaxSize+1))
+	if err != nil || len(data) > productImportMaxSize {
+		http.Error(w, "file too large", http.StatusBadRequest)
+		return
+	}
+
+	records, err := parseProductImportFile(header.Filename, data)
+	if err != nil {
+		_ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	dryRun := isTruthy(r.FormValue("dry_run"))
+	if len(records) > productImportSyncLimit || isTruthy(r.FormValue("async")) {
+		job := &models.ProductImportJob{
+			Filename: header.Filename,
+			DryRun:   dryRun,
+			Total:    len(records),
+		}
+		if admin := server.CurrentUser(w, r); admin != nil {
+			job.CreatedBy = admin.ID
+		}
+		if err := server.DB.Create(job).Error; err != nil {
+			persistError(err)
+			_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		// salin sebelum goroutine mulai mengubah job
+		queued := *job
+		go server.runProductImportJob(job, records)
+
+		_ = ren.JSON(w, http.StatusAccepted, queued)
+		return
+	}
+
+	opts := models.ProductImportOptions{
+		DryRun:     dryRun,
+		FetchImage: server.fetchImportImage,
+	}
+	if admin := server.CurrentUser(w, r); admin != nil {
+		opts.Actor = admin.ID
+	}
+	summary, err := models.ImportProducts(server.DB, records, opts)
+	if err != nil {
+		persistError(err)
+		_ = ren.JSON(w, http.StatusInternalServerError, map[string]interface{}{"error": err.Error(), "report": summary})
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, summary)
+}
+
+// APIAdminProductImportJob menampilkan status dan laporan job import.
+func (server *Server) APIAdminProductImportJob(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	var job models.ProductImportJob
+	if err := server.DB.Where("id = ?", vars["id"]).First(&job).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, job)
+}
+
+// APIAdminProductExport mengunduh semua produk dalam format yang sama dengan
+// import (?format=csv|xlsx, default csv).
+func (server *Server) APIAdminProductExport(w http.ResponseWriter, r *http.Request) {
+	records, err := models.ExportProductRecords(server.DB)
+	if err != nil {
+		http.Error(w, "failed to load products", http.StatusInternalServerError)
+		return
+	}
+
+	filename := "products_" + time.Now().Format("20060102_150405")
+	if strings.ToLower(r.URL.Query().Get("format")) == "xlsx" {
+		f := excelize.NewFile()
+		defer f.Close()
+		sheetName := "Products"
+		idx, _ := f.NewSheet(sheetName)
+		f.SetActiveSheet(idx)
+		_ = f.DeleteSheet("Sheet1")
+		for i, column := range models.ProductImportColumns {
+			cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+			f.SetCellValue(sheetName, cell, column)
+		}
+		for rIdx, record := range records {
+			for i, column := range models.ProductImportColumns {
+				cell, _ := excelize.CoordinatesToCellName(i+1, rIdx+2)
+				f.SetCellValue(sheetName, cell, record.Values[column])
+			}
+		}
+
+		buf, err := f.WriteToBuffer()
+		if err != nil {
+			http.Error(w, "failed to build xlsx", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.xlsx", filename))
+		_, _ = w.Write(buf.Bytes())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", filename))
+	// BOM supaya Excel membaca UTF-8
+	_, _ = w.Write([]byte("\xef\xbb\xbf"))
+	writer := csv.NewWriter(w)
+	_ = writer.Write(models.ProductImportColumns)
+	for _, record := range records {
+		row := make([]string, len(models.ProductImportColumns))
+		for i, column := range models.ProductImportColumns {
+			row[i] = record.Values[column]
+		}
+		_ = writer.Write(row)
+	}
+	writer.Flush()
+}
+
+func isTruthy(value string) bool {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "1", "true", "yes", "on":
+		return true
+	}
+
+	return false
+}
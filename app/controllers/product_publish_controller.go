@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+)
+
+var errInvalidScheduleTime = errors.New("schedule time must be RFC3339 or YYYY-MM-DDTHH:MM")
+
+// parseScheduleTime membaca jadwal dari input datetime-local (zona waktu
+// server) atau RFC3339. Nilai kosong berarti tanpa jadwal.
+func parseScheduleTime(value string) (*time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04", value, time.Local); err == nil {
+		return &t, nil
+	}
+
+	return nil, errInvalidScheduleTime
+}
+
+// productPublishingFromForm membaca status, publish_at dan unpublish_at dari
+// form admin. Status kosong mempertahankan status sekarang.
+func productPublishingFromForm(r *http.Request, current *models.Product) (int, *time.Time, *time.Time, error) {
+	status := current.Status
+	if value := strings.TrimSpace(r.FormValue("status")); value != "" {
+		parsed, err := models.ParseProductStatus(value)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		status = parsed
+	}
+
+	publishAt, err := parseScheduleTime(r.FormValue("publish_at"))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	unpublishAt, err := parseScheduleTime(r.FormValue("unpublish_at"))
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	return status, publishAt, unpublishAt, nil
+}
+
+// productPublishingFromPayload mengambil status, publish_at dan unpublish_at
+// dari payload JSON PUT (lalu menghapusnya dari payload). Key yang tidak ada
+// mempertahankan nilai sekarang; null menghapus jadwal.
+func productPublishingFromPayload(payload map[string]interface{}, current *models.Product) (bool, int, *time.Time, *time.Time, error) {
+	changed := false
+	status, publishAt, unpublishAt := current.Status, current.PublishAt, current.UnpublishAt
+
+	if value, ok := payload["status"]; ok {
+		changed = true
+		delete(payload, "status")
+		switch v := value.(type) {
+		case string:
+			parsed, err := models.ParseProductStatus(v)
+			if err != nil {
+				return false, 0, nil, nil, err
+			}
+			status = parsed
+		case float64:
+			if _, valid := models.ProductStatusNames[int(v)]; !valid || v != float64(int(v)) {
+				return false, 0, nil, nil, models.ErrInvalidProductStatus
+			}
+			status = int(v)
+		default:
+			return false, 0, nil, nil, models.ErrInvalidProductStatus
+		}
+	}
+
+	for key, target := range map[string]**time.Time{"publish_at": &publishAt, "unpublish_at": &unpublishAt} {
+		value, ok := payload[key]
+		if !ok {
+			continue
+		}
+		changed = true
+		delete(payload, key)
+		text, isString := value.(string)
+		if value != nil && !isString {
+			return false, 0, nil, nil, errInvalidScheduleTime
+		}
+		parsed, err := parseScheduleTime(text)
+		if err != nil {
+			return false, 0, nil, nil, err
+		}
+		*target = parsed
+	}
+
+	return changed, status, publishAt, unpublishAt, nil
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+)
+
+// StartProductScheduler menjalankan ProcessProductSchedules setiap
+// PRODUCT_SCHEDULE_INTERVAL menit (default 1) kecuali
+// PRODUCT_SCHEDULE_ENABLED=false.
+func (server *Server) StartProductScheduler() {
+	if os.Getenv("PRODUCT_SCHEDULE_ENABLED") == "false" {
+		return
+	}
+
+	interval := envMinutes("PRODUCT_SCHEDULE_INTERVAL", time.Minute)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for ; ; <-ticker.C {
+			published, unpublished, err := models.ProcessProductSchedules(server.DB, time.Now())
+			if err != nil {
+				log.Println("product scheduler:", err)
+				continue
+			}
+			if published > 0 || unpublished > 0 {
+				log.Printf("product scheduler: published %d, unpublished %d\n", published, unpublished)
+			}
+		}
+	}()
+}
@@ -2,8 +2,11 @@ package controllers
 
 import (
 	"html/template"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/codeuiprogramming/e-commerce/app/helpers"
 	"github.com/codeuiprogramming/e-commerce/app/models"
@@ -31,23 +34,51 @@ render := render.New(render.Options{
 
 	perPage := 9
 
-	productModel := models.Product{}
-	products, totalRows, err := productModel.GetProducts(server.DB, perPage, page)
+	// /products?q= menampilkan hasil pencarian di grid yang sama
+	if query := strings.TrimSpace(q.Get("q")); query != "" {
+		results, totalRows, err := server.searchProducts(w, r, query, perPage, page)
+		if err != nil {
+			log.Printf("search: %v", err)
+			http.Error(w, "search failed", http.StatusInternalServerError)
+			return
+		}
+
+		products, snippets := searchResultsView(results)
+		pagination, _ := GetPaginationLinks(server.AppConfig, PaginationParams{
+			Path:        "products",
+			TotalRows:   int32(totalRows),
+			PerPage:     int32(perPage),
+			CurrentPage: int32(page),
+			Query:       url.Values{"q": {query}},
+		})
+
+		_ = render.HTML(w, http.StatusOK, "products", server.DefaultRenderData(w, r, map[string]interface{}{
+			"products":   products,
+			"pagination": pagination,
+			"q":          query,
+			"total":      totalRows,
+			"snippets":   snippets,
+		}))
+		return
+	}
+
+	listing, err := server.productListing(r, perPage)
 	if err != nil {
+		log.Printf("products: %v", err)
+		http.Error(w, "failed to load products", http.StatusInternalServerError)
 		return
 	}
 
-	pagination, _ := GetPaginationLinks(server.AppConfig, PaginationParams{
-		Path:        "products",
-		TotalRows:   int32(totalRows),
-		PerPage:     int32(perPage),
-		CurrentPage: int32(page),
-	})
+	active := listing["filters"].(url.Values)
+	listing["categoryFacets"], listing["priceFacets"] = productFacetLinks(active, listing["facets"].(*models.ProductFacets))
+	inStock := "1"
+	if active.Get("in_stock") == "1" {
+		inStock = ""
+	}
+	listing["inStockURL"] = listingURL(active, map[string]string{"in_stock": inStock})
+	listing["sortLabels"] = productSortLabels
 
-	_ = render.HTML(w, http.StatusOK, "products", server.DefaultRenderData(w, r, map[string]interface{}{
-		"products":   products,
-		"pagination": pagination,
-	}))
+	_ = render.HTML(w, http.StatusOK, "products", server.DefaultRenderData(w, r, listing))
 }
 
 func (server *Server) GetProductBySlug(w http.ResponseWriter, r *http.Request) {
@@ -70,17 +101,47 @@ render := render.New(render.Options{
 
 	productModel := models.Product{}
 	product, err := productModel.FindBySlug(server.DB, vars["slug"])
+	// admin bisa melihat draft/arsip lewat ?preview=1
+	preview := false
+	if err != nil && isTruthy(r.URL.Query().Get("preview")) && isAdminUser(server.CurrentUser(w, r)) {
+		if product, err = productModel.FindBySlugForPreview(server.DB, vars["slug"]); err == nil {
+			preview = !product.IsPublished()
+		}
+	}
 	if err != nil {
+		// slug lama diarahkan permanen ke slug yang sekarang
+		if current, err := productModel.FindBySlugHistory(server.DB, vars["slug"]); err == nil && current.Slug != "" {
+			target := "/products/" + current.Slug
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
 		// product not found -> return 404 to client
 		http.NotFound(w, r)
 		return
 	}
 
-	_ = render.HTML(w, http.StatusOK, "product", server.DefaultRenderData(w, r, map[string]interface{}{
-		"product": product,
-		"success": GetFlash(w, r, "success"),
-		"error":   GetFlash(w, r, "error"),
-	}))
+	// breadcrumb mengikuti kategori pertama produk (tanpa Home, sudah ada di template)
+	var breadcrumbs []Breadcrumb
+	if len(product.Categories) > 0 {
+		categoryModel := models.Category{}
+		if category, err := categoryModel.FindByID(server.DB, product.Categories[0].ID); err == nil {
+			crumbs := server.categoryBreadcrumbs(category)
+			breadcrumbs = crumbs[1 : len(crumbs)-1]
+			breadcrumbs = append(breadcrumbs, Breadcrumb{Name: category.Name, URL: categoryURL(*category)})
+		}
+	}
+
+	data := server.productReviewData(w, r, product)
+	data["product"] = product
+	data["breadcrumbs"] = breadcrumbs
+	data["preview"] = preview
+	data["success"] = GetFlash(w, r, "success")
+	data["error"] = GetFlash(w, r, "error")
+
+	_ = render.HTML(w, http.StatusOK, "product", server.DefaultRenderData(w, r, data))
 }
 
 // PRODUCT CUSTOM
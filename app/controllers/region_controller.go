@@ -0,0 +1,266 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/shipping"
+)
+
+// regionsFromDB melaporkan apakah data wilayah sudah di-import ke database.
+// Selama tabel masih kosong, lookup tetap memakai RajaOngkir.
+func (server *Server) regionsFromDB() bool {
+	var count int64
+	server.DB.Model(&models.RegionProvince{}).Count(&count)
+
+	return count > 0
+}
+
+func (server *Server) regionProvinces() ([]models.Province, error) {
+	regionModel := models.RegionProvince{}
+	rows, err := regionModel.GetProvinces(server.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	var provinces []models.Province
+	for _, row := range rows {
+		id, _ := strconv.Atoi(row.ID)
+		provinces = append(provinces, models.Province{ID: id, Name: row.Name})
+	}
+
+	return provinces, nil
+}
+
+func (server *Server) regionCities(provinceID string) ([]models.City, error) {
+	regionModel := models.RegionCity{}
+	rows, err := regionModel.GetCitiesByProvinceID(server.DB, provinceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var cities []models.City
+	for _, row := range rows {
+		id, _ := strconv.Atoi(row.ID)
+		cities = append(cities, models.City{ID: id, Name: row.Name, ZipCode: row.ZipCode})
+	}
+
+	return cities, nil
+}
+
+// ImportRegions memuat dataset wilayah dari file lokal (.csv/.json), URL, atau
+// langsung dari RajaOngkir (provinsi, kota dan kecamatan) jika source == "api".
+func (server *Server) ImportRegions(source string) (int, error) {
+	if source == "api" {
+		return server.importRegionsFromAPI()
+	}
+
+	var reader io.Reader
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		client := &http.Client{Timeout: 60 * time.Second}
+		resp, err := client.Get(source)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("region dataset returned %d", resp.StatusCode)
+		}
+		reader = resp.Body
+	} else {
+		file, err := os.Open(source)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var records []models.RegionRecord
+	var err error
+	if strings.EqualFold(filepath.Ext(strings.SplitN(source, "?", 2)[0]), ".json") {
+		err = json.NewDecoder(reader).Decode(&records)
+	} else {
+		records, err = parseRegionCSV(reader)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return models.ImportRegions(server.DB, records)
+}
+
+// parseRegionCSV membaca CSV dengan header level,id,parent_id,name,postal_code.
+func parseRegionCSV(reader io.Reader) ([]models.RegionRecord, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []models.RegionRecord
+	for i, row := range rows {
+		if i == 0 && len(row) > 0 && strings.EqualFold(strings.TrimSpace(row[0]), "level") {
+			continue
+		}
+		if len(row) < 4 {
+			return nil, fmt.Errorf("row %d: expected level,id,parent_id,name,postal_code", i+1)
+		}
+
+		record := models.RegionRecord{Level: row[0], ID: row[1], ParentID: row[2], Name: row[3]}
+		if len(row) > 4 {
+			record.PostalCode = row[4]
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// regionImportAttempts dan regionImportBackoff mengatur percobaan ulang tiap
+// request saat RajaOngkir membatasi request (429) atau tidak bisa dihubungi.
+const (
+	regionImportAttempts   = 6
+	regionImportBackoff    = 2 * time.Second
+	regionImportMaxBackoff = time.Minute
+)
+
+// withRegionBackoff menjalankan fetch dengan backoff eksponensial. Retry-After
+// dari respons 429 dipakai bila ada; error lain (misalnya 4xx) tidak diulang.
+func withRegionBackoff(op string, fetch func() error) error {
+	wait := regionImportBackoff
+	for attempt := 1; ; attempt++ {
+		err := fetch()
+		if err == nil || !shipping.IsUnavailable(err) || attempt >= regionImportAttempts {
+			return err
+		}
+
+		delay := wait
+		if rateLimited, ok := shipping.IsRateLimited(err); ok && rateLimited.RetryAfter > delay {
+			delay = rateLimited.RetryAfter
+		}
+		log.Printf("regions:import %s: %v, retrying in %s", op, err, delay)
+		time.Sleep(delay)
+
+		if wait *= 2; wait > regionImportMaxBackoff {
+			wait = regionImportMaxBackoff
+		}
+	}
+}
+
+// regionID mengubah ID dari JSON ke string. Angka dari json.Unmarshal berupa
+// float64; fmt.Sprint akan menulis ID besar sebagai 1.234567e+06.
+func regionID(v interface{}) string {
+	switch id := v.(type) {
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	case json.Number:
+		return id.String()
+	case string:
+		return strings.TrimSpace(id)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(id)
+	}
+}
+
+// importRegionsFromAPI mengambil wilayah dari RajaOngkir dan menyimpannya per
+// provinsi dan per kota, sehingga import yang terhenti bisa dijalankan ulang:
+// provinsi yang kotanya sudah tersimpan dan kota yang kecamatannya sudah
+// tersimpan dilewati.
+func (server *Server) importRegionsFromAPI() (int, error) {
+	client := server.ShippingClient()
+	imported := 0
+
+	var provinces []models.Province
+	if err := withRegionBackoff("provinces", func() (err error) {
+		provinces, err = client.Provinces()
+		return err
+	}); err != nil {
+		return imported, err
+	}
+
+	regionModel := models.RegionCity{}
+	for _, province := range provinces {
+		provinceID := strconv.Itoa(province.ID)
+
+		stored, err := regionModel.GetCitiesByProvinceID(server.DB, provinceID)
+		if err != nil {
+			return imported, err
+		}
+
+		var cities []models.City
+		if len(stored) > 0 {
+			for _, city := range stored {
+				id, _ := strconv.Atoi(city.ID)
+				cities = append(cities, models.City{ID: id, Name: city.Name, ZipCode: city.ZipCode})
+			}
+		} else {
+			if err := withRegionBackoff("cities of province "+provinceID, func() (err error) {
+				cities, err = client.Cities(provinceID)
+				return err
+			}); err != nil {
+				return imported, err
+			}
+
+			records := []models.RegionRecord{{Level: models.RegionLevelProvince, ID: provinceID, Name: province.Name}}
+			for _, city := range cities {
+				records = append(records, models.RegionRecord{Level: models.RegionLevelCity, ID: strconv.Itoa(city.ID), ParentID: provinceID, Name: city.Name, PostalCode: city.ZipCode})
+			}
+			count, err := models.ImportRegions(server.DB, records)
+			imported += count
+			if err != nil {
+				return imported, err
+			}
+		}
+
+		for _, city := range cities {
+			cityID := strconv.Itoa(city.ID)
+
+			var done int64
+			if err := server.DB.Model(&models.RegionDistrict{}).Where("city_id = ?", cityID).Count(&done).Error; err != nil {
+				return imported, err
+			}
+			if done > 0 {
+				continue
+			}
+
+			var districts []map[string]interface{}
+			if err := withRegionBackoff("districts of city "+cityID, func() (err error) {
+				districts, err = client.Districts(cityID)
+				return err
+			}); err != nil {
+				return imported, err
+			}
+
+			var records []models.RegionRecord
+			for _, district := range districts {
+				records = append(records, models.RegionRecord{
+					Level:    models.RegionLevelDistrict,
+					ID:       regionID(district["id"]),
+					ParentID: cityID,
+					Name:     fmt.Sprint(district["name"]),
+				})
+			}
+			count, err := models.ImportRegions(server.DB, records)
+			imported += count
+			if err != nil {
+				return imported, err
+			}
+		}
+	}
+
+	return imported, nil
+}
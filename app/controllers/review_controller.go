@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/storage"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+const (
+	reviewPhotoLimit   = 5
+	reviewPhotoMaxSize = 5 << 20
+	reviewsPerPage     = 5
+)
+
+// ekstensi file untuk tipe foto ulasan yang diterima
+var reviewPhotoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// saveReviewPhotos menyimpan foto ulasan (field "photos") di storage upload
+// (uploads/reviews/{userID}) dan mengembalikan path publiknya.
+func (server *Server) saveReviewPhotos(r *http.Request, userID string) ([]string, error) {
+	if r.MultipartForm == nil || len(r.MultipartForm.File["photos"]) == 0 {
+		return nil, nil
+	}
+
+	headers := r.MultipartForm.File["photos"]
+	if len(headers) > reviewPhotoLimit {
+		return nil, fmt.Errorf("maksimal %d foto per ulasan", reviewPhotoLimit)
+	}
+
+	var saved []string
+	cleanup := func() { server.removeReviewPhotos(saved) }
+
+	for _, header := range headers {
+		if header.Size > reviewPhotoMaxSize {
+			cleanup()
+			return nil, fmt.Errorf("foto %s melebihi 5MB", header.Filename)
+		}
+
+		file, err := header.Open()
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		data, err := io.ReadAll(io.LimitReader(file, reviewPhotoMaxSize+1))
+		file.Close()
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+
+		ext, ok := reviewPhotoTypes[http.DetectContentType(data)]
+		if !ok {
+			cleanup()
+			return nil, fmt.Errorf("foto %s bukan gambar JPG/PNG/GIF/WebP", header.Filename)
+		}
+
+		key := "uploads/reviews/" + userID + "/" + uuid.New().String() + ext
+		if err := server.putUpload(key, data); err != nil {
+			cleanup()
+			return nil, err
+		}
+		saved = append(saved, "/public/"+key)
+	}
+
+	return saved, nil
+}
+
+// removeReviewPhotos menghapus file foto ulasan dari path publiknya.
+func (server *Server) removeReviewPhotos(paths []string) {
+	for _, path := range paths {
+		if err := server.Storage().Delete(storage.KeyFromPath(path)); err != nil {
+			log.Println("remove review photo:", err)
+		}
+	}
+}
+
+// productReviewData menyiapkan ringkasan rating, ulasan (sort dan halaman dari
+// query) dan status boleh-ulas untuk halaman produk.
+func (server *Server) productReviewData(w http.ResponseWriter, r *http.Request, product *models.Product) map[string]interface{} {
+	sort := models.NormalizeReviewSort(r.URL.Query().Get("review_sort"))
+	page := pageFromQuery(r)
+
+	summary, _ := models.GetRatingSummary(server.DB, product)
+	reviews, totalRows, err := models.GetApprovedReviews(server.DB, product.ID, sort, reviewsPerPage, page)
+	if err != nil {
+		log.Printf("reviews: %v", err)
+	}
+
+	query := url.Values{}
+	if sort != consts.ReviewSortNewest {
+		query.Set("review_sort", sort)
+	}
+	pagination, _ := GetPaginationLinks(server.AppConfig, PaginationParams{
+		Path:        "products/" + product.Slug,
+		TotalRows:   int32(totalRows),
+		PerPage:     int32(reviewsPerPage),
+		CurrentPage: int32(page),
+		Query:       query,
+	})
+
+	canReview := false
+	if user := server.CurrentUser(w, r); user != nil {
+		_, err := models.FindReviewableOrderItem(server.DB, user.ID, product.ID)
+		canReview = err == nil
+	}
+
+	return map[string]interface{}{
+		"ratingSummary": summary,
+		"reviews":       reviews,
+		"reviewTotal":   totalRows,
+		"reviewSort":    sort,
+		"pagination":    pagination,
+		"canReview":     canReview,
+		"starScale":     []int{1, 2, 3, 4, 5},
+		"starScaleDesc": []int{5, 4, 3, 2, 1},
+	}
+}
+
+// SubmitProductReview menyimpan ulasan pelanggan yang sudah menerima produk.
+func (server *Server) SubmitProductReview(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	productURL := "/products/" + vars["slug"]
+
+	user := server.CurrentUser(w, r)
+	if user == nil {
+		http.Redirect(w, r, "/login", http.StatusSeeOther)
+		return
+	}
+
+	productModel := models.Product{}
+	product, err := productModel.FindBySlug(server.DB, vars["slug"])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := r.ParseMultipartForm(reviewPhotoLimit*reviewPhotoMaxSize + 1<<20); err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		SetFlash(w, r, "error", "Form ulasan tidak valid")
+		http.Redirect(w, r, productURL, http.StatusSeeOther)
+		return
+	}
+
+	rating, _ := strconv.Atoi(r.FormValue("rating"))
+	if rating < 1 || rating > 5 {
+		SetFlash(w, r, "error", "Pilih rating 1 sampai 5 bintang")
+		http.Redirect(w, r, productURL, http.StatusSeeOther)
+		return
+	}
+	if _, err := models.FindReviewableOrderItem(server.DB, user.ID, product.ID); err != nil {
+		SetFlash(w, r, "error", "Ulasan hanya bisa diberikan untuk produk yang sudah Anda terima")
+		http.Redirect(w, r, productURL, http.StatusSeeOther)
+		return
+	}
+
+	photos, err := server.saveReviewPhotos(r, user.ID)
+	if err != nil {
+		SetFlash(w, r, "error", err.Error())
+		http.Redirect(w, r, productURL, http.StatusSeeOther)
+		return
+	}
+
+	if _, err := models.CreateProductReview(server.DB, user.ID, product.ID, rating, r.FormValue("title"), r.FormValue("body"), photos); err != nil {
+		server.removeReviewPhotos(photos)
+		log.Printf("reviews: create failed: %v", err)
+		SetFlash(w, r, "error", "Gagal menyimpan ulasan")
+		http.Redirect(w, r, productURL, http.StatusSeeOther)
+		return
+	}
+
+	SetFlash(w, r, "success", "Terima kasih! Ulasan Anda akan tampil setelah dimoderasi.")
+	http.Redirect(w, r, productURL, http.StatusSeeOther)
+}
+
+// APIProductReviews handles GET /api/products/{slug}/reviews?sort=&page=
+func (server *Server) APIProductReviews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	productModel := models.Product{}
+	product, err := productModel.FindBySlug(server.DB, vars["slug"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	page := pageFromQuery(r)
+	sort := models.NormalizeReviewSort(r.URL.Query().Get("sort"))
+	reviews, totalRows, err := models.GetApprovedReviews(server.DB, product.ID, sort, reviewsPerPage, page)
+	if err != nil {
+		http.Error(w, "failed to load reviews", http.StatusInternalServerError)
+		return
+	}
+	summary, _ := models.GetRatingSummary(server.DB, product)
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+		"summary":  summary,
+		"reviews":  reviews,
+		"total":    totalRows,
+		"page":     page,
+		"per_page": reviewsPerPage,
+		"sort":     sort,
+	})
+}
+
+// APIAdminReviews menampilkan antrian moderasi ulasan (filter ?status=).
+func (server *Server) APIAdminReviews(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	page := pageFromQuery(r)
+	status := r.URL.Query().Get("status")
+	reviews, totalRows, err := models.GetReviewsByStatus(server.DB, status, 50, page)
+	if err != nil {
+		http.Error(w, "failed to load reviews", http.StatusInternalServerError)
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+		"reviews": reviews,
+		"total":   totalRows,
+		"page":    page,
+	})
+}
+
+// APIAdminReview handles GET/PUT/DELETE satu ulasan. PUT menerima
+// {status: approved|rejected|pending, reply: "..."}; keduanya opsional.
+func (server *Server) APIAdminReview(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	review, err := models.FindReviewByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		_ = ren.JSON(w, http.StatusOK, review)
+		return
+	case "PUT":
+		var payload struct {
+			Status *string `json:"status"`
+			Reply  *string `json:"reply"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if payload.Status != nil {
+			adminID := ""
+			if admin := server.CurrentUser(w, r); admin != nil {
+				adminID = admin.ID
+			}
+			if err := review.Moderate(server.DB, strings.ToLower(*payload.Status), adminID); err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, models.ErrInvalidReviewStatus) {
+					status = http.StatusBadRequest
+				}
+				_ = ren.JSON(w, status, map[string]string{"error": err.Error()})
+				return
+			}
+		}
+		if payload.Reply != nil {
+			if err := review.Reply(server.DB, *payload.Reply); err != nil {
+				http.Error(w, "failed to save reply", http.StatusInternalServerError)
+				return
+			}
+		}
+		_ = ren.JSON(w, http.StatusOK, review)
+		return
+	case "DELETE":
+		if err := review.Delete(server.DB); err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		paths := []string{}
+		for _, image := range review.Images {
+			paths = append(paths, image.Path)
+		}
+		server.removeReviewPhotos(paths)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
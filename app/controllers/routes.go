@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	// "github.com/codeuiprogramming/e-commerce/app/controllers"
+	"github.com/codeuiprogramming/e-commerce/app/storage"
 	"github.com/gorilla/mux"
 )
 
@@ -25,9 +26,16 @@ func (server *Server) initializeRoutes() {
 	server.Router.HandleFunc("/register", server.Register).Methods("GET")
 	server.Router.HandleFunc("/register", server.DoRegister).Methods("POST")
 	server.Router.HandleFunc("/logout", server.Logout).Methods("GET")
+	server.Router.HandleFunc("/currency/{code}", server.SetCurrency).Methods("GET")
 
 	server.Router.HandleFunc("/products", server.Products).Methods("GET")
+	server.Router.HandleFunc("/api/products", server.APIProducts).Methods("GET")
+	server.Router.HandleFunc("/api/products/search", server.APIProductSearch).Methods("GET")
 	server.Router.HandleFunc("/products/{slug}", server.GetProductBySlug).Methods("GET")
+	server.Router.Handle("/products/{slug}/reviews", server.AuthRequired(http.HandlerFunc(server.SubmitProductReview))).Methods("POST")
+	server.Router.HandleFunc("/api/products/{slug}/reviews", server.APIProductReviews).Methods("GET")
+	server.Router.HandleFunc("/categories/{slug}", server.CategoryProducts).Methods("GET")
+	server.Router.HandleFunc("/sections/{slug}", server.SectionProducts).Methods("GET")
 
 	// server.Router.HandleFunc("/product-custom", server.ProductCustom).Methods("GET")
 	// server.Router.HandleFunc("/product-custom", server.ProductCustomList).Methods("GET")
@@ -35,6 +43,7 @@ func (server *Server) initializeRoutes() {
 	server.Router.HandleFunc("/product-custom/{type}", server.ProductCustomDetail).Methods("GET")
 
 	server.Router.HandleFunc("/carts", server.GetCart).Methods("GET")
+	server.Router.HandleFunc("/api/cart", server.APICart).Methods("GET")
 	server.Router.HandleFunc("/carts", server.AddItemToCart).Methods("POST")
 	server.Router.HandleFunc("/carts/custom", server.AddCustomToCart).Methods("POST")
 	server.Router.HandleFunc("/carts/update", server.UpdateCart).Methods("POST")
@@ -45,10 +54,12 @@ func (server *Server) initializeRoutes() {
 	server.Router.HandleFunc("/carts/calculate-shipping", server.CalculateShipping).Methods("POST")
 	server.Router.HandleFunc("/carts/apply-shipping", server.ApplyShipping).Methods("POST")
 	server.Router.HandleFunc("/carts/remove/{id}", server.RemoveItemByID).Methods("GET")
+	server.Router.HandleFunc("/gift-cards/check", server.CheckGiftCard).Methods("GET")
 
 	// Lindungi route checkout dengan middleware AuthRequired (menggunakan session lama).
 	server.Router.Handle("/orders/checkout", server.AuthRequired(http.HandlerFunc(server.Checkout))).Methods("POST")
 	server.Router.HandleFunc("/orders/{id}", server.ShowOrder).Methods("GET")
+	server.Router.Handle("/orders/{id}/tracking", server.AuthRequired(http.HandlerFunc(server.ShowOrderTracking))).Methods("GET")
 	// Profile page
 	server.Router.HandleFunc("/profile", server.Profile).Methods("GET")
 	server.Router.HandleFunc("/profile", server.UpdateProfile).Methods("POST")
@@ -60,17 +71,72 @@ func (server *Server) initializeRoutes() {
 	// Products CRUD
 	// API for product CRUD (JSON) - protected by RequireAdminAuth
 	server.Router.Handle("/api/admin/products", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProducts))).Methods("GET", "POST")
+	// Import/export massal (didaftarkan sebelum /products/{id})
+	server.Router.Handle("/api/admin/products/import", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductImport))).Methods("POST")
+	server.Router.Handle("/api/admin/products/import/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductImportJob))).Methods("GET")
+	server.Router.Handle("/api/admin/products/export", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductExport))).Methods("GET")
 	server.Router.Handle("/api/admin/products/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProduct))).Methods("GET", "PUT", "DELETE")
-	// upload product images
-	server.Router.Handle("/api/admin/products/{id}/images", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductImageUpload))).Methods("POST")
+	// Gambar produk: upload (diproses di background), urutan, gambar utama dan alt text
+	server.Router.Handle("/api/admin/products/{id}/images", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductImages))).Methods("GET", "POST")
+	server.Router.Handle("/api/admin/products/{id}/images/order", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductImageOrder))).Methods("PUT")
+	server.Router.Handle("/api/admin/products/{id}/images/{imageID}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductImage))).Methods("GET", "PUT", "DELETE")
 
 	// API for user management (admin only)
 	server.Router.Handle("/api/admin/users", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminUsers))).Methods("GET")
 	server.Router.Handle("/api/admin/users/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminUser))).Methods("GET", "PUT", "DELETE")
+	server.Router.Handle("/api/admin/users/{id}/wallet", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminUserWallet))).Methods("GET", "POST")
 
     // API for orders (admin only)
     server.Router.Handle("/api/admin/orders", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminOrders))).Methods("GET")
+    // Packing slip dan label pengiriman (PDF), batch didaftarkan sebelum /orders/{id}
+    server.Router.Handle("/api/admin/orders/print", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminOrderDocuments))).Methods("GET", "POST")
     server.Router.Handle("/api/admin/orders/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminOrder))).Methods("GET")
+    server.Router.Handle("/api/admin/orders/{id}/print", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminOrderDocument))).Methods("GET")
+    server.Router.Handle("/api/admin/orders/{id}/cancel", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminOrderCancel))).Methods("POST")
+    // COD: konfirmasi uang tunai dari kurir, satu per satu atau lewat import setoran
+    server.Router.Handle("/api/admin/orders/{id}/cod-confirm", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminOrderCODConfirm))).Methods("POST")
+    server.Router.Handle("/api/admin/orders/{id}/payments", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminOrderPayments))).Methods("GET")
+    // Shipping rules (flat, tabel berat, gratis ongkir, kurir lokal, surcharge)
+    server.Router.Handle("/api/admin/shipping-methods", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminShippingMethods))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/shipping-methods/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminShippingMethod))).Methods("GET", "PUT", "DELETE")
+    // Shipments: buat resi dari order yang sudah lunas dan perbarui status pengiriman
+    server.Router.Handle("/api/admin/orders/{id}/shipment", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminOrderShipment))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/shipments/{id}/status", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminShipmentStatus))).Methods("POST")
+    // Section dan pohon kategori, serta kategori per produk
+    server.Router.Handle("/api/admin/sections", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminSections))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/sections/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminSection))).Methods("GET", "PUT", "DELETE")
+    server.Router.Handle("/api/admin/categories", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminCategories))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/categories/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminCategory))).Methods("GET", "PUT", "DELETE")
+    server.Router.Handle("/api/admin/products/{id}/categories", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductCategories))).Methods("GET", "PUT")
+    // Moderasi ulasan produk
+    server.Router.Handle("/api/admin/reviews", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminReviews))).Methods("GET")
+    server.Router.Handle("/api/admin/reviews/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminReview))).Methods("GET", "PUT", "DELETE")
+    server.Router.Handle("/api/admin/search/zero-results", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminZeroResultSearches))).Methods("GET")
+    // Kardus kemasan untuk berat volume
+    server.Router.Handle("/api/admin/packaging-boxes", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminPackagingBoxes))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/packaging-boxes/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminPackagingBox))).Methods("GET", "PUT", "DELETE")
+    // Gudang: stok per lokasi dan transfer stok antar gudang
+    server.Router.Handle("/api/admin/warehouses", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminWarehouses))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/warehouses/{id}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminWarehouse))).Methods("GET", "PUT", "DELETE")
+    server.Router.Handle("/api/admin/warehouses/{id}/stocks", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminWarehouseStocks))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/products/{id}/stocks", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductStocks))).Methods("GET")
+    server.Router.Handle("/api/admin/products/{id}/stock-movements", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminProductStockMovements))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/inventory/low-stock", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminLowStock))).Methods("GET")
+    server.Router.Handle("/api/admin/stock-transfers", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminStockTransfers))).Methods("GET", "POST")
+    // COD: import setoran tunai dari kurir
+    server.Router.Handle("/api/admin/cod/remittances", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminCODRemittanceImport))).Methods("POST")
+
+    // API for currencies and exchange rates (admin only)
+    server.Router.Handle("/api/admin/currencies", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminCurrencies))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/currencies/{code}", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminCurrency))).Methods("PUT", "DELETE")
+
+    // API for gift cards (admin only)
+    server.Router.Handle("/api/admin/gift-cards", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminGiftCards))).Methods("GET", "POST")
+    server.Router.Handle("/api/admin/gift-cards/liability", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminGiftCardLiability))).Methods("GET")
+
+    // API for payments ledger (admin only)
+    server.Router.Handle("/api/admin/payments", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminPayments))).Methods("GET")
+    server.Router.Handle("/api/admin/payments/{id}/reprocess", server.RequireAdminAuth(http.HandlerFunc(server.APIAdminPaymentReprocess))).Methods("POST")
 
 	server.Router.HandleFunc("/material-dashboard-shadcn-vue", func(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/material-dashboard-shadcn-vue/dashboard", http.StatusMovedPermanently)
@@ -182,13 +248,24 @@ func (server *Server) initializeRoutes() {
 			http.ServeFile(w, r, filepath.Join("public", p))
 			return
 		}
+		// upload yang tidak ada di disk dilayani storage (S3 atau STORAGE_LOCAL_DIR)
+		if storage.IsUploadKey(p) {
+			server.serveUpload(w, r, p)
+			return
+		}
 		http.NotFound(w, r)
 	})
 
 	server.Router.PathPrefix("/public/").Handler(staticHandler).Methods("GET", "HEAD")
 
-	// Serve uploaded files under /uploads/ -> ./public/uploads/
-	server.Router.PathPrefix("/uploads/").Handler(http.StripPrefix("/uploads/", http.FileServer(http.Dir("./public/uploads/")))).Methods("GET", "HEAD")
+	// Serve uploaded files under /uploads/ dari storage upload
+	server.Router.PathPrefix("/uploads/").HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.serveUpload(w, r, strings.TrimPrefix(r.URL.Path, "/"))
+	}).Methods("GET", "HEAD")
+	// URL bertanda tangan untuk storage lokal (storage.Local.SignedURL)
+	if local, ok := server.Storage().(*storage.Local); ok {
+		server.Router.PathPrefix(local.SignedBaseURL).Handler(local).Methods("GET", "HEAD")
+	}
 
 	// Serve built admin2 SPA (static files placed in public/admin2)
 	// Serve index for /admin and static assets under /admin/
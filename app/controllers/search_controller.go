@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+)
+
+// searchProducts menjalankan pencarian dan mencatat kata kunci yang tidak
+// menemukan apa pun (hanya di halaman pertama supaya tidak dihitung ganda).
+func (server *Server) searchProducts(w http.ResponseWriter, r *http.Request, query string, perPage int, page int) ([]models.ProductSearchResult, int64, error) {
+	productModel := models.Product{}
+	results, totalRows, err := productModel.SearchProducts(server.DB, query, perPage, page)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if totalRows == 0 && page == 1 {
+		userID := ""
+		if user := server.CurrentUser(w, r); user != nil {
+			userID = user.ID
+		}
+		if err := models.LogZeroResultSearch(server.DB, query, userID); err != nil {
+			log.Printf("search: failed to log zero result query %q: %v", query, err)
+		}
+	}
+
+	return results, totalRows, nil
+}
+
+// searchResultsView memecah hasil pencarian menjadi daftar produk (untuk grid
+// yang sama dengan /products) dan snippet per ID produk.
+func searchResultsView(results []models.ProductSearchResult) ([]models.Product, map[string]template.HTML) {
+	products := make([]models.Product, 0, len(results))
+	snippets := map[string]template.HTML{}
+	for _, result := range results {
+		products = append(products, result.Product)
+		// snippet sudah di-escape oleh models.HighlightSnippet
+		snippets[result.Product.ID] = template.HTML(result.Snippet)
+	}
+
+	return products, snippets
+}
+
+// APIProductSearch handles GET /api/products/search?q=&page=&per_page=
+func (server *Server) APIProductSearch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	q := r.URL.Query()
+	query := strings.TrimSpace(q.Get("q"))
+	if query == "" {
+		_ = ren.JSON(w, http.StatusBadRequest, map[string]string{"error": "q is required"})
+		return
+	}
+
+	page := pageFromQuery(r)
+	perPage, _ := strconv.Atoi(q.Get("per_page"))
+	if perPage <= 0 || perPage > 50 {
+		perPage = 12
+	}
+
+	results, totalRows, err := server.searchProducts(w, r, query, perPage, page)
+	if err != nil {
+		log.Printf("search: %v", err)
+		_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": "search failed"})
+		return
+	}
+
+	pagination, _ := GetPaginationLinks(server.AppConfig, PaginationParams{
+		Path:        "api/products/search",
+		TotalRows:   int32(totalRows),
+		PerPage:     int32(perPage),
+		CurrentPage: int32(page),
+		Query:       url.Values{"q": {query}, "per_page": {strconv.Itoa(perPage)}},
+	})
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+		"query":      query,
+		"total":      totalRows,
+		"page":       page,
+		"per_page":   perPage,
+		"results":    results,
+		"pagination": pagination,
+	})
+}
+
+// APIAdminZeroResultSearches menampilkan kata kunci pencarian tanpa hasil.
+func (server *Server) APIAdminZeroResultSearches(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	searches, err := models.GetZeroResultSearches(server.DB, limit)
+	if err != nil {
+		http.Error(w, "failed to load searches", http.StatusInternalServerError)
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, searches)
+}
@@ -0,0 +1,160 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/helpers"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+	"github.com/unrolled/render"
+)
+
+// ShowOrderTracking menampilkan timeline pengiriman untuk pemilik order.
+func (server *Server) ShowOrderTracking(w http.ResponseWriter, r *http.Request) {
+	render := render.New(render.Options{
+		Layout:     "layout",
+		Extensions: []string{".html", ".tmpl"},
+		Funcs: []template.FuncMap{
+			{
+				"FormatPrice": helpers.FormatPrice,
+			},
+		},
+	})
+
+	vars := mux.Vars(r)
+
+	orderModel := models.Order{}
+	order, err := orderModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Redirect(w, r, "/products", http.StatusSeeOther)
+		return
+	}
+
+	user := server.CurrentUser(w, r)
+	if user == nil || (user.ID != order.UserID && user.Role != "admin" && user.Role != "superadmin") {
+		http.Redirect(w, r, "/products", http.StatusSeeOther)
+		return
+	}
+
+	shipmentModel := models.Shipment{}
+	shipment, err := shipmentModel.FindByOrderID(server.DB, order.ID)
+	if err != nil {
+		shipment = nil
+	}
+
+	if err := render.HTML(w, http.StatusOK, "tracking", server.DefaultRenderData(w, r, map[string]interface{}{
+		"order":    order,
+		"shipment": shipment,
+	})); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// APIAdminOrderShipment returns (GET) or creates (POST) the shipment of an order
+func (server *Server) APIAdminOrderShipment(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	orderModel := models.Order{}
+	order, err := orderModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	shipmentModel := models.Shipment{}
+	switch r.Method {
+	case "GET":
+		shipment, err := shipmentModel.FindByOrderID(server.DB, order.ID)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		_ = ren.JSON(w, http.StatusOK, shipment)
+		return
+	case "POST":
+		var payload struct {
+			TrackNumber string `json:"track_number"`
+			Courier     string `json:"courier"`
+			Service     string `json:"service"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(payload.TrackNumber) == "" {
+			http.Error(w, "track_number is required", http.StatusBadRequest)
+			return
+		}
+
+		shippedBy := ""
+		if admin := server.CurrentUser(w, r); admin != nil {
+			shippedBy = admin.ID
+		}
+
+		shipment, err := shipmentModel.CreateFromOrder(server.DB, order, strings.TrimSpace(payload.TrackNumber), payload.Courier, payload.Service, shippedBy)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, models.ErrShipmentExists) || errors.Is(err, models.ErrOrderNotShippable) {
+				status = http.StatusUnprocessableEntity
+			} else {
+				persistError(err)
+			}
+			_ = ren.JSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+
+		_ = ren.JSON(w, http.StatusCreated, shipment)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminShipmentStatus appends a status event to a shipment's timeline
+func (server *Server) APIAdminShipmentStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	shipmentModel := models.Shipment{}
+	shipment, err := shipmentModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	var payload struct {
+		Status      string     `json:"status"`
+		Description string     `json:"description"`
+		Location    string     `json:"location"`
+		OccurredAt  *time.Time `json:"occurred_at"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		http.Error(w, "invalid json", http.StatusBadRequest)
+		return
+	}
+
+	occurredAt := time.Time{}
+	if payload.OccurredAt != nil {
+		occurredAt = *payload.OccurredAt
+	}
+
+	if err := shipment.UpdateStatus(server.DB, payload.Status, payload.Description, payload.Location, occurredAt); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, models.ErrInvalidShipmentStatus) || errors.Is(err, models.ErrShipmentAlreadyClosed) {
+			status = http.StatusUnprocessableEntity
+		}
+		_ = ren.JSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, shipment)
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/codeuiprogramming/e-commerce/app/helpers"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/shipping"
+)
+
+// status yang dikirimkan ke pelanggan lewat email
+var shipmentMilestones = map[string]string{
+	consts.ShipmentStatusInTransit:      "Pesanan Anda sedang dalam perjalanan",
+	consts.ShipmentStatusOutForDelivery: "Pesanan Anda sedang diantar kurir",
+	consts.ShipmentStatusDelivered:      "Pesanan Anda sudah diterima",
+	consts.ShipmentStatusFailedDelivery: "Pengantaran pesanan Anda gagal",
+	consts.ShipmentStatusReturned:       "Pesanan Anda dikembalikan ke pengirim",
+}
+
+var pollPausedUntil time.Time
+var pollMu sync.Mutex
+
+func envMinutes(key string, fallback time.Duration) time.Duration {
+	if v, err := strconv.Atoi(os.Getenv(key)); err == nil && v > 0 {
+		return time.Duration(v) * time.Minute
+	}
+
+	return fallback
+}
+
+// StartShipmentPoller menjalankan PollShipments setiap SHIPMENT_POLL_INTERVAL menit
+// (default 30) jika SHIPMENT_POLL_ENABLED=true.
+func (server *Server) StartShipmentPoller() {
+	if os.Getenv("SHIPMENT_POLL_ENABLED") != "true" {
+		return
+	}
+
+	interval := envMinutes("SHIPMENT_POLL_INTERVAL", 30*time.Minute)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for ; ; <-ticker.C {
+			polled, err := server.PollShipments(server.ShippingClient())
+			if err != nil {
+				log.Println("shipment poller:", err)
+			}
+			if polled > 0 {
+				log.Printf("shipment poller: polled %d shipments\n", polled)
+			}
+		}
+	}()
+}
+
+// PollShipments melacak semua shipment yang belum selesai dan sudah jatuh tempo.
+// Shipment yang gagal dilacak dijadwalkan ulang dengan backoff; jika kurir
+// membatasi request (429) seluruh polling dihentikan sampai Retry-After lewat.
+func (server *Server) PollShipments(provider shipping.TrackingProvider) (int, error) {
+	pollMu.Lock()
+	defer pollMu.Unlock()
+
+	now := time.Now()
+	if now.Before(pollPausedUntil) {
+		return 0, fmt.Errorf("rate limited until %s", pollPausedUntil.Format(time.RFC3339))
+	}
+
+	batch := 50
+	if v, err := strconv.Atoi(os.Getenv("SHIPMENT_POLL_BATCH")); err == nil && v > 0 {
+		batch = v
+	}
+	base := envMinutes("SHIPMENT_POLL_INTERVAL", 30*time.Minute)
+	maxWait := 24 * time.Hour
+
+	shipmentModel := models.Shipment{}
+	shipments, err := shipmentModel.GetShipmentsDueForPolling(server.DB, now, batch)
+	if err != nil {
+		return 0, err
+	}
+
+	polled := 0
+	for i := range shipments {
+		shipment := &shipments[i]
+
+		result, err := provider.Track(shipment.TrackNumber, shipment.Courier)
+		if err != nil {
+			if rateLimited, ok := shipping.IsRateLimited(err); ok {
+				pollPausedUntil = time.Now().Add(rateLimited.RetryAfter)
+				return polled, err
+			}
+			log.Printf("shipment poller: %s (%s): %v\n", shipment.TrackNumber, shipment.ID, err)
+			shipment.SchedulePoll(server.DB, time.Now(), true, base, maxWait)
+			continue
+		}
+		polled++
+
+		var events []models.ShipmentEvent
+		for _, event := range result.Events {
+			events = append(events, models.ShipmentEvent{
+				Status:      event.Status,
+				Description: event.Description,
+				Location:    event.Location,
+				OccurredAt:  event.OccurredAt,
+			})
+		}
+
+		if _, changed, err := shipment.SyncTrackingEvents(server.DB, events, result.Status); err != nil {
+			log.Printf("shipment poller: %s: %v\n", shipment.ID, err)
+		} else if changed {
+			server.notifyShipmentMilestone(shipment)
+		}
+
+		shipment.SchedulePoll(server.DB, time.Now(), false, base, maxWait)
+	}
+
+	return polled, nil
+}
+
+func (server *Server) notifyShipmentMilestone(shipment *models.Shipment) {
+	subject, ok := shipmentMilestones[shipment.Status]
+	if !ok || shipment.Email == "" {
+		return
+	}
+
+	appURL := ""
+	if server.AppConfig != nil {
+		appURL = server.AppConfig.AppURL
+	}
+
+	body := fmt.Sprintf("%s.\n\nKurir: %s %s\nNo. Resi: %s\n\nLacak pesanan: %s/orders/%s/tracking",
+		subject, shipment.Courier, shipment.Service, shipment.TrackNumber, appURL, shipment.OrderID)
+
+	if err := helpers.SendMail(shipment.Email, subject, body); err != nil && !errors.Is(err, helpers.ErrMailDisabled) {
+		persistError(err)
+	}
+}
@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+func shippingMode() string {
+	switch mode := strings.ToLower(os.Getenv("SHIPPING_MODE")); mode {
+	case consts.ShippingModeAPI, consts.ShippingModeRules:
+		return mode
+	default:
+		return consts.ShippingModeBoth
+	}
+}
+
+func (server *Server) activeShippingMethods() []models.ShippingMethod {
+	if shippingMode() == consts.ShippingModeAPI {
+		return nil
+	}
+
+	methodModel := models.ShippingMethod{}
+	methods, err := methodModel.GetActiveShippingMethods(server.DB)
+	if err != nil {
+		persistError(err)
+		return nil
+	}
+
+	return methods
+}
+
+func findShippingMethod(methods []models.ShippingMethod, code string) *models.ShippingMethod {
+	for i := range methods {
+		if methods[i].Type != consts.ShippingMethodTypeSurcharge && strings.EqualFold(methods[i].Code, code) {
+			return &methods[i]
+		}
+	}
+
+	return nil
+}
+
+func shippingMethodOption(method *models.ShippingMethod, params models.ShippingFeeParams) (models.ShippingFeeOption, bool) {
+	fee, ok := method.Quote(params)
+	if !ok {
+		return models.ShippingFeeOption{}, false
+	}
+
+	return models.ShippingFeeOption{
+		Courier:     method.Code,
+		ServiceCode: strings.ToUpper(method.Type),
+		Service:     method.Name,
+		Description: method.Name,
+		Fee:         fee.Ceil().IntPart(),
+		Etd:         method.Etd,
+	}, true
+}
+
+func applyShippingSurcharges(methods []models.ShippingMethod, params models.ShippingFeeParams, options []models.ShippingFeeOption) []models.ShippingFeeOption {
+	surcharge := decimal.Zero
+	for i := range methods {
+		surcharge = surcharge.Add(methods[i].Surcharge(params))
+	}
+	if surcharge.IsZero() {
+		return options
+	}
+
+	for i := range options {
+		options[i].Fee += surcharge.Ceil().IntPart()
+	}
+
+	return options
+}
+
+// shippingParams menyusun parameter ongkir dari keranjang. Provinsi hanya
+// diambil dari tabel wilayah berdasarkan kota tujuan, tidak pernah dari form,
+// karena dipakai untuk aturan zona ongkir dan syarat COD. Jika kota tidak dikenal
+// (atau tabel wilayah kosong) provinsi dibiarkan kosong sehingga aturan yang
+// dibatasi provinsi tidak berlaku. Origin adalah kota gudang yang dipilih untuk
+// memenuhi keranjang, atau API_ONGKIR_ORIGIN bila belum ada gudang.
+func (server *Server) shippingParams(cart *models.Cart, cityID string) (models.ShippingFeeParams, error) {
+	provinceID := ""
+	if cityID != "" {
+		var city models.RegionCity
+		if err := server.DB.Where("id = ?", cityID).First(&city).Error; err == nil {
+			provinceID = city.ProvinceID
+		}
+	}
+
+	params := models.ShippingFeeParams{
+		Origin:      os.Getenv("API_ONGKIR_ORIGIN"),
+		Destination: cityID,
+		Weight:      cart.TotalWeight,
+		Parcel:      cart.Parcel,
+		ProvinceID:  provinceID,
+		Subtotal:    cart.BaseTotalPrice,
+	}
+
+	warehouse, err := server.fulfilmentWarehouse(cart, cityID, provinceID)
+	if err != nil {
+		return params, err
+	}
+	if warehouse != nil {
+		params.Origin = warehouse.CityID
+		params.WarehouseID = warehouse.ID
+	}
+
+	return params, nil
+}
+
+// APIAdminShippingMethods handles JSON list and create for shipping rules
+func (server *Server) APIAdminShippingMethods(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	switch r.Method {
+	case "GET":
+		var methods []models.ShippingMethod
+		server.DB.Order("code asc").Find(&methods)
+		_ = ren.JSON(w, http.StatusOK, methods)
+		return
+	case "POST":
+		// aturan baru aktif kecuali is_active dikirim false
+		method := models.ShippingMethod{IsActive: true}
+		if err := json.NewDecoder(r.Body).Decode(&method); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		method.ID = ""
+		method.Code = strings.ToLower(strings.TrimSpace(method.Code))
+		if method.Code == "" || !models.IsValidShippingMethodType(method.Type) {
+			http.Error(w, "code and a valid type are required", http.StatusBadRequest)
+			return
+		}
+		if _, err := method.GetTiers(); err != nil {
+			http.Error(w, "invalid tiers: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := server.DB.Create(&method).Error; err != nil {
+			persistError(err)
+			_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		_ = ren.JSON(w, http.StatusCreated, method)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminShippingMethod handles GET/PUT/DELETE for a single shipping rule
+func (server *Server) APIAdminShippingMethod(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	var method models.ShippingMethod
+	if err := server.DB.Where("id = ?", vars["id"]).First(&method).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		_ = ren.JSON(w, http.StatusOK, method)
+		return
+	case "PUT":
+		var payload models.ShippingMethod
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		payload.ID = method.ID
+		payload.CreatedAt = method.CreatedAt
+		payload.Code = strings.ToLower(strings.TrimSpace(payload.Code))
+		if payload.Code == "" || !models.IsValidShippingMethodType(payload.Type) {
+			http.Error(w, "code and a valid type are required", http.StatusBadRequest)
+			return
+		}
+		if _, err := payload.GetTiers(); err != nil {
+			http.Error(w, "invalid tiers: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := server.DB.Save(&payload).Error; err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		_ = ren.JSON(w, http.StatusOK, payload)
+		return
+	case "DELETE":
+		if err := server.DB.Where("id = ?", method.ID).Delete(&models.ShippingMethod{}).Error; err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
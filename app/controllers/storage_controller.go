@@ -0,0 +1,157 @@
+package controllers
+
+import (
+	"bytes"
+	"io/fs"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/codeuiprogramming/e-commerce/app/storage"
+)
+
+var fileStorage storage.Storage
+var fileStorageOnce sync.Once
+
+// Storage mengembalikan backend upload bersama (dipilih dari STORAGE_DRIVER).
+// Konfigurasi yang salah menghentikan aplikasi saat start.
+func (server *Server) Storage() storage.Storage {
+	fileStorageOnce.Do(func() {
+		store, err := storage.NewFromEnv()
+		if err != nil {
+			log.Fatal(err)
+		}
+		fileStorage = store
+	})
+
+	return fileStorage
+}
+
+// serveUpload melayani upload yang tidak ditemukan static handler: dari disk
+// untuk storage lokal, atau redirect ke URL bucket/presigned untuk S3.
+func (server *Server) serveUpload(w http.ResponseWriter, r *http.Request, key string) {
+	switch store := server.Storage().(type) {
+	case *storage.Local:
+		full, err := store.Path(key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, full)
+	default:
+		target := store.URL(key)
+		if target == "" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	}
+}
+
+// putUpload menyimpan data ke storage dengan content type dari ekstensi key.
+func (server *Server) putUpload(key string, data []byte) error {
+	return server.Storage().Put(key, bytes.NewReader(data), mime.TypeByExtension(filepath.Ext(key)))
+}
+
+// moveUpload memindahkan file ke key lain (salin lalu hapus, karena S3 tidak
+// punya rename).
+func (server *Server) moveUpload(from string, to string) error {
+	data, err := storage.ReadAll(server.Storage(), from)
+	if err != nil {
+		return err
+	}
+	if err := server.putUpload(to, data); err != nil {
+		return err
+	}
+
+	return server.Storage().Delete(from)
+}
+
+// MigrateUploads memindahkan file di {dir}/uploads (default public dan assets)
+// ke storage aktif dengan key yang sama, sehingga path di database tidak
+// berubah. File yang sudah ada di storage dilewati; deleteLocal menghapus file
+// lokal setelah berhasil disalin.
+func (server *Server) MigrateUploads(dirs []string, deleteLocal bool, dryRun bool) (int, int, error) {
+	store := server.Storage()
+	copied, skipped := 0, 0
+
+	for _, dir := range dirs {
+		// file di root storage lokal sudah berada di tempatnya
+		if local, ok := store.(*storage.Local); ok && filepath.Clean(local.Root) == filepath.Clean(dir) {
+			continue
+		}
+
+		root := filepath.Join(dir, "uploads")
+		err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) && path == root {
+					return filepath.SkipDir
+				}
+				return err
+			}
+			if entry.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			key := filepath.ToSlash(rel)
+
+			exists, err := uploadMigrated(store, key)
+			if err != nil {
+				return err
+			}
+			if exists {
+				skipped++
+				if deleteLocal && !dryRun {
+					return os.Remove(path)
+				}
+				return nil
+			}
+			if dryRun {
+				log.Println("storage migrate: would copy", key)
+				copied++
+				return nil
+			}
+
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			if err := server.putUpload(key, data); err != nil {
+				return err
+			}
+			copied++
+			if deleteLocal {
+				return os.Remove(path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return copied, skipped, err
+		}
+	}
+
+	return copied, skipped, nil
+}
+
+// uploadMigrated memeriksa apakah key sudah ada di storage tujuan. Untuk storage
+// lokal hanya Root yang diperiksa, karena Exists juga membaca public/ dan
+// assets/ yang sedang dipindahkan.
+func uploadMigrated(store storage.Storage, key string) (bool, error) {
+	if local, ok := store.(*storage.Local); ok {
+		_, err := os.Stat(filepath.Join(local.Root, filepath.FromSlash(key)))
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	return store.Exists(key)
+}
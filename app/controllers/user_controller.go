@@ -5,7 +5,6 @@ import (
 	"html/template"
 	"io"
 	"net/http"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -13,6 +12,7 @@ import (
 
 	"github.com/codeuiprogramming/e-commerce/app/helpers"
 	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/codeuiprogramming/e-commerce/app/storage"
 	"github.com/google/uuid"
 	"github.com/gorilla/sessions"
 	"github.com/unrolled/render"
@@ -183,6 +183,13 @@ func (server *Server) Profile(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// wallet balance and latest ledger entries
+	if u != nil {
+		walletModel := models.Wallet{}
+		data["walletBalance"] = walletModel.GetBalance(server.DB, u.ID)
+		data["walletTransactions"], _ = walletModel.GetTransactions(server.DB, u.ID, 10)
+	}
+
 	// load provinces server-side so template can render options immediately
 	if provinces, err := server.GetProvince(); err == nil {
 		data["provinces"] = provinces
@@ -195,6 +202,11 @@ func (server *Server) Profile(w http.ResponseWriter, r *http.Request) {
 		var addrs []models.Address
 		if err := server.DB.Where("user_id = ?", u.ID).Order("created_at desc").Find(&addrs).Error; err == nil {
 			data["addresses"] = addrs
+			addressRegions := map[string]models.RegionNames{}
+			for _, addr := range addrs {
+				addressRegions[addr.ID] = addr.RegionNames(server.DB)
+			}
+			data["addressRegions"] = addressRegions
 		} else {
 			data["addresses"] = nil
 		}
@@ -250,52 +262,46 @@ func (server *Server) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// handle avatar upload (optional)
-	file, handler, err := r.FormFile("avatar")
+	file, _, err := r.FormFile("avatar")
 	if err == nil {
 		defer file.Close()
 
-		// ensure upload dir exists (place under assets so it's served at /public/...)
-		uploadDir := "assets/uploads/avatars"
-		if err := os.MkdirAll(uploadDir, 0755); err != nil {
-			SetFlash(w, r, "error", "failed to create upload directory")
+		data, err := io.ReadAll(file)
+		if err != nil {
+			SetFlash(w, r, "error", "failed to read uploaded file")
 			http.Redirect(w, r, "/profile", http.StatusSeeOther)
 			return
 		}
 
-		// remove any existing files for this user (cleanup old uploads)
-		oldPattern := filepath.Join(uploadDir, user.ID+".*")
-		oldFiles, _ := filepath.Glob(oldPattern)
-		for _, f := range oldFiles {
-			_ = os.Remove(f)
-		}
-
-		// also remove DB records for avatars for this user (ensure table exists)
-		_ = server.DB.AutoMigrate(&models.UserAvatar{})
-		_ = server.DB.Where("user_id = ?", user.ID).Delete(&models.UserAvatar{}).Error
-
-		ext := filepath.Ext(handler.Filename)
-		if ext == "" {
-			ext = ".jpg"
+		// ekstensi diambil dari isi file, bukan nama file dari klien; tipe yang
+		// diterima sama dengan foto ulasan
+		ext, ok := reviewPhotoTypes[http.DetectContentType(data)]
+		if !ok {
+			SetFlash(w, r, "error", "avatar must be a JPG, PNG, GIF or WebP image")
+			http.Redirect(w, r, "/profile", http.StatusSeeOther)
+			return
 		}
-		filename := fmt.Sprintf("%s%s", user.ID, ext)
-		dstPath := filepath.Join(uploadDir, filename)
-
-		dst, err := os.Create(dstPath)
-		if err != nil {
+		// simpan di storage upload, dilayani lewat /public/uploads/avatars/...
+		key := "uploads/avatars/" + user.ID + ext
+		if err := server.putUpload(key, data); err != nil {
 			SetFlash(w, r, "error", "failed to save uploaded file")
 			http.Redirect(w, r, "/profile", http.StatusSeeOther)
 			return
 		}
-		defer dst.Close()
 
-		if _, err := io.Copy(dst, file); err != nil {
-			SetFlash(w, r, "error", "failed to write uploaded file")
-			http.Redirect(w, r, "/profile", http.StatusSeeOther)
-			return
+		// remove old avatar files and DB records for this user (ensure table exists)
+		_ = server.DB.AutoMigrate(&models.UserAvatar{})
+		var oldAvatars []models.UserAvatar
+		server.DB.Where("user_id = ?", user.ID).Find(&oldAvatars)
+		for _, old := range oldAvatars {
+			if oldKey := storage.KeyFromPath(old.Path); oldKey != key && storage.IsUploadKey(oldKey) {
+				_ = server.Storage().Delete(oldKey)
+			}
 		}
+		_ = server.DB.Where("user_id = ?", user.ID).Delete(&models.UserAvatar{}).Error
 
 		// create DB record for this avatar (path uses public mapping)
-		avatarPath := "/public/uploads/avatars/" + filename
+		avatarPath := "/public/" + key
 		avatarRecord := &models.UserAvatar{
 			ID:        uuid.New().String(),
 			UserID:    user.ID,
@@ -304,7 +310,7 @@ func (server *Server) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 		}
 		if err := server.DB.Create(avatarRecord).Error; err != nil {
 			// if DB insert failed, remove the saved file and report error
-			_ = os.Remove(dstPath)
+			_ = server.Storage().Delete(key)
 			SetFlash(w, r, "error", "failed to save avatar metadata")
 			http.Redirect(w, r, "/profile", http.StatusSeeOther)
 			return
@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+)
+
+// APIAdminUserWallet shows a user's wallet ledger (GET) or credits it (POST)
+// for refunds, goodwill gestures and promotions.
+func (server *Server) APIAdminUserWallet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	userModel := models.User{}
+	user, err := userModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	walletModel := models.Wallet{}
+	switch r.Method {
+	case "GET":
+		transactions, err := walletModel.GetTransactions(server.DB, user.ID, 100)
+		if err != nil {
+			http.Error(w, "failed to load wallet", http.StatusInternalServerError)
+			return
+		}
+		_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+			"user_id":      user.ID,
+			"balance":      walletModel.GetBalance(server.DB, user.ID),
+			"transactions": transactions,
+		})
+		return
+	case "POST":
+		var payload struct {
+			Amount    decimal.Decimal `json:"amount"`
+			Reason    string          `json:"reason"`
+			Reference string          `json:"reference"`
+			Note      string          `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+
+		switch payload.Reason {
+		case consts.WalletReasonRefund, consts.WalletReasonGoodwill, consts.WalletReasonPromotion, consts.WalletReasonAdjustment:
+		default:
+			http.Error(w, "reason must be refund, goodwill, promotion or adjustment", http.StatusBadRequest)
+			return
+		}
+
+		actor := ""
+		if admin := server.CurrentUser(w, r); admin != nil {
+			actor = admin.ID
+		}
+
+		entry, err := walletModel.Credit(server.DB, user.ID, payload.Amount, payload.Reason, payload.Reference, payload.Note, actor)
+		if err != nil {
+			_ = ren.JSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+			return
+		}
+		_ = ren.JSON(w, http.StatusCreated, entry)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
@@ -0,0 +1,358 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// cartItemQuantities menjumlahkan qty keranjang per produk.
+func cartItemQuantities(cart *models.Cart) map[string]int {
+	items := map[string]int{}
+	for _, item := range cart.CartItems {
+		items[item.ProductID] += item.Qty
+	}
+
+	return items
+}
+
+// fulfilmentWarehouse memilih gudang terdekat yang punya stok untuk seluruh isi
+// keranjang. Nil berarti toko belum memakai gudang (origin dari API_ONGKIR_ORIGIN).
+func (server *Server) fulfilmentWarehouse(cart *models.Cart, cityID string, provinceID string) (*models.Warehouse, error) {
+	if provinceID == "" && cityID != "" {
+		var city models.RegionCity
+		if err := server.DB.Where("id = ?", cityID).First(&city).Error; err == nil {
+			provinceID = city.ProvinceID
+		}
+	}
+
+	return models.SelectFulfilmentWarehouse(server.DB, cartItemQuantities(cart), cityID, provinceID)
+}
+
+func warehouseID(warehouse *models.Warehouse) string {
+	if warehouse == nil {
+		return ""
+	}
+
+	return warehouse.ID
+}
+
+// shippingOrigin menjelaskan asal pengiriman untuk ditampilkan di keranjang.
+func (server *Server) shippingOrigin(params models.ShippingFeeParams) map[string]string {
+	origin := map[string]string{"city_id": params.Origin}
+	if params.WarehouseID == "" {
+		return origin
+	}
+
+	warehouseModel := models.Warehouse{}
+	if warehouse, err := warehouseModel.FindByID(server.DB, params.WarehouseID); err == nil {
+		origin["warehouse_id"] = warehouse.ID
+		origin["name"] = warehouse.Name
+		origin["city"] = models.ResolveRegionNames(server.DB, "", warehouse.CityID, "").City
+	}
+
+	return origin
+}
+
+type warehousePayload struct {
+	Code       *string `json:"code"`
+	Name       *string `json:"name"`
+	Address    *string `json:"address"`
+	Phone      *string `json:"phone"`
+	CityID     *string `json:"city_id"`
+	ProvinceID *string `json:"province_id"`
+	PostCode   *string `json:"post_code"`
+	Priority   *int    `json:"priority"`
+	IsActive   *bool   `json:"is_active"`
+}
+
+func (p warehousePayload) apply(warehouse *models.Warehouse) {
+	if p.Code != nil {
+		warehouse.Code = models.NormalizeWarehouseCode(*p.Code)
+	}
+	if p.Name != nil {
+		warehouse.Name = strings.TrimSpace(*p.Name)
+	}
+	if p.Address != nil {
+		warehouse.Address = *p.Address
+	}
+	if p.Phone != nil {
+		warehouse.Phone = *p.Phone
+	}
+	if p.CityID != nil {
+		warehouse.CityID = strings.TrimSpace(*p.CityID)
+	}
+	if p.ProvinceID != nil {
+		warehouse.ProvinceID = strings.TrimSpace(*p.ProvinceID)
+	}
+	if p.PostCode != nil {
+		warehouse.PostCode = *p.PostCode
+	}
+	if p.Priority != nil {
+		warehouse.Priority = *p.Priority
+	}
+	if p.IsActive != nil {
+		warehouse.IsActive = *p.IsActive
+	}
+}
+
+func validateWarehouse(warehouse *models.Warehouse) string {
+	if warehouse.Code == "" || warehouse.Name == "" {
+		return "code and name are required"
+	}
+	if warehouse.CityID == "" {
+		return "city_id is required as shipping origin"
+	}
+
+	return ""
+}
+
+// APIAdminWarehouses handles JSON list and create for warehouses
+func (server *Server) APIAdminWarehouses(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	switch r.Method {
+	case "GET":
+		var warehouses []models.Warehouse
+		server.DB.Order("priority asc, name asc").Find(&warehouses)
+		_ = ren.JSON(w, http.StatusOK, warehouses)
+		return
+	case "POST":
+		var payload warehousePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		warehouse := models.Warehouse{IsActive: true}
+		payload.apply(&warehouse)
+		if msg := validateWarehouse(&warehouse); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		if err := server.DB.Create(&warehouse).Error; err != nil {
+			persistError(err)
+			_ = ren.JSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		_ = ren.JSON(w, http.StatusCreated, warehouse)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminWarehouse handles GET/PUT/DELETE for a single warehouse
+func (server *Server) APIAdminWarehouse(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	warehouseModel := models.Warehouse{}
+	warehouse, err := warehouseModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		_ = ren.JSON(w, http.StatusOK, warehouse)
+		return
+	case "PUT":
+		var payload warehousePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		payload.apply(warehouse)
+		if msg := validateWarehouse(warehouse); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		if err := server.DB.Save(warehouse).Error; err != nil {
+			http.Error(w, "failed to update", http.StatusInternalServerError)
+			return
+		}
+		_ = ren.JSON(w, http.StatusOK, warehouse)
+		return
+	case "DELETE":
+		// gudang yang masih menyimpan stok harus dikosongkan (transfer) dulu
+		var remaining int64
+		server.DB.Model(&models.WarehouseStock{}).Where("warehouse_id = ? AND qty > 0", warehouse.ID).Count(&remaining)
+		if remaining > 0 {
+			_ = ren.JSON(w, http.StatusConflict, map[string]string{"error": "warehouse still holds stock, transfer it first"})
+			return
+		}
+		if err := server.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("warehouse_id = ?", warehouse.ID).Delete(&models.WarehouseStock{}).Error; err != nil {
+				return err
+			}
+			return tx.Delete(warehouse).Error
+		}); err != nil {
+			http.Error(w, "failed to delete", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminWarehouseStocks menampilkan stok di satu gudang (GET) atau menetapkan
+// stok satu produk di gudang tersebut (POST {product_id, qty}).
+func (server *Server) APIAdminWarehouseStocks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	warehouseModel := models.Warehouse{}
+	warehouse, err := warehouseModel.FindByID(server.DB, vars["id"])
+	if err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		stocks, err := warehouse.GetStocks(server.DB)
+		if err != nil {
+			http.Error(w, "failed to load stocks", http.StatusInternalServerError)
+			return
+		}
+		out := []map[string]interface{}{}
+		for _, stock := range stocks {
+			out = append(out, map[string]interface{}{
+				"product_id": stock.ProductID,
+				"sku":        stock.Product.Sku,
+				"name":       stock.Product.Name,
+				"qty":        stock.Qty,
+				"updated_at": stock.UpdatedAt,
+			})
+		}
+		_ = ren.JSON(w, http.StatusOK, map[string]interface{}{"warehouse": warehouse, "stocks": out})
+		return
+	case "POST":
+		var payload struct {
+			ProductID string `json:"product_id"`
+			Qty       int    `json:"qty"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+		var product models.Product
+		if err := server.DB.Where("id = ? OR sku = ?", payload.ProductID, payload.ProductID).First(&product).Error; err != nil {
+			http.Error(w, "product not found", http.StatusNotFound)
+			return
+		}
+		actor := ""
+		if admin := server.CurrentUser(w, r); admin != nil {
+			actor = admin.ID
+		}
+		stock, err := warehouse.SetStock(server.DB, product.ID, payload.Qty, actor)
+		if err != nil {
+			_ = ren.JSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+			return
+		}
+		_ = ren.JSON(w, http.StatusOK, stock)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
+
+// APIAdminProductStocks menampilkan stok satu produk per gudang.
+func (server *Server) APIAdminProductStocks(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+	vars := mux.Vars(r)
+
+	var product models.Product
+	if err := server.DB.Where("id = ?", vars["id"]).First(&product).Error; err != nil {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	stocks, err := models.GetProductStocks(server.DB, product.ID)
+	if err != nil {
+		http.Error(w, "failed to load stocks", http.StatusInternalServerError)
+		return
+	}
+
+	_ = ren.JSON(w, http.StatusOK, map[string]interface{}{
+		"product_id": product.ID,
+		"total":      product.Stock,
+		"stocks":     stocks,
+	})
+}
+
+// APIAdminStockTransfers menampilkan riwayat transfer (GET, filter product_id)
+// atau memindahkan stok antar gudang (POST).
+func (server *Server) APIAdminStockTransfers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	ren := newAdminRender()
+
+	switch r.Method {
+	case "GET":
+		var transfers []models.StockTransfer
+		query := server.DB.Order("created_at desc").Limit(100)
+		if productID := r.URL.Query().Get("product_id"); productID != "" {
+			query = query.Where("product_id = ?", productID)
+		}
+		query.Find(&transfers)
+		_ = ren.JSON(w, http.StatusOK, transfers)
+		return
+	case "POST":
+		var payload struct {
+			ProductID       string `json:"product_id"`
+			FromWarehouseID string `json:"from_warehouse_id"`
+			ToWarehouseID   string `json:"to_warehouse_id"`
+			Qty             int    `json:"qty"`
+			Note            string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, "invalid json", http.StatusBadRequest)
+			return
+		}
+
+		warehouseModel := models.Warehouse{}
+		from, err := warehouseModel.FindByID(server.DB, payload.FromWarehouseID)
+		if err != nil {
+			http.Error(w, "source warehouse not found", http.StatusNotFound)
+			return
+		}
+		to, err := warehouseModel.FindByID(server.DB, payload.ToWarehouseID)
+		if err != nil {
+			http.Error(w, "target warehouse not found", http.StatusNotFound)
+			return
+		}
+
+		actor := ""
+		if admin := server.CurrentUser(w, r); admin != nil {
+			actor = admin.ID
+		}
+		transfer, err := models.TransferStock(server.DB, payload.ProductID, from.ID, to.ID, payload.Qty, payload.Note, actor)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, models.ErrInvalidStockTransfer) || errors.Is(err, models.ErrInsufficientWarehouseStock) {
+				status = http.StatusUnprocessableEntity
+			}
+			_ = ren.JSON(w, status, map[string]string{"error": err.Error()})
+			return
+		}
+		_ = ren.JSON(w, http.StatusCreated, transfer)
+		return
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+}
@@ -0,0 +1,56 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/smtp"
+	"os"
+	"strings"
+)
+
+// ErrMailDisabled dikembalikan SendMail bila SMTP_HOST belum diset.
+var ErrMailDisabled = errors.New("mail: SMTP_HOST is not set")
+
+// SendMail mengirim email teks lewat SMTP (SMTP_HOST, SMTP_PORT, SMTP_USERNAME,
+// SMTP_PASSWORD, MAIL_FROM). Jika SMTP_HOST kosong, email tidak dikirim dan
+// hanya penerima dan subjek yang dicatat ke log; isi email (bisa berisi kode
+// rahasia seperti gift card) tidak pernah ditulis ke log.
+func SendMail(to string, subject string, body string) error {
+	host := os.Getenv("SMTP_HOST")
+	if host == "" {
+		log.Printf("SendMail skipped (SMTP_HOST not set) to=%s subject=%q", to, subject)
+		return ErrMailDisabled
+	}
+
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = os.Getenv("SMTP_USERNAME")
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	msg := strings.Join([]string{
+		"From: " + from,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=\"UTF-8\"",
+		"",
+		body,
+	}, "\r\n")
+
+	if err := smtp.SendMail(host+":"+port, auth, from, []string{to}, []byte(msg)); err != nil {
+		return fmt.Errorf("send mail to %s: %w", to, err)
+	}
+
+	return nil
+}
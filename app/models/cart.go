@@ -1,10 +1,19 @@
 package models
 
 import (
+	"errors"
+	"time"
+
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrShippingQuoteMissing = errors.New("pilih paket pengiriman terlebih dahulu")
+	ErrShippingQuoteExpired = errors.New("ongkos kirim sudah kedaluwarsa, silakan pilih ulang paket pengiriman")
+	ErrShippingQuoteChanged = errors.New("alamat atau isi keranjang berubah, silakan pilih ulang paket pengiriman")
+)
+
 type Cart struct {
 	ID 				string `gorm:"size:36;not null;uniqueIndex;primary_key"`
 	CartItems 		[]CartItem
@@ -14,8 +23,17 @@ type Cart struct {
 	DiscountAmount 	decimal.Decimal `gorm:"type:decimal(16,2)"`
 	DiscountPercent decimal.Decimal `gorm:"type:decimal(10,2)"`
 	ShippingFee     decimal.Decimal `gorm:"type:decimal(16,2)"`  // **Tambahan baru**
+	// quote ongkir yang dipilih di keranjang; checkout hanya memakai nilai ini
+	ShippingCourier        string `gorm:"size:50"`
+	ShippingService        string `gorm:"size:100"`
+	ShippingCityID         string `gorm:"size:20"`
+	ShippingProvinceID     string `gorm:"size:20"`
+	ShippingWarehouseID    string `gorm:"size:36"`
+	ShippingWeight         int
+	ShippingQuoteExpiresAt *time.Time
 	GrandTotal 		decimal.Decimal `gorm:"type:decimal(16,2)"`
 	TotalWeight 	int 			`gorm:"-"`
+	Parcel          *Parcel         `gorm:"-"`
 }
 
 func (c *Cart) GetCart(db *gorm.DB, cartID string) (*Cart, error) {
@@ -226,4 +244,70 @@ func (c *Cart) ClearCart(db *gorm.DB, cartID string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// SaveShippingQuote menyimpan paket ongkir yang dipilih beserta tujuan, berat
+// dan masa berlakunya.
+func (c *Cart) SaveShippingQuote(db *gorm.DB, courier string, service string, fee decimal.Decimal, cityID string, provinceID string, warehouseID string, weight int, expiresAt time.Time) error {
+	c.ShippingCourier = courier
+	c.ShippingService = service
+	c.ShippingFee = fee
+	c.ShippingCityID = cityID
+	c.ShippingProvinceID = provinceID
+	c.ShippingWarehouseID = warehouseID
+	c.ShippingWeight = weight
+	c.ShippingQuoteExpiresAt = &expiresAt
+
+	return db.Debug().Model(&Cart{}).Where("id = ?", c.ID).Updates(map[string]interface{}{
+		"shipping_courier":          courier,
+		"shipping_service":          service,
+		"shipping_fee":              fee,
+		"shipping_city_id":          cityID,
+		"shipping_province_id":      provinceID,
+		"shipping_warehouse_id":     warehouseID,
+		"shipping_weight":           weight,
+		"shipping_quote_expires_at": expiresAt,
+	}).Error
+}
+
+func (c *Cart) ClearShippingQuote(db *gorm.DB) error {
+	if c.ShippingQuoteExpiresAt == nil && c.ShippingCourier == "" {
+		return nil
+	}
+
+	c.ShippingCourier = ""
+	c.ShippingService = ""
+	c.ShippingFee = decimal.Zero
+	c.ShippingCityID = ""
+	c.ShippingProvinceID = ""
+	c.ShippingWarehouseID = ""
+	c.ShippingWeight = 0
+	c.ShippingQuoteExpiresAt = nil
+
+	return db.Debug().Model(&Cart{}).Where("id = ?", c.ID).Updates(map[string]interface{}{
+		"shipping_courier":          "",
+		"shipping_service":          "",
+		"shipping_fee":              decimal.Zero,
+		"shipping_city_id":          "",
+		"shipping_province_id":      "",
+		"shipping_warehouse_id":     "",
+		"shipping_weight":           0,
+		"shipping_quote_expires_at": nil,
+	}).Error
+}
+
+// ValidateShippingQuote memastikan quote tersimpan masih berlaku untuk kota
+// tujuan dan berat yang ditagih untuk isi keranjang saat ini.
+func (c *Cart) ValidateShippingQuote(cityID string, weight int, now time.Time) error {
+	if c.ShippingQuoteExpiresAt == nil || c.ShippingCourier == "" {
+		return ErrShippingQuoteMissing
+	}
+	if now.After(*c.ShippingQuoteExpiresAt) {
+		return ErrShippingQuoteExpired
+	}
+	if c.ShippingCityID != cityID || c.ShippingWeight != weight {
+		return ErrShippingQuoteChanged
+	}
+
+	return nil
+}
@@ -1,6 +1,14 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrCategoryCycle = errors.New("kategori tidak boleh menjadi induk dari dirinya sendiri")
 
 type Category struct {
 	ID        string `gorm:"size:36;not null;uniqueIndex;primary_key"`
@@ -9,7 +17,186 @@ type Category struct {
 	SectionID string     `gorm:"size:36;index"`
 	Products  []Product  `gorm:"many2many:product_categories;"`
 	Name      string     `gorm:"size:100;"`
-	Slug      string     `gorm:"size:100;"`
+	Slug      string     `gorm:"size:100;index"`
+	Position  int        `gorm:"default:0"`
+	Children  []Category `gorm:"-"`
 	CreatedAt time.Time
 	UpdateAt  time.Time
-}
\ No newline at end of file
+}
+
+func (c *Category) BeforeCreate(db *gorm.DB) error {
+	if c.ID == "" {
+		c.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func (c *Category) GetCategories(db *gorm.DB) ([]Category, error) {
+	var categories []Category
+	err := db.Debug().Order("position asc, name asc").Find(&categories).Error
+
+	return categories, err
+}
+
+func (c *Category) FindByID(db *gorm.DB, id string) (*Category, error) {
+	var category Category
+	if err := db.Debug().Preload("Section").Where("id = ?", id).First(&category).Error; err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+func (c *Category) FindBySlug(db *gorm.DB, slug string) (*Category, error) {
+	var category Category
+	if err := db.Debug().Preload("Section").Where("slug = ?", slug).First(&category).Error; err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// BuildCategoryTree menyusun daftar kategori datar menjadi pohon berdasarkan
+// ParentID. Kategori yang induknya tidak ada dianggap akar.
+func BuildCategoryTree(categories []Category) []Category {
+	byParent := map[string][]Category{}
+	ids := map[string]bool{}
+	for _, category := range categories {
+		ids[category.ID] = true
+	}
+	for _, category := range categories {
+		parentID := category.ParentID
+		if !ids[parentID] {
+			parentID = ""
+		}
+		byParent[parentID] = append(byParent[parentID], category)
+	}
+
+	var build func(parentID string) []Category
+	build = func(parentID string) []Category {
+		nodes := byParent[parentID]
+		for i := range nodes {
+			nodes[i].Children = build(nodes[i].ID)
+		}
+		return nodes
+	}
+
+	return build("")
+}
+
+// Ancestors mengembalikan induk kategori dari akar sampai induk langsung.
+func (c *Category) Ancestors(db *gorm.DB) []Category {
+	var ancestors []Category
+	seen := map[string]bool{c.ID: true}
+	parentID := c.ParentID
+	for parentID != "" && !seen[parentID] {
+		var parent Category
+		if err := db.Where("id = ?", parentID).First(&parent).Error; err != nil {
+			break
+		}
+		seen[parent.ID] = true
+		ancestors = append([]Category{parent}, ancestors...)
+		parentID = parent.ParentID
+	}
+
+	return ancestors
+}
+
+// GetChildren mengembalikan subkategori langsung.
+func (c *Category) GetChildren(db *gorm.DB) []Category {
+	var children []Category
+	db.Debug().Where("parent_id = ?", c.ID).Order("position asc, name asc").Find(&children)
+
+	return children
+}
+
+// DescendantIDs mengembalikan ID kategori ini beserta semua turunannya.
+func (c *Category) DescendantIDs(db *gorm.DB) []string {
+	ids := []string{c.ID}
+	seen := map[string]bool{c.ID: true}
+	queue := []string{c.ID}
+	for len(queue) > 0 {
+		var children []Category
+		db.Select("id").Where("parent_id IN ?", queue).Find(&children)
+		queue = nil
+		for _, child := range children {
+			if seen[child.ID] {
+				continue
+			}
+			seen[child.ID] = true
+			ids = append(ids, child.ID)
+			queue = append(queue, child.ID)
+		}
+	}
+
+	return ids
+}
+
+// ValidateParent memastikan parentID bukan kategori ini atau turunannya.
+func (c *Category) ValidateParent(db *gorm.DB, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+	for _, id := range c.DescendantIDs(db) {
+		if id == parentID {
+			return ErrCategoryCycle
+		}
+	}
+
+	return nil
+}
+
+// Delete menghapus kategori; subkategori dipindah ke induknya dan relasi produk dilepas.
+func (c *Category) Delete(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Category{}).Where("parent_id = ?", c.ID).Update("parent_id", c.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM product_categories WHERE category_id = ?", c.ID).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&Category{}, "id = ?", c.ID).Error
+	})
+}
+
+// GetProductsByCategoryIDs mengembalikan produk publik yang ada di salah satu kategori.
+func (p *Product) GetProductsByCategoryIDs(db *gorm.DB, categoryIDs []string, perPage int, page int) (*[]Product, int64, error) {
+	var products []Product
+	var count int64
+
+	scope := func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("is_temporary = ?", false).
+			Scopes(PublishedProducts).
+			Where("id IN (SELECT product_id FROM product_categories WHERE category_id IN ?)", categoryIDs)
+	}
+
+	if err := db.Model(&Product{}).Scopes(scope).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := db.Debug().Scopes(scope).
+		Preload("ProductImages", OrderedProductImages).
+		Order("created_at desc").
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Find(&products).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &products, count, nil
+}
+
+// SetCategories mengganti seluruh kategori produk.
+func (p *Product) SetCategories(db *gorm.DB, categoryIDs []string) error {
+	var categories []Category
+	if len(categoryIDs) > 0 {
+		if err := db.Where("id IN ?", categoryIDs).Find(&categories).Error; err != nil {
+			return err
+		}
+	}
+
+	return db.Model(p).Association("Categories").Replace(categories)
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// CODRule berisi batasan yang harus dipenuhi agar order boleh dibayar di tempat (COD).
+// Slice kosong berarti tidak ada batasan untuk field tersebut.
+type CODRule struct {
+	Enabled          bool
+	MaxOrderValue    decimal.Decimal
+	Fee              decimal.Decimal
+	AllowedCouriers  []string
+	AllowedProvinces []string
+	AllowedCities    []string
+}
+
+// Validate memeriksa order dengan total orderTotal (belum termasuk biaya COD).
+// Batas MaxOrderValue dibandingkan dengan jumlah yang dibayar pelanggan, yaitu
+// orderTotal ditambah Fee.
+func (c *CODRule) Validate(orderTotal decimal.Decimal, courier string, provinceID string, cityID string) error {
+	if !c.Enabled {
+		return fmt.Errorf("COD tidak tersedia")
+	}
+
+	if c.MaxOrderValue.IsPositive() && orderTotal.Add(c.Fee).GreaterThan(c.MaxOrderValue) {
+		return fmt.Errorf("total order melebihi batas COD %s", c.MaxOrderValue.StringFixed(0))
+	}
+
+	if !containsFold(c.AllowedCouriers, courier) {
+		return fmt.Errorf("kurir %s tidak mendukung COD", courier)
+	}
+
+	if !containsFold(c.AllowedProvinces, provinceID) || !containsFold(c.AllowedCities, cityID) {
+		return fmt.Errorf("COD tidak tersedia untuk alamat tujuan")
+	}
+
+	return nil
+}
+
+func containsFold(list []string, value string) bool {
+	if len(list) == 0 {
+		return true
+	}
+
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), strings.TrimSpace(value)) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const BaseCurrencyCode = "IDR"
+
+// Currency menyimpan kurs tampilan. Rate adalah nilai 1 unit mata uang dalam IDR;
+// settlement tetap selalu dalam IDR.
+type Currency struct {
+	Code      string          `gorm:"size:3;not null;uniqueIndex;primary_key" json:"code"`
+	Name      string          `gorm:"size:100" json:"name"`
+	Symbol    string          `gorm:"size:10" json:"symbol"`
+	Rate      decimal.Decimal `gorm:"type:decimal(20,8)" json:"rate"`
+	Decimals  int             `gorm:"default:0" json:"decimals"`
+	IsActive  bool            `json:"is_active"`
+	Source    string          `gorm:"size:100" json:"source"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+func BaseCurrency() *Currency {
+	return &Currency{
+		Code:     BaseCurrencyCode,
+		Name:     "Rupiah",
+		Symbol:   "Rp",
+		Rate:     decimal.NewFromInt(1),
+		Decimals: 0,
+		IsActive: true,
+	}
+}
+
+func (c *Currency) FindByCode(db *gorm.DB, code string) (*Currency, error) {
+	var currency Currency
+
+	err := db.Debug().Where("code = ?", strings.ToUpper(code)).First(&currency).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &currency, nil
+}
+
+func (c *Currency) GetActiveCurrencies(db *gorm.DB) ([]Currency, error) {
+	var currencies []Currency
+
+	err := db.Debug().Where("is_active = ?", true).Order("code ASC").Find(&currencies).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return currencies, nil
+}
+
+// Convert mengubah nominal IDR ke mata uang ini.
+func (c *Currency) Convert(amount decimal.Decimal) decimal.Decimal {
+	if c == nil || c.Code == BaseCurrencyCode || !c.Rate.IsPositive() {
+		return amount
+	}
+
+	return amount.Div(c.Rate).Round(int32(c.Decimals))
+}
+
+// Format mengonversi nominal IDR lalu memformatnya dengan simbol mata uang ini.
+func (c *Currency) Format(price interface{}) string {
+	var amount decimal.Decimal
+	switch v := price.(type) {
+	case decimal.Decimal:
+		amount = v
+	case *decimal.Decimal:
+		if v != nil {
+			amount = *v
+		}
+	case int:
+		amount = decimal.NewFromInt(int64(v))
+	case int64:
+		amount = decimal.NewFromInt(v)
+	case float64:
+		amount = decimal.NewFromFloat(v)
+	}
+
+	if c == nil {
+		return fmt.Sprintf("Rp %s", amount.StringFixed(0))
+	}
+
+	return fmt.Sprintf("%s %s", c.Symbol, c.Convert(amount).StringFixed(int32(c.Decimals)))
+}
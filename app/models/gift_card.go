@@ -0,0 +1,234 @@
+package models
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrGiftCardNotRedeemable = errors.New("gift card tidak valid atau sudah tidak berlaku")
+
+type GiftCard struct {
+	ID             string          `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	Code           string          `gorm:"size:32;not null;uniqueIndex" json:"code"`
+	InitialAmount  decimal.Decimal `gorm:"type:decimal(16,2)" json:"initial_amount"`
+	Balance        decimal.Decimal `gorm:"type:decimal(16,2)" json:"balance"`
+	Status         string          `gorm:"size:20;index" json:"status"`
+	ExpiresAt      *time.Time      `gorm:"index" json:"expires_at"`
+	OrderID        string          `gorm:"size:36;index" json:"order_id"`
+	OrderItemID    string          `gorm:"size:36;index" json:"order_item_id"`
+	PurchaserID    string          `gorm:"size:36;index" json:"purchaser_id"`
+	RecipientEmail string          `gorm:"size:100" json:"recipient_email"`
+	IssuedBy       string          `gorm:"size:36" json:"issued_by"`
+	Note           string          `gorm:"size:255" json:"note"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+type GiftCardRedemption struct {
+	ID         string          `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	GiftCardID string          `gorm:"size:36;index" json:"gift_card_id"`
+	OrderID    string          `gorm:"size:36;index" json:"order_id"`
+	Amount     decimal.Decimal `gorm:"type:decimal(16,2)" json:"amount"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func (g *GiftCard) BeforeCreate(db *gorm.DB) error {
+	if g.ID == "" {
+		g.ID = uuid.New().String()
+	}
+
+	if g.Code == "" {
+		g.Code = generateGiftCardCode()
+	}
+
+	if g.Status == "" {
+		g.Status = consts.GiftCardStatusActive
+	}
+
+	return nil
+}
+
+func (r *GiftCardRedemption) BeforeCreate(db *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// generateGiftCardCode menghasilkan kode acak berbentuk XXXX-XXXX-XXXX-XXXX tanpa
+// karakter yang mudah tertukar (0/O, 1/I).
+func generateGiftCardCode() string {
+	const alphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	var sb strings.Builder
+	for i := 0; i < 16; i++ {
+		if i > 0 && i%4 == 0 {
+			sb.WriteByte('-')
+		}
+		n, _ := rand.Int(rand.Reader, big.NewInt(int64(len(alphabet))))
+		sb.WriteByte(alphabet[n.Int64()])
+	}
+
+	return sb.String()
+}
+
+func NormalizeGiftCardCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func (g *GiftCard) IsRedeemable(now time.Time) bool {
+	if g.Status != consts.GiftCardStatusActive || !g.Balance.IsPositive() {
+		return false
+	}
+
+	return g.ExpiresAt == nil || now.Before(*g.ExpiresAt)
+}
+
+func (g *GiftCard) FindByCode(db *gorm.DB, code string) (*GiftCard, error) {
+	var giftCard GiftCard
+
+	err := db.Debug().Where("code = ?", NormalizeGiftCardCode(code)).First(&giftCard).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &giftCard, nil
+}
+
+func (g *GiftCard) CreateGiftCard(db *gorm.DB, giftCard *GiftCard) (*GiftCard, error) {
+	giftCard.Balance = giftCard.InitialAmount
+
+	if err := db.Debug().Create(giftCard).Error; err != nil {
+		return nil, err
+	}
+
+	return giftCard, nil
+}
+
+// Redeem memotong saldo gift card (dengan row lock) sebesar maksimal amount dan
+// mengembalikan jumlah yang benar-benar terpakai.
+func (g *GiftCard) Redeem(db *gorm.DB, code string, amount decimal.Decimal, orderID string) (decimal.Decimal, error) {
+	used := decimal.Zero
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var giftCard GiftCard
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code = ?", NormalizeGiftCardCode(code)).
+			First(&giftCard).Error; err != nil {
+			return ErrGiftCardNotRedeemable
+		}
+
+		if !giftCard.IsRedeemable(time.Now()) {
+			return ErrGiftCardNotRedeemable
+		}
+
+		used = decimal.Min(giftCard.Balance, amount)
+		balance := giftCard.Balance.Sub(used)
+		updates := map[string]interface{}{"balance": balance}
+		if !balance.IsPositive() {
+			updates["status"] = consts.GiftCardStatusRedeemed
+		}
+
+		if err := tx.Model(&GiftCard{}).Where("id = ?", giftCard.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&GiftCardRedemption{GiftCardID: giftCard.ID, OrderID: orderID, Amount: used}).Error
+	})
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	return used, nil
+}
+
+// RestoreForOrder mengembalikan saldo gift card yang dipakai order yang
+// dibatalkan. Pengembalian dicatat sebagai redemption bernilai negatif sehingga
+// pemanggilan berikutnya tidak mengembalikan saldo dua kali.
+func (g *GiftCard) RestoreForOrder(db *gorm.DB, orderID string) error {
+	var used []struct {
+		GiftCardID string
+		Amount     decimal.Decimal
+	}
+	if err := db.Model(&GiftCardRedemption{}).
+		Select("gift_card_id, SUM(amount) AS amount").
+		Where("order_id = ?", orderID).
+		Group("gift_card_id").
+		Scan(&used).Error; err != nil {
+		return err
+	}
+
+	for _, row := range used {
+		if !row.Amount.IsPositive() {
+			continue
+		}
+
+		var giftCard GiftCard
+		if err := db.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", row.GiftCardID).
+			First(&giftCard).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{"balance": giftCard.Balance.Add(row.Amount)}
+		if giftCard.Status == consts.GiftCardStatusRedeemed {
+			updates["status"] = consts.GiftCardStatusActive
+		}
+		if err := db.Model(&GiftCard{}).Where("id = ?", giftCard.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if err := db.Create(&GiftCardRedemption{GiftCardID: giftCard.ID, OrderID: orderID, Amount: row.Amount.Neg()}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GiftCardLiability merangkum saldo gift card aktif yang belum kedaluwarsa.
+type GiftCardLiability struct {
+	Count            int64           `json:"count"`
+	OutstandingTotal decimal.Decimal `json:"outstanding_total"`
+	IssuedTotal      decimal.Decimal `json:"issued_total"`
+	ExpiredBalance   decimal.Decimal `json:"expired_balance"`
+}
+
+func (g *GiftCard) GetLiability(db *gorm.DB, now time.Time) (*GiftCardLiability, error) {
+	var liability GiftCardLiability
+	var outstanding, issued, expired string
+
+	active := db.Model(&GiftCard{}).
+		Where("status = ?", consts.GiftCardStatusActive).
+		Where("expires_at IS NULL OR expires_at > ?", now)
+	if err := active.Count(&liability.Count).Error; err != nil {
+		return nil, err
+	}
+	if err := active.Select("COALESCE(SUM(balance)::text, '0')").Row().Scan(&outstanding); err != nil {
+		return nil, err
+	}
+	if err := db.Model(&GiftCard{}).Select("COALESCE(SUM(initial_amount)::text, '0')").Row().Scan(&issued); err != nil {
+		return nil, err
+	}
+	if err := db.Model(&GiftCard{}).
+		Where("status = ? AND expires_at <= ?", consts.GiftCardStatusActive, now).
+		Select("COALESCE(SUM(balance)::text, '0')").Row().Scan(&expired); err != nil {
+		return nil, err
+	}
+
+	liability.OutstandingTotal, _ = decimal.NewFromString(outstanding)
+	liability.IssuedTotal, _ = decimal.NewFromString(issued)
+	liability.ExpiredBalance, _ = decimal.NewFromString(expired)
+
+	return &liability, nil
+}
@@ -0,0 +1,363 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInsufficientStock  = errors.New("stok tidak mencukupi")
+	ErrInvalidStockChange = errors.New("perubahan stok tidak valid")
+	ErrWarehouseRequired  = errors.New("stok produk ini dikelola per gudang, pilih gudang")
+)
+
+// DefaultLowStockThreshold dipakai untuk produk tanpa LowStockThreshold sendiri.
+const DefaultLowStockThreshold = 5
+
+// StockMovement adalah satu baris ledger stok. Qty adalah perubahan
+// Product.Stock (transfer antar gudang dicatat berpasangan dan saling
+// meniadakan) dan Balance adalah Product.Stock setelah perubahan.
+type StockMovement struct {
+	ID            string    `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	ProductID     string    `gorm:"size:36;index" json:"product_id"`
+	WarehouseID   string    `gorm:"size:36;index" json:"warehouse_id"`
+	Qty           int       `gorm:"not null" json:"qty"`
+	Balance       int       `gorm:"not null" json:"balance"`
+	Reason        string    `gorm:"size:30;index" json:"reason"`
+	ReferenceType string    `gorm:"size:30;index:idx_stock_movement_reference" json:"reference_type"`
+	ReferenceID   string    `gorm:"size:36;index:idx_stock_movement_reference" json:"reference_id"`
+	Note          string    `gorm:"size:255" json:"note"`
+	CreatedBy     string    `gorm:"size:36" json:"created_by"`
+	CreatedAt     time.Time `gorm:"index" json:"created_at"`
+}
+
+// StockChange adalah perubahan stok yang akan diterapkan dan dicatat.
+// WarehouseID wajib untuk produk yang stoknya dikelola per gudang.
+type StockChange struct {
+	ProductID     string
+	WarehouseID   string
+	Qty           int
+	Reason        string
+	ReferenceType string
+	ReferenceID   string
+	Note          string
+	Actor         string
+}
+
+// LowStockProduct adalah satu baris laporan stok menipis.
+type LowStockProduct struct {
+	ID             string     `json:"id"`
+	Sku            string     `json:"sku"`
+	Name           string     `json:"name"`
+	Stock          int        `json:"stock"`
+	Threshold      int        `json:"threshold"`
+	LastMovementAt *time.Time `json:"last_movement_at"`
+}
+
+func (m *StockMovement) BeforeCreate(db *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// AdjustStock menambah (Qty positif) atau mengurangi stok lalu mencatatnya di
+// ledger. Untuk produk per gudang stok gudang yang diubah dan Product.Stock
+// disinkronkan. Panggil di dalam transaksi bila perlu digabung dengan
+// perubahan lain.
+func AdjustStock(db *gorm.DB, change StockChange) (*StockMovement, error) {
+	if change.Qty == 0 || change.ProductID == "" || change.Reason == "" {
+		return nil, ErrInvalidStockChange
+	}
+
+	return adjustStock(db, change)
+}
+
+// adjustStock seperti AdjustStock tetapi menerima Qty 0 untuk gudang (membuat
+// baris stok gudang kosong); pergerakan hanya dicatat bila Product.Stock berubah.
+func adjustStock(db *gorm.DB, change StockChange) (*StockMovement, error) {
+	var movement *StockMovement
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var product Product
+		// Unscoped supaya order atas produk yang sudah dihapus tetap bisa di-restock
+		if err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "stock").
+			Where("id = ?", change.ProductID).
+			First(&product).Error; err != nil {
+			return err
+		}
+		before := product.Stock
+
+		if change.WarehouseID == "" {
+			if productHasWarehouseStock(tx, product.ID) {
+				return ErrWarehouseRequired
+			}
+			if change.Qty == 0 {
+				return ErrInvalidStockChange
+			}
+			if before+change.Qty < 0 {
+				return ErrInsufficientStock
+			}
+			if err := tx.Unscoped().Model(&Product{}).Where("id = ?", product.ID).Update("stock", before+change.Qty).Error; err != nil {
+				return err
+			}
+		} else if err := changeWarehouseStock(tx, change.WarehouseID, product.ID, change.Qty); err != nil {
+			return err
+		}
+
+		var after int
+		if err := tx.Unscoped().Model(&Product{}).Select("stock").Where("id = ?", product.ID).Scan(&after).Error; err != nil {
+			return err
+		}
+		if change.Qty == 0 && after == before {
+			return nil
+		}
+
+		movement = &StockMovement{
+			ProductID:     product.ID,
+			WarehouseID:   change.WarehouseID,
+			Qty:           after - before,
+			Balance:       after,
+			Reason:        change.Reason,
+			ReferenceType: change.ReferenceType,
+			ReferenceID:   change.ReferenceID,
+			Note:          change.Note,
+			CreatedBy:     change.Actor,
+		}
+
+		return tx.Create(movement).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// changeWarehouseStock menambah/mengurangi stok satu gudang lalu
+// menyinkronkan Product.Stock.
+func changeWarehouseStock(tx *gorm.DB, warehouseID string, productID string, qty int) error {
+	if qty < 0 {
+		result := tx.Model(&WarehouseStock{}).
+			Where("warehouse_id = ? AND product_id = ? AND qty >= ?", warehouseID, productID, -qty).
+			Updates(map[string]interface{}{"qty": gorm.Expr("qty + ?", qty), "updated_at": time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInsufficientWarehouseStock
+		}
+	} else {
+		stock := WarehouseStock{WarehouseID: warehouseID, ProductID: productID, Qty: qty}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "warehouse_id"}, {Name: "product_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"qty": gorm.Expr("warehouse_stocks.qty + ?", qty), "updated_at": time.Now()}),
+		}).Create(&stock).Error; err != nil {
+			return err
+		}
+	}
+
+	return SyncProductStock(tx, productID)
+}
+
+// SetProductStock menetapkan Product.Stock (produk tanpa stok per gudang) dan
+// mencatat selisihnya. Mengembalikan nil bila stok tidak berubah.
+func SetProductStock(db *gorm.DB, qty int, change StockChange) (*StockMovement, error) {
+	if qty < 0 {
+		return nil, ErrInvalidStockChange
+	}
+
+	var movement *StockMovement
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var product Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "stock").
+			Where("id = ?", change.ProductID).
+			First(&product).Error; err != nil {
+			return err
+		}
+		if qty == product.Stock {
+			return nil
+		}
+
+		change.WarehouseID = ""
+		change.Qty = qty - product.Stock
+		var err error
+		movement, err = AdjustStock(tx, change)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+// RecordInitialStock mencatat stok awal produk yang baru dibuat supaya jumlah
+// Qty di ledger sama dengan Product.Stock. Qty dan Balance diambil dari produk.
+func RecordInitialStock(db *gorm.DB, product *Product, change StockChange) error {
+	if product.Stock == 0 {
+		return nil
+	}
+
+	note := change.Note
+	if note == "" {
+		note = "stok awal"
+	}
+
+	return db.Create(&StockMovement{
+		ProductID:     product.ID,
+		Qty:           product.Stock,
+		Balance:       product.Stock,
+		Reason:        change.Reason,
+		ReferenceType: change.ReferenceType,
+		ReferenceID:   change.ReferenceID,
+		Note:          note,
+		CreatedBy:     change.Actor,
+	}).Error
+}
+
+// RecordOpeningBalances mencatat saldo awal untuk produk yang sudah punya stok
+// sebelum ledger ada, supaya jumlah Qty per produk sama dengan Product.Stock.
+func RecordOpeningBalances(db *gorm.DB) (int, error) {
+	var products []Product
+	if err := db.Unscoped().Select("id", "stock").Where("stock <> 0 AND is_temporary = ?", false).Find(&products).Error; err != nil {
+		return 0, err
+	}
+
+	for i := range products {
+		if err := RecordInitialStock(db, &products[i], StockChange{Reason: consts.StockReasonAdjustment, Note: "saldo awal"}); err != nil {
+			return i, err
+		}
+	}
+
+	return len(products), nil
+}
+
+// DeductOrderStock mengurangi stok untuk item order saat checkout (di dalam
+// transaksi checkout). Produk per gudang dikurangi dari warehouseID; produk
+// custom sementara dilewati.
+func DeductOrderStock(tx *gorm.DB, order *Order, warehouseID string, items map[string]int, actor string) error {
+	for productID, qty := range items {
+		var product Product
+		if err := tx.Unscoped().Select("id", "is_temporary").Where("id = ?", productID).First(&product).Error; err != nil {
+			return err
+		}
+		if product.IsTemporary {
+			continue
+		}
+
+		change := StockChange{
+			ProductID:     productID,
+			Qty:           -qty,
+			Reason:        consts.StockReasonSale,
+			ReferenceType: consts.StockReferenceOrder,
+			ReferenceID:   order.ID,
+			Note:          order.Code,
+			Actor:         actor,
+		}
+		if productHasWarehouseStock(tx, productID) {
+			if warehouseID == "" {
+				return ErrInsufficientWarehouseStock
+			}
+			change.WarehouseID = warehouseID
+		}
+		if _, err := AdjustStock(tx, change); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RestockOrder mengembalikan stok yang dikurangi saat checkout (pembatalan
+// atau retur) ke gudang asalnya. Order yang sudah pernah di-restock dilewati.
+func RestockOrder(tx *gorm.DB, order *Order, reason string, actor string) error {
+	var restocked int64
+	if err := tx.Model(&StockMovement{}).
+		Where("reference_type = ? AND reference_id = ? AND reason IN ?", consts.StockReferenceOrder, order.ID,
+			[]string{consts.StockReasonCancellation, consts.StockReasonReturn}).
+		Count(&restocked).Error; err != nil {
+		return err
+	}
+	if restocked > 0 {
+		return nil
+	}
+
+	var sold []struct {
+		ProductID   string
+		WarehouseID string
+		Qty         int
+	}
+	if err := tx.Model(&StockMovement{}).
+		Select("product_id, warehouse_id, SUM(qty) AS qty").
+		Where("reference_type = ? AND reference_id = ? AND reason = ?", consts.StockReferenceOrder, order.ID, consts.StockReasonSale).
+		Group("product_id, warehouse_id").
+		Scan(&sold).Error; err != nil {
+		return err
+	}
+
+	for _, row := range sold {
+		if row.Qty >= 0 {
+			continue
+		}
+		if _, err := AdjustStock(tx, StockChange{
+			ProductID:     row.ProductID,
+			WarehouseID:   row.WarehouseID,
+			Qty:           -row.Qty,
+			Reason:        reason,
+			ReferenceType: consts.StockReferenceOrder,
+			ReferenceID:   order.ID,
+			Note:          order.Code,
+			Actor:         actor,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetStockMovements mengembalikan ledger satu produk, terbaru dulu.
+func GetStockMovements(db *gorm.DB, productID string, perPage int, page int) ([]StockMovement, int64, error) {
+	var movements []StockMovement
+	var count int64
+
+	query := db.Model(&StockMovement{}).Where("product_id = ?", productID)
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Debug().
+		Order("created_at desc").
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Find(&movements).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return movements, count, nil
+}
+
+// GetLowStockProducts mengembalikan produk (kecuali arsip dan produk custom)
+// yang stoknya di bawah atau sama dengan ambang, stok terendah dulu.
+func GetLowStockProducts(db *gorm.DB, defaultThreshold int) ([]LowStockProduct, error) {
+	var products []LowStockProduct
+	err := db.Debug().Model(&Product{}).
+		Select(`products.id, products.sku, products.name, products.stock,
+			COALESCE(products.low_stock_threshold, ?) AS threshold,
+			(SELECT MAX(created_at) FROM stock_movements WHERE stock_movements.product_id = products.id) AS last_movement_at`, defaultThreshold).
+		Where("products.is_temporary = ? AND products.status <> ?", false, consts.ProductStatusArchived).
+		Where("products.stock <= COALESCE(products.low_stock_threshold, ?)", defaultThreshold).
+		Order("products.stock asc, products.name asc").
+		Scan(&products).Error
+
+	return products, err
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"database/sql"
+	"errors"
 	"strconv"
 	"strings"
 	"time"
@@ -9,9 +10,12 @@ import (
 	"github.com/codeuiprogramming/e-commerce/app/consts"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"gorm.io/datatypes"
 	"gorm.io/gorm"
 )
 
+var ErrOrderNotCancellable = errors.New("only pending or received orders can be cancelled")
+
 type Order struct {
 	ID                  string          `gorm:"size:36;not null;uniqueIndex;primaryKey"`
 	UserID              string          `gorm:"size:36;index"`
@@ -34,6 +38,14 @@ type Order struct {
 	Note                string          `gorm:"type:text"`
 	ShippingCourier     string          `gorm:"size:100"`
 	ShippingServiceName string          `gorm:"size:100"`
+	WarehouseID         string          `gorm:"size:36;index"`
+	PaymentMethod       string          `gorm:"size:50;default:'midtrans'"`
+	CODFee              decimal.Decimal `gorm:"type:decimal(16,2)"`
+	WalletAmount        decimal.Decimal `gorm:"type:decimal(16,2)"`
+	GiftCardCode        string          `gorm:"size:32"`
+	GiftCardAmount      decimal.Decimal `gorm:"type:decimal(16,2)"`
+	DisplayCurrency     string          `gorm:"size:3;default:'IDR'"`
+	DisplayRate         decimal.Decimal `gorm:"type:decimal(20,8)"`
 	ApprovedBy          sql.NullString  `gorm:"size:36"`
 	ApprovedAt          sql.NullTime
 	CancelledBy         sql.NullString  `gorm:"size:36"`
@@ -103,6 +115,75 @@ func (o *Order) IsPaid() bool {
 	return o.PaymentStatus == consts.OrderPaymentStatusPaid
 }
 
+// DisplayCurrencyModel mengembalikan mata uang tampilan yang di-snapshot saat checkout.
+func (o *Order) DisplayCurrencyModel() *Currency {
+	if o.DisplayCurrency == "" || o.DisplayCurrency == BaseCurrencyCode || !o.DisplayRate.IsPositive() {
+		return BaseCurrency()
+	}
+
+	return &Currency{Code: o.DisplayCurrency, Symbol: o.DisplayCurrency, Rate: o.DisplayRate, Decimals: 2}
+}
+
+// AmountDue adalah sisa tagihan setelah dikurangi gift card dan saldo wallet.
+func (o *Order) AmountDue() decimal.Decimal {
+	return o.GrandTotal.Sub(o.GiftCardAmount).Sub(o.WalletAmount)
+}
+
+func (o *Order) IsCOD() bool {
+	return o.PaymentMethod == consts.PaymentMethodCOD
+}
+
+// ConfirmCODPayment mencatat uang tunai yang ditagih kurir sebagai Payment dan
+// menandai order sebagai lunas dan terkirim dalam satu transaksi.
+func (o *Order) ConfirmCODPayment(db *gorm.DB, amount decimal.Decimal, reference string, payload []byte) (*Payment, error) {
+	if !o.IsCOD() {
+		return nil, errors.New("order is not cash on delivery")
+	}
+
+	if o.IsPaid() {
+		return nil, errors.New("order already paid")
+	}
+
+	if len(payload) == 0 {
+		payload = []byte("{}")
+	}
+
+	payment := &Payment{
+		OrderID:           o.ID,
+		Amount:            amount,
+		TransactionID:     reference,
+		TransactionStatus: consts.PaymentStatusSettlement,
+		Payload:           datatypes.JSON(payload),
+		PaymentType:       consts.PaymentTypeCOD,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// update bersyarat supaya dua konfirmasi paralel tidak mencatat pembayaran dua kali
+		result := tx.Model(&Order{}).
+			Where("id = ? AND payment_status <> ?", o.ID, consts.OrderPaymentStatusPaid).
+			Updates(map[string]interface{}{
+				"payment_status": consts.OrderPaymentStatusPaid,
+				"status":         consts.OrderStatusDelivered,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != 1 {
+			return errors.New("order already paid")
+		}
+
+		return tx.Create(payment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	o.PaymentStatus = consts.OrderPaymentStatusPaid
+	o.Status = consts.OrderStatusDelivered
+
+	return payment, nil
+}
+
 func generateOrderNumber(db *gorm.DB) string {
 	now := time.Now()
 	month := now.Month()
@@ -163,4 +244,37 @@ func (o *Order) MarkAsPaid(db *gorm.DB) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// Cancel membatalkan order yang belum terkirim, mengembalikan stoknya serta
+// saldo wallet dan gift card yang dipakai.
+func (o *Order) Cancel(db *gorm.DB, actor string, note string) error {
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Order{}).
+			Where("id = ? AND status IN ?", o.ID, []int{consts.OrderStatusPending, consts.OrderStatusReceived}).
+			Updates(map[string]interface{}{
+				"status":            consts.OrderStatusCancelled,
+				"cancelled_by":      sql.NullString{String: actor, Valid: actor != ""},
+				"cancell_at":        sql.NullTime{Time: now, Valid: true},
+				"cancellation_note": sql.NullString{String: note, Valid: note != ""},
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrOrderNotCancellable
+		}
+		o.Status = consts.OrderStatusCancelled
+
+		if err := RestockOrder(tx, o, consts.StockReasonCancellation, actor); err != nil {
+			return err
+		}
+
+		if err := (&Wallet{}).RefundOrder(tx, o, actor); err != nil {
+			return err
+		}
+
+		return (&GiftCard{}).RestoreForOrder(tx, o.ID)
+	})
+}
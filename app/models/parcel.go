@@ -0,0 +1,198 @@
+package models
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// DefaultVolumetricDivisor adalah pembagi volume (cm3 per kg) yang umum dipakai
+// kurir domestik bila kurir tidak punya pembagi sendiri.
+const DefaultVolumetricDivisor = 6000
+
+// PackagingBox adalah kardus yang tersedia di gudang. Ukuran dalam cm, berat
+// dalam gram. Box terkecil yang muat dipakai untuk menghitung berat volume.
+type PackagingBox struct {
+	ID         string          `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	Code       string          `gorm:"size:50;not null;uniqueIndex" json:"code"`
+	Name       string          `gorm:"size:100" json:"name"`
+	Length     decimal.Decimal `gorm:"type:decimal(10,2)" json:"length"`
+	Width      decimal.Decimal `gorm:"type:decimal(10,2)" json:"width"`
+	Height     decimal.Decimal `gorm:"type:decimal(10,2)" json:"height"`
+	TareWeight int             `json:"tare_weight"`
+	// MaxWeight 0 berarti tanpa batas berat isi
+	MaxWeight int       `json:"max_weight"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (b *PackagingBox) BeforeCreate(db *gorm.DB) error {
+	if b.ID == "" {
+		b.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func (b *PackagingBox) GetActiveBoxes(db *gorm.DB) ([]PackagingBox, error) {
+	var boxes []PackagingBox
+	err := db.Debug().Where("is_active = ?", true).Find(&boxes).Error
+
+	return boxes, err
+}
+
+func (b *PackagingBox) dimensions() [3]float64 {
+	return sortedDimensions(b.Length, b.Width, b.Height)
+}
+
+func (b *PackagingBox) Volume() float64 {
+	d := b.dimensions()
+	return d[0] * d[1] * d[2]
+}
+
+// ParcelItem adalah satu baris keranjang/order untuk perhitungan paket.
+type ParcelItem struct {
+	Qty    int
+	Weight decimal.Decimal
+	Length decimal.Decimal
+	Width  decimal.Decimal
+	Height decimal.Decimal
+}
+
+// Parcel adalah rincian berat satu kiriman. Semua berat dalam gram.
+type Parcel struct {
+	ActualWeight int     `json:"actual_weight"`
+	TareWeight   int     `json:"tare_weight"`
+	GrossWeight  int     `json:"gross_weight"`
+	ItemVolume   float64 `json:"item_volume"`
+	BoxCode      string  `json:"box_code,omitempty"`
+	BoxName      string  `json:"box_name,omitempty"`
+	Length       float64 `json:"length"`
+	Width        float64 `json:"width"`
+	Height       float64 `json:"height"`
+	// Volume adalah volume yang ditagih kurir (kardus jika ada, jika tidak total volume item)
+	Volume float64 `json:"volume"`
+}
+
+// ParcelQuote adalah berat yang ditagih untuk satu kurir.
+type ParcelQuote struct {
+	Courier          string `json:"courier"`
+	Divisor          int    `json:"divisor"`
+	VolumetricWeight int    `json:"volumetric_weight"`
+	ChargeableWeight int    `json:"chargeable_weight"`
+}
+
+// BuildParcel menghitung berat aktual (dibulatkan ke atas per kiriman, bukan per
+// unit), memilih kardus terkecil yang muat dan menambahkan berat kardusnya.
+func BuildParcel(items []ParcelItem, boxes []PackagingBox) *Parcel {
+	parcel := &Parcel{}
+
+	actual := decimal.Zero
+	var longest [3]float64
+	for _, item := range items {
+		if item.Qty <= 0 {
+			continue
+		}
+		qty := decimal.NewFromInt(int64(item.Qty))
+		actual = actual.Add(item.Weight.Mul(qty))
+
+		dims := sortedDimensions(item.Length, item.Width, item.Height)
+		parcel.ItemVolume += dims[0] * dims[1] * dims[2] * float64(item.Qty)
+		for i := range dims {
+			if dims[i] > longest[i] {
+				longest[i] = dims[i]
+			}
+		}
+	}
+	parcel.ActualWeight = int(math.Ceil(actual.InexactFloat64()))
+
+	if box := pickBox(boxes, parcel.ItemVolume, longest, parcel.ActualWeight); box != nil {
+		dims := box.dimensions()
+		parcel.BoxCode = box.Code
+		parcel.BoxName = box.Name
+		parcel.TareWeight = box.TareWeight
+		parcel.Length, parcel.Width, parcel.Height = dims[0], dims[1], dims[2]
+		parcel.Volume = box.Volume()
+	} else {
+		parcel.Length, parcel.Width, parcel.Height = longest[0], longest[1], longest[2]
+		parcel.Volume = parcel.ItemVolume
+	}
+	parcel.GrossWeight = parcel.ActualWeight + parcel.TareWeight
+
+	return parcel
+}
+
+// pickBox memilih kardus aktif dengan volume terkecil yang muat seluruh item dan
+// sisi terpanjangnya. Nil bila tidak ada item berdimensi atau tidak ada yang muat.
+func pickBox(boxes []PackagingBox, volume float64, longest [3]float64, weight int) *PackagingBox {
+	if volume <= 0 {
+		return nil
+	}
+
+	sorted := make([]PackagingBox, len(boxes))
+	copy(sorted, boxes)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Volume() < sorted[j].Volume()
+	})
+
+	for i := range sorted {
+		box := &sorted[i]
+		if box.Volume() < volume {
+			continue
+		}
+		if box.MaxWeight > 0 && weight > box.MaxWeight {
+			continue
+		}
+		dims := box.dimensions()
+		if dims[0] < longest[0] || dims[1] < longest[1] || dims[2] < longest[2] {
+			continue
+		}
+		return box
+	}
+
+	return nil
+}
+
+// VolumetricWeight mengembalikan berat volume dalam gram untuk pembagi tertentu.
+func (p *Parcel) VolumetricWeight(divisor int) int {
+	if divisor <= 0 {
+		divisor = DefaultVolumetricDivisor
+	}
+
+	return int(math.Ceil(p.Volume * 1000 / float64(divisor)))
+}
+
+// ChargeableWeight adalah nilai terbesar antara berat kotor dan berat volume.
+func (p *Parcel) ChargeableWeight(divisor int) int {
+	if volumetric := p.VolumetricWeight(divisor); volumetric > p.GrossWeight {
+		return volumetric
+	}
+
+	return p.GrossWeight
+}
+
+func (p *Parcel) Quote(courier string, divisor int) ParcelQuote {
+	if divisor <= 0 {
+		divisor = DefaultVolumetricDivisor
+	}
+
+	return ParcelQuote{
+		Courier:          strings.ToLower(courier),
+		Divisor:          divisor,
+		VolumetricWeight: p.VolumetricWeight(divisor),
+		ChargeableWeight: p.ChargeableWeight(divisor),
+	}
+}
+
+func sortedDimensions(length decimal.Decimal, width decimal.Decimal, height decimal.Decimal) [3]float64 {
+	dims := []float64{length.InexactFloat64(), width.InexactFloat64(), height.InexactFloat64()}
+	sort.Sort(sort.Reverse(sort.Float64Slice(dims)))
+
+	return [3]float64{dims[0], dims[1], dims[2]}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"strconv"
 	"strings"
 	"time"
@@ -58,6 +59,16 @@ type MidtransNotification struct {
 	PermataVaNumber        string          `json:"permata_va_number"`
 }
 
+// PaymentFilter berisi filter opsional untuk ledger pembayaran di admin.
+type PaymentFilter struct {
+	Start             time.Time
+	End               time.Time
+	TransactionStatus string
+	PaymentType       string
+	OrderCode         string
+	TransactionID     string
+}
+
 type VaNumber struct {
 	Bank     string `json:"bank"`
 	VaNumber string `json:"va_number"`
@@ -137,3 +148,82 @@ func (p *Payment) UpdatePaymentStatus(db *gorm.DB, notification MidtransNotifica
 
     return nil
 }
+
+
+func (p *Payment) FindByID(db *gorm.DB, id string) (*Payment, error) {
+	var payment Payment
+
+	err := db.Debug().Preload("Order").Where("id = ?", id).First(&payment).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &payment, nil
+}
+
+func (p *Payment) GetPayments(db *gorm.DB, filter PaymentFilter, perPage int, page int) ([]Payment, int64, error) {
+	var payments []Payment
+	var count int64
+
+	query := db.Model(&Payment{}).Joins("LEFT JOIN orders ON orders.id = payments.order_id")
+	if !filter.Start.IsZero() {
+		query = query.Where("payments.created_at >= ?", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		query = query.Where("payments.created_at < ?", filter.End)
+	}
+	if filter.TransactionStatus != "" {
+		query = query.Where("payments.transaction_status = ?", filter.TransactionStatus)
+	}
+	if filter.PaymentType != "" {
+		query = query.Where("payments.payment_type = ?", filter.PaymentType)
+	}
+	if filter.OrderCode != "" {
+		query = query.Where("orders.code = ? OR orders.id = ?", filter.OrderCode, filter.OrderCode)
+	}
+	if filter.TransactionID != "" {
+		query = query.Where("payments.transaction_id = ?", filter.TransactionID)
+	}
+
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * perPage
+	err := query.
+		Preload("Order").
+		Order("payments.created_at DESC").
+		Limit(perPage).
+		Offset(offset).
+		Find(&payments).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return payments, count, nil
+}
+
+func (p *Payment) GetPaymentsByOrderID(db *gorm.DB, orderID string) ([]Payment, error) {
+	var payments []Payment
+
+	err := db.Debug().Where("order_id = ?", orderID).Order("created_at ASC").Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// Notification mengembalikan payload Midtrans yang tersimpan dalam bentuk terstruktur.
+func (p *Payment) Notification() (*MidtransNotification, error) {
+	var notification MidtransNotification
+	if len(p.Payload) == 0 {
+		return &notification, nil
+	}
+
+	if err := json.Unmarshal(p.Payload, &notification); err != nil {
+		return nil, err
+	}
+
+	return &notification, nil
+}
@@ -1,9 +1,9 @@
 package models
 
 import (
-	"log"
 	"time"
 
+	"github.com/codeuiprogramming/e-commerce/app/consts"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
@@ -18,68 +18,103 @@ type Product struct {
 	Categories       []Category 	 `gorm:"many2many:product_categories;"`
 	Sku              string          `gorm:"size:100;index"`
 	Name             string          `gorm:"size:255"`
-	Slug             string          `gorm:"size:255"`
+	Slug             string          `gorm:"size:255;uniqueIndex"`
 	Price            decimal.Decimal `gorm:"type:decimal(16,2);"`
+	// Stock adalah saldo berjalan ledger StockMovement; ubah lewat AdjustStock/SetProductStock
 	Stock            int
+	// LowStockThreshold nil berarti memakai ambang default laporan stok menipis
+	LowStockThreshold *int
 	Weight           decimal.Decimal `gorm:"type:decimal(10,2);"`
+	// dimensi kemasan produk dalam cm untuk berat volume
+	Length           decimal.Decimal `gorm:"type:decimal(10,2);"`
+	Width            decimal.Decimal `gorm:"type:decimal(10,2);"`
+	Height           decimal.Decimal `gorm:"type:decimal(10,2);"`
 	ShortDescription string          `gorm:"type:text"`
 	Description      string          `gorm:"type:text"`
-	Status           int             `gorm:"default:0"`
+	// Status draft/published/archived (consts.ProductStatus*), hanya published yang publik
+	Status           int             `gorm:"default:0;index"`
+	// jadwal publish/unpublish otomatis, diproses ProcessProductSchedules
+	PublishAt        *time.Time      `gorm:"index"`
+	UnpublishAt      *time.Time      `gorm:"index"`
+	PublishedAt      *time.Time
+	Type             string          `gorm:"size:20;default:'standard'"`
 	// IsTemporary menandai produk yang dibuat sementara untuk custom items
 	IsTemporary      bool            `gorm:"default:false"`
+	// Position mengatur urutan unggulan: negatif disematkan di atas, positif di bawah
+	Position         int             `gorm:"default:0;index"`
+	// agregat ulasan yang disetujui, dihitung ulang oleh RefreshProductRating
+	RatingAverage    decimal.Decimal `gorm:"type:decimal(3,2);default:0"`
+	RatingCount      int             `gorm:"default:0"`
 	CreatedAt        time.Time
 	UpdateAt         time.Time
 	DeleteAt         gorm.DeletedAt
  	Images      	 []ProductImage `gorm:"foreignKey:ProductID"`
 }
 
+// GetProducts mengembalikan produk publik dengan urutan unggulan (Position).
 func (p *Product) GetProducts(db *gorm.DB, perPage int, page int) (*[]Product, int64, error) {
-	var err error
-	var products []Product
-	var count int64
-
-	// only count non-temporary products for public listing
-	err = db.Model(&Product{}).Where("is_temporary = ?", false).Count(&count).Error
-	if err != nil {
-		return nil, 0, err
-	}
-
-	offset := (page - 1) * perPage
-
-	err = db.
-		Where("is_temporary = ?", false).
-		Preload("ProductImages").
-		Order(`
-		CASE WHEN name = 'Totebag Barong' THEN 9999 ELSE 1 END,
-		created_at DESC
-	`).
-		Limit(perPage).
-		Offset(offset).
-		Find(&products).Error
+	return p.FilterProducts(db, ProductFilter{}, perPage, page)
+}
 
-		for _, prod := range products {
-			log.Println("Loaded product:", prod.Name, "Price:", prod.Price.StringFixed(2))
-		}
+// PositionLegacyProducts memindahkan produk yang dulu ditaruh paling bawah oleh
+// aturan urutan lama (Totebag Barong) ke Position positif, supaya urutannya tetap
+// sama setelah migrasi.
+func PositionLegacyProducts(db *gorm.DB) (int64, error) {
+	result := db.Debug().Model(&Product{}).
+		Where("name = ? AND position = ?", "Totebag Barong", 0).
+		Update("position", 9999)
 
-		if err != nil {
-			return nil, 0, err
-		}
+	return result.RowsAffected, result.Error
+}
 
-		return &products, count, nil
-	}
+func (p *Product) IsGiftCard() bool {
+	return p.Type == consts.ProductTypeGiftCard
+}
 
 func (p *Product) BeforeCreate(tx *gorm.DB) error {
     if p.ID == "" {
         p.ID = uuid.New().String()
     }
+    // slug dibuat dari nama bila kosong, atau dirapikan bila diisi manual
+    source := p.Slug
+    if source == "" {
+        source = p.Name
+    }
+    newSlug, err := UniqueSlug(tx.Session(&gorm.Session{NewDB: true}), &Product{}, source, p.ID)
+    if err != nil {
+        return err
+    }
+    p.Slug = newSlug
     return nil
 }
 
+// CreateProduct menyimpan produk baru (kolom omit dilewati). Bila insert
+// bersamaan mengambil slug yang sama, BeforeCreate dijalankan ulang dan
+// memilih slug berikutnya.
+func CreateProduct(db *gorm.DB, p *Product, omit ...string) error {
+	return retrySlugConflict(db, func(tx *gorm.DB) error {
+		if len(omit) > 0 {
+			tx = tx.Omit(omit...)
+		}
+		return tx.Create(p).Error
+	})
+}
+
+// FindBySlug mengembalikan produk published dengan slug ini.
 func (p *Product) FindBySlug(db *gorm.DB, slug string) (*Product, error) {
+	return p.findBySlug(db.Scopes(PublishedProducts), slug)
+}
+
+// FindBySlugForPreview mengembalikan produk apa pun statusnya, untuk preview admin.
+func (p *Product) FindBySlugForPreview(db *gorm.DB, slug string) (*Product, error) {
+	return p.findBySlug(db, slug)
+}
+
+func (p *Product) findBySlug(db *gorm.DB, slug string) (*Product, error) {
 	var err error
 	var product Product
 
-	err = db.Debug().Preload("ProductImages").Model(&Product{}).Where("slug = ?", slug).First(&product).Error
+	err = db.Debug().Preload("ProductImages", OrderedProductImages).Preload("Categories").Model(&Product{}).Where("slug = ?", slug).First(&product).Error
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	ProductSortFeatured    = "featured"
+	ProductSortNewest      = "newest"
+	ProductSortPriceAsc    = "price_asc"
+	ProductSortPriceDesc   = "price_desc"
+	ProductSortBestSelling = "best_selling"
+)
+
+// ProductSorts adalah pilihan urutan yang valid, sesuai urutan tampil.
+var ProductSorts = []string{
+	ProductSortFeatured,
+	ProductSortNewest,
+	ProductSortPriceAsc,
+	ProductSortPriceDesc,
+	ProductSortBestSelling,
+}
+
+// DefaultPriceBuckets adalah batas rentang harga (mata uang dasar) untuk facet.
+var DefaultPriceBuckets = []decimal.Decimal{
+	decimal.NewFromInt(50000),
+	decimal.NewFromInt(100000),
+	decimal.NewFromInt(250000),
+	decimal.NewFromInt(500000),
+}
+
+// ProductFilter adalah filter listing produk publik. Harga dalam mata uang dasar.
+type ProductFilter struct {
+	CategoryIDs []string
+	MinPrice    *decimal.Decimal
+	MaxPrice    *decimal.Decimal
+	InStock     bool
+	Sort        string
+}
+
+// CategoryFacet adalah jumlah produk per kategori untuk filter yang aktif.
+type CategoryFacet struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Slug     string `json:"slug"`
+	Position int    `json:"-"`
+	Count    int64  `json:"count"`
+}
+
+// PriceBucket adalah jumlah produk pada rentang harga [Min, Max). Max nil
+// berarti tanpa batas atas.
+type PriceBucket struct {
+	Min   decimal.Decimal  `json:"min"`
+	Max   *decimal.Decimal `json:"max"`
+	Count int64            `json:"count"`
+}
+
+type ProductFacets struct {
+	Categories   []CategoryFacet `json:"categories"`
+	PriceBuckets []PriceBucket   `json:"price_buckets"`
+}
+
+// NormalizeProductSort mengembalikan sort yang valid, default featured.
+func NormalizeProductSort(sort string) string {
+	sort = strings.ToLower(strings.TrimSpace(sort))
+	for _, valid := range ProductSorts {
+		if sort == valid {
+			return sort
+		}
+	}
+
+	return ProductSortFeatured
+}
+
+// scope menerapkan filter ke query products. Facet memakai scope tanpa filter
+// dimensinya sendiri supaya pilihan lain di dimensi itu tetap terlihat.
+func (f ProductFilter) scope(withCategory bool, withPrice bool) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("products.is_temporary = ?", false).Scopes(PublishedProducts)
+		if withCategory && len(f.CategoryIDs) > 0 {
+			tx = tx.Where("products.id IN (SELECT product_id FROM product_categories WHERE category_id IN ?)", f.CategoryIDs)
+		}
+		if withPrice && f.MinPrice != nil {
+			tx = tx.Where("products.price >= ?", *f.MinPrice)
+		}
+		if withPrice && f.MaxPrice != nil {
+			tx = tx.Where("products.price <= ?", *f.MaxPrice)
+		}
+		if f.InStock {
+			tx = tx.Where("products.stock > 0")
+		}
+
+		return tx
+	}
+}
+
+// FilterProducts mengembalikan produk publik sesuai filter dan urutan.
+func (p *Product) FilterProducts(db *gorm.DB, filter ProductFilter, perPage int, page int) (*[]Product, int64, error) {
+	var products []Product
+	var count int64
+
+	if err := db.Model(&Product{}).Scopes(filter.scope(true, true)).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	query := db.Debug().Model(&Product{}).Scopes(filter.scope(true, true)).Preload("ProductImages", OrderedProductImages)
+	switch NormalizeProductSort(filter.Sort) {
+	case ProductSortNewest:
+		query = query.Order("products.created_at desc")
+	case ProductSortPriceAsc:
+		query = query.Order("products.price asc, products.created_at desc")
+	case ProductSortPriceDesc:
+		query = query.Order("products.price desc, products.created_at desc")
+	case ProductSortBestSelling:
+		// jumlah terjual dari order yang tidak dibatalkan
+		query = query.Select("products.*").
+			Joins(`LEFT JOIN (SELECT order_items.product_id, SUM(order_items.qty) AS sold
+				FROM order_items JOIN orders ON orders.id = order_items.order_id
+				WHERE orders.status <> ? AND orders.delete_at IS NULL
+				GROUP BY order_items.product_id) sales ON sales.product_id = products.id`, consts.OrderStatusCancelled).
+			Order("COALESCE(sales.sold, 0) desc, products.created_at desc")
+	default:
+		query = query.Order("products.position asc, products.created_at desc")
+	}
+
+	err := query.Limit(perPage).Offset((page - 1) * perPage).Find(&products).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &products, count, nil
+}
+
+// GetProductFacets menghitung jumlah produk per kategori dan per rentang harga.
+func (p *Product) GetProductFacets(db *gorm.DB, filter ProductFilter, buckets []decimal.Decimal) (*ProductFacets, error) {
+	facets := &ProductFacets{Categories: []CategoryFacet{}, PriceBuckets: []PriceBucket{}}
+
+	err := db.Debug().Table("categories").
+		Select("categories.id, categories.name, categories.slug, categories.position, COUNT(DISTINCT products.id) AS count").
+		Joins("JOIN product_categories ON product_categories.category_id = categories.id").
+		Joins("JOIN products ON products.id = product_categories.product_id AND products.delete_at IS NULL").
+		Scopes(filter.scope(false, true)).
+		Group("categories.id, categories.name, categories.slug, categories.position").
+		Order("categories.position asc, categories.name asc").
+		Scan(&facets.Categories).Error
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := make([]decimal.Decimal, len(buckets))
+	copy(bounds, buckets)
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i].LessThan(bounds[j]) })
+
+	// CASE WHEN price < b0 THEN 0 WHEN price < b1 THEN 1 ... ELSE n END
+	var caseExpr strings.Builder
+	args := make([]interface{}, 0, len(bounds))
+	caseExpr.WriteString("CASE")
+	for i, bound := range bounds {
+		caseExpr.WriteString(fmt.Sprintf(" WHEN products.price < ? THEN %d", i))
+		args = append(args, bound)
+	}
+	caseExpr.WriteString(fmt.Sprintf(" ELSE %d END", len(bounds)))
+
+	var rows []struct {
+		Bucket int
+		Count  int64
+	}
+	err = db.Debug().Model(&Product{}).
+		Select(caseExpr.String()+" AS bucket, COUNT(*) AS count", args...).
+		Scopes(filter.scope(true, false)).
+		Group("bucket").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[int]int64{}
+	for _, row := range rows {
+		counts[row.Bucket] = row.Count
+	}
+
+	min := decimal.Zero
+	for i := 0; i <= len(bounds); i++ {
+		bucket := PriceBucket{Min: min, Count: counts[i]}
+		if i < len(bounds) {
+			max := bounds[i]
+			bucket.Max = &max
+			min = max
+		}
+		facets.PriceBuckets = append(facets.PriceBuckets, bucket)
+	}
+
+	return facets, nil
+}
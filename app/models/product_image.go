@@ -1,6 +1,16 @@
 package models
 
-import "time"
+import (
+	"errors"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidImageOrder = errors.New("order must list every image of the product exactly once")
 
 type ProductImage struct {
 	ID         string `gorm:"size:36;not null;uniqueIndex;primary_key"`
@@ -11,6 +21,285 @@ type ProductImage struct {
 	Large      string `gorm:"type:text"`
 	Medium     string `gorm:"type:text"`
 	Small      string `gorm:"type:text"`
-	CreatedAt  time.Time
-	UpdateAt   time.Time
-}
\ No newline at end of file
+	// varian WebP, kosong jika server tidak punya encoder WebP
+	WebpExtraLarge string `gorm:"type:text"`
+	WebpLarge      string `gorm:"type:text"`
+	WebpMedium     string `gorm:"type:text"`
+	WebpSmall      string `gorm:"type:text"`
+	// Hash adalah sha256 isi file asli, dipakai sebagai nama file dan untuk dedupe
+	Hash      string `gorm:"size:64;index"`
+	MimeType  string `gorm:"size:50"`
+	Size      int64
+	Width     int
+	Height    int
+	AltText   string `gorm:"size:255"`
+	Position  int    `gorm:"default:0"`
+	IsPrimary bool   `gorm:"default:false"`
+	// Status pending/processing/ready/failed (consts.ProductImageStatus*);
+	// gambar lama tanpa varian dianggap ready
+	Status string `gorm:"size:20;index;default:'ready'"`
+	Error  string `gorm:"type:text"`
+	// Warning diisi bila gambar ready tapi tidak lengkap, misalnya varian WebP
+	// dilewati karena cwebp tidak tersedia
+	Warning     string `gorm:"type:text"`
+	ProcessedAt *time.Time
+	CreatedAt   time.Time
+	UpdateAt    time.Time
+}
+
+func (i *ProductImage) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// OrderedProductImages mengurutkan gambar produk: gambar utama dulu, lalu
+// berdasarkan Position. Dipakai sebagai kondisi Preload("ProductImages").
+func OrderedProductImages(tx *gorm.DB) *gorm.DB {
+	return tx.Order("product_images.is_primary desc, product_images.position asc, product_images.created_at asc")
+}
+
+func GetProductImages(db *gorm.DB, productID string) ([]ProductImage, error) {
+	var images []ProductImage
+	err := db.Debug().Scopes(OrderedProductImages).Where("product_id = ?", productID).Find(&images).Error
+
+	return images, err
+}
+
+func FindProductImage(db *gorm.DB, productID string, imageID string) (*ProductImage, error) {
+	var image ProductImage
+	err := db.Debug().Where("product_id = ? AND id = ?", productID, imageID).First(&image).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// FindProductImageByHash mencari gambar produk dengan isi file yang sama.
+func FindProductImageByHash(db *gorm.DB, productID string, hash string) (*ProductImage, error) {
+	var image ProductImage
+	err := db.Debug().Where("product_id = ? AND hash = ?", productID, hash).First(&image).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// CreateProductImage menyimpan gambar di urutan terakhir. Gambar pertama
+// sebuah produk otomatis menjadi gambar utama.
+func CreateProductImage(db *gorm.DB, image *ProductImage) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// kunci produk supaya dua upload bersamaan tidak mendapat posisi yang sama
+		if err := tx.Unscoped().Select("id").Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", image.ProductID).First(&Product{}).Error; err != nil {
+			return err
+		}
+
+		var last struct {
+			Count    int64
+			Position int
+		}
+		tx.Model(&ProductImage{}).
+			Select("COUNT(*) AS count, COALESCE(MAX(position), -1) AS position").
+			Where("product_id = ?", image.ProductID).
+			Scan(&last)
+		image.Position = last.Position + 1
+		if last.Count == 0 {
+			image.IsPrimary = true
+		} else if image.IsPrimary {
+			if err := tx.Model(&ProductImage{}).Where("product_id = ?", image.ProductID).Update("is_primary", false).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Omit("Product").Create(image).Error
+	})
+}
+
+// SetPrimary menjadikan gambar ini satu-satunya gambar utama produknya.
+func (i *ProductImage) SetPrimary(db *gorm.DB) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&ProductImage{}).Where("product_id = ? AND id <> ?", i.ProductID, i.ID).Update("is_primary", false).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&ProductImage{}).Where("id = ?", i.ID).Update("is_primary", true).Error
+	})
+	if err != nil {
+		return err
+	}
+	i.IsPrimary = true
+
+	return nil
+}
+
+// ReorderProductImages menyimpan urutan gambar sesuai ids, yang harus berisi
+// semua gambar produk tepat satu kali.
+func ReorderProductImages(db *gorm.DB, productID string, ids []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing []string
+		if err := tx.Model(&ProductImage{}).Where("product_id = ?", productID).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) != len(ids) {
+			return ErrInvalidImageOrder
+		}
+		known := map[string]bool{}
+		for _, id := range existing {
+			known[id] = true
+		}
+		for _, id := range ids {
+			if !known[id] {
+				return ErrInvalidImageOrder
+			}
+			delete(known, id)
+		}
+
+		for position, id := range ids {
+			if err := tx.Model(&ProductImage{}).Where("id = ?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Delete menghapus gambar dan memindahkan status gambar utama ke gambar
+// berikutnya. Hasil true berarti tidak ada gambar lain yang memakai file yang
+// sama, sehingga file di disk boleh dihapus.
+func (i *ProductImage) Delete(db *gorm.DB) (bool, error) {
+	orphaned := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", i.ID).Delete(&ProductImage{}).Error; err != nil {
+			return err
+		}
+
+		if i.IsPrimary {
+			var next ProductImage
+			err := tx.Scopes(OrderedProductImages).Where("product_id = ?", i.ProductID).First(&next).Error
+			if err == nil {
+				if err := tx.Model(&ProductImage{}).Where("id = ?", next.ID).Update("is_primary", true).Error; err != nil {
+					return err
+				}
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		var count int64
+		if err := tx.Model(&ProductImage{}).Where("path = ?", i.Path).Count(&count).Error; err != nil {
+			return err
+		}
+		orphaned = count == 0
+
+		return nil
+	})
+
+	return orphaned, err
+}
+
+// PendingProductImageIDs mengembalikan gambar yang menunggu diproses worker.
+func PendingProductImageIDs(db *gorm.DB, limit int) ([]string, error) {
+	var ids []string
+	err := db.Model(&ProductImage{}).
+		Where("status = ?", consts.ProductImageStatusPending).
+		Order("created_at asc").
+		Limit(limit).
+		Pluck("id", &ids).Error
+
+	return ids, err
+}
+
+// RequeueProductImages mengembalikan gambar yang terhenti saat diproses (mis.
+// server restart) ke antrian, dan juga gambar yang gagal jika failed true.
+func RequeueProductImages(db *gorm.DB, failed bool) (int64, error) {
+	statuses := []string{consts.ProductImageStatusProcessing}
+	if failed {
+		statuses = append(statuses, consts.ProductImageStatusFailed)
+	}
+	result := db.Model(&ProductImage{}).
+		Where("status IN ?", statuses).
+		Updates(map[string]interface{}{"status": consts.ProductImageStatusPending, "error": ""})
+
+	return result.RowsAffected, result.Error
+}
+
+// ClaimProductImage mengambil gambar pending untuk diproses. Hasil nil berarti
+// gambar sudah diambil worker lain atau sudah dihapus.
+func ClaimProductImage(db *gorm.DB, id string) (*ProductImage, error) {
+	result := db.Model(&ProductImage{}).
+		Where("id = ? AND status = ?", id, consts.ProductImageStatusPending).
+		Update("status", consts.ProductImageStatusProcessing)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+
+	var image ProductImage
+	if err := db.Where("id = ?", id).First(&image).Error; err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// FindProcessedImage mencari gambar lain dengan file yang sama yang variannya
+// sudah dibuat, supaya file yang sama tidak diproses dua kali.
+func FindProcessedImage(db *gorm.DB, hash string, excludeID string) (*ProductImage, error) {
+	var image ProductImage
+	err := db.Where("hash = ? AND id <> ? AND status = ?", hash, excludeID, consts.ProductImageStatusReady).
+		Order("processed_at desc").
+		First(&image).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &image, nil
+}
+
+// CopyVariants menyalin path varian dari gambar lain dengan file yang sama.
+func (i *ProductImage) CopyVariants(source *ProductImage) {
+	i.ExtraLarge, i.Large, i.Medium, i.Small = source.ExtraLarge, source.Large, source.Medium, source.Small
+	i.WebpExtraLarge, i.WebpLarge, i.WebpMedium, i.WebpSmall = source.WebpExtraLarge, source.WebpLarge, source.WebpMedium, source.WebpSmall
+	i.Warning = source.Warning
+}
+
+// FinishProcessing menyimpan hasil worker: varian, status ready dan Warning,
+// atau status failed beserta pesan errornya.
+func (i *ProductImage) FinishProcessing(db *gorm.DB, processErr error) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":       consts.ProductImageStatusReady,
+		"error":        "",
+		"warning":      i.Warning,
+		"processed_at": now,
+	}
+	if processErr != nil {
+		updates["warning"] = ""
+		updates["status"] = consts.ProductImageStatusFailed
+		updates["error"] = processErr.Error()
+	} else {
+		updates["extra_large"] = i.ExtraLarge
+		updates["large"] = i.Large
+		updates["medium"] = i.Medium
+		updates["small"] = i.Small
+		updates["webp_extra_large"] = i.WebpExtraLarge
+		updates["webp_large"] = i.WebpLarge
+		updates["webp_medium"] = i.WebpMedium
+		updates["webp_small"] = i.WebpSmall
+	}
+
+	if err := db.Model(&ProductImage{}).Where("id = ?", i.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	i.Status = updates["status"].(string)
+	i.Error = updates["error"].(string)
+	i.Warning = updates["warning"].(string)
+	i.ProcessedAt = &now
+
+	return nil
+}
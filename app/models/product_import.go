@@ -0,0 +1,478 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
+	"github.com/gosimple/slug"
+	"github.com/shopspring/decimal"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ProductImportColumns adalah kolom file import/export produk, sesuai urutan
+// export. Kolom categories dan images berisi beberapa nilai dipisah "|".
+var ProductImportColumns = []string{
+	"sku",
+	"name",
+	"price",
+	"stock",
+	"weight",
+	"length",
+	"width",
+	"height",
+	"short_description",
+	"description",
+	"categories",
+	"images",
+	"status",
+}
+
+const ProductImportListSeparator = "|"
+
+// ProductImportRecord adalah satu baris file import. Sel kosong berarti nilai
+// produk yang sudah ada tidak diubah.
+type ProductImportRecord struct {
+	Row    int
+	Values map[string]string
+}
+
+func (r ProductImportRecord) value(column string) string {
+	return strings.TrimSpace(r.Values[column])
+}
+
+func (r ProductImportRecord) list(column string) []string {
+	var items []string
+	for _, item := range strings.Split(r.value(column), ProductImportListSeparator) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+type ProductImportRowResult struct {
+	Row       int      `json:"row"`
+	Sku       string   `json:"sku"`
+	Action    string   `json:"action,omitempty"`
+	ProductID string   `json:"product_id,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+type ProductImportSummary struct {
+	DryRun  bool                     `json:"dry_run"`
+	Total   int                      `json:"total"`
+	Created int                      `json:"created"`
+	Updated int                      `json:"updated"`
+	Failed  int                      `json:"failed"`
+	Rows    []ProductImportRowResult `json:"rows"`
+}
+
+// ProductImportOptions mengatur jalannya import. FetchImage mengubah URL atau
+// path gambar menjadi path yang disimpan di ProductImage; Progress dipanggil
+// setelah setiap baris.
+type ProductImportOptions struct {
+	DryRun     bool
+	FetchImage func(productID string, source string) (string, error)
+	Progress   func(done int, summary *ProductImportSummary)
+	// JobID dan Actor dicatat sebagai referensi pergerakan stok
+	JobID string
+	Actor string
+}
+
+// ProductImportJob mencatat import yang berjalan di background.
+type ProductImportJob struct {
+	ID         string         `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	Filename   string         `gorm:"size:255" json:"filename"`
+	Status     string         `gorm:"size:20;index" json:"status"`
+	DryRun     bool           `json:"dry_run"`
+	Total      int            `json:"total"`
+	Processed  int            `json:"processed"`
+	Created    int            `json:"created"`
+	Updated    int            `json:"updated"`
+	Failed     int            `json:"failed"`
+	Report     datatypes.JSON `json:"report"`
+	Error      string         `gorm:"type:text" json:"error,omitempty"`
+	CreatedBy  string         `gorm:"size:36" json:"created_by"`
+	StartedAt  *time.Time     `json:"started_at"`
+	FinishedAt *time.Time     `json:"finished_at"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+}
+
+func (j *ProductImportJob) BeforeCreate(db *gorm.DB) error {
+	if j.ID == "" {
+		j.ID = uuid.New().String()
+	}
+	if j.Status == "" {
+		j.Status = consts.ImportJobStatusQueued
+	}
+
+	return nil
+}
+
+// SaveProgress menyimpan jumlah baris yang sudah diproses.
+func (j *ProductImportJob) SaveProgress(db *gorm.DB, done int, summary *ProductImportSummary) error {
+	j.Processed = done
+	j.Created, j.Updated, j.Failed = summary.Created, summary.Updated, summary.Failed
+
+	return db.Model(&ProductImportJob{}).Where("id = ?", j.ID).Updates(map[string]interface{}{
+		"processed": j.Processed,
+		"created":   j.Created,
+		"updated":   j.Updated,
+		"failed":    j.Failed,
+	}).Error
+}
+
+// Finish menandai job selesai (atau gagal bila err tidak nil) dan menyimpan laporan.
+func (j *ProductImportJob) Finish(db *gorm.DB, summary *ProductImportSummary, err error) error {
+	now := time.Now()
+	j.FinishedAt = &now
+	j.Status = consts.ImportJobStatusCompleted
+	if err != nil {
+		j.Status = consts.ImportJobStatusFailed
+		j.Error = err.Error()
+	}
+	if summary != nil {
+		j.Processed = len(summary.Rows)
+		j.Created, j.Updated, j.Failed = summary.Created, summary.Updated, summary.Failed
+		if report, err := json.Marshal(summary); err == nil {
+			j.Report = datatypes.JSON(report)
+		}
+	}
+
+	return db.Save(j).Error
+}
+
+type productImportRow struct {
+	record        ProductImportRecord
+	product       Product
+	exists        bool
+	categoryIDs   []string
+	hasCategories bool
+	setStock      bool
+	setStatus     bool
+	images        []string
+	result        ProductImportRowResult
+}
+
+// ImportProducts membuat atau memperbarui produk berdasarkan SKU. Setiap baris
+// divalidasi sendiri; baris yang gagal dilaporkan tanpa menghentikan import.
+func ImportProducts(db *gorm.DB, records []ProductImportRecord, opts ProductImportOptions) (*ProductImportSummary, error) {
+	summary := &ProductImportSummary{DryRun: opts.DryRun, Total: len(records), Rows: []ProductImportRowResult{}}
+	seen := map[string]int{}
+
+	for i, record := range records {
+		row, err := prepareProductImportRow(db, record, seen)
+		if err != nil {
+			return summary, err
+		}
+
+		if opts.DryRun && !row.exists {
+			// ID produk baru baru ada setelah import sungguhan
+			row.result.ProductID = ""
+		}
+		if len(row.result.Errors) == 0 && !opts.DryRun {
+			if err := applyProductImportRow(db, row, opts); err != nil {
+				row.result.Errors = append(row.result.Errors, err.Error())
+			}
+		}
+
+		switch {
+		case len(row.result.Errors) > 0:
+			summary.Failed++
+		case row.result.Action == consts.ImportActionCreate:
+			summary.Created++
+		default:
+			summary.Updated++
+		}
+		summary.Rows = append(summary.Rows, row.result)
+
+		if opts.Progress != nil {
+			opts.Progress(i+1, summary)
+		}
+	}
+
+	return summary, nil
+}
+
+// prepareProductImportRow memvalidasi satu baris dan menyiapkan produk yang
+// akan disimpan tanpa menulis ke database.
+func prepareProductImportRow(db *gorm.DB, record ProductImportRecord, seen map[string]int) (*productImportRow, error) {
+	row := &productImportRow{record: record}
+	result := &row.result
+	result.Row = record.Row
+	result.Sku = record.value("sku")
+
+	if result.Sku == "" {
+		result.Errors = append(result.Errors, "sku is required")
+		return row, nil
+	}
+	if previous, ok := seen[result.Sku]; ok {
+		result.Errors = append(result.Errors, fmt.Sprintf("duplicate sku, already on row %d", previous))
+		return row, nil
+	}
+	seen[result.Sku] = record.Row
+
+	err := db.Where("sku = ? AND is_temporary = ?", result.Sku, false).First(&row.product).Error
+	switch {
+	case err == nil:
+		row.exists = true
+		result.Action = consts.ImportActionUpdate
+		result.ProductID = row.product.ID
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		row.product = Product{ID: uuid.New().String(), Sku: result.Sku, Type: consts.ProductTypeStandard}
+		result.Action = consts.ImportActionCreate
+		result.ProductID = row.product.ID
+	default:
+		return nil, err
+	}
+
+	product := &row.product
+	if name := record.value("name"); name != "" {
+		product.Name = name
+	} else if !row.exists {
+		result.Errors = append(result.Errors, "name is required for new products")
+	}
+
+	if value := record.value("price"); value != "" {
+		if d, err := decimal.NewFromString(value); err != nil || d.IsNegative() {
+			result.Errors = append(result.Errors, "invalid price")
+		} else {
+			product.Price = d
+		}
+	} else if !row.exists {
+		result.Errors = append(result.Errors, "price is required for new products")
+	}
+
+	if value := record.value("stock"); value != "" {
+		stock, err := strconv.Atoi(value)
+		switch {
+		case err != nil || stock < 0:
+			result.Errors = append(result.Errors, "invalid stock")
+		case row.exists && productHasWarehouseStock(db, product.ID):
+			result.Warnings = append(result.Warnings, "stock is managed per warehouse, stock column ignored")
+		default:
+			product.Stock = stock
+			row.setStock = true
+		}
+	}
+
+	measurements := map[string]*decimal.Decimal{
+		"weight": &product.Weight,
+		"length": &product.Length,
+		"width":  &product.Width,
+		"height": &product.Height,
+	}
+	for _, column := range []string{"weight", "length", "width", "height"} {
+		value := record.value(column)
+		if value == "" {
+			continue
+		}
+		d, err := decimal.NewFromString(value)
+		if err != nil || d.IsNegative() {
+			result.Errors = append(result.Errors, "invalid "+column)
+			continue
+		}
+		*measurements[column] = d
+	}
+
+	if value := record.value("short_description"); value != "" {
+		product.ShortDescription = value
+	}
+	if value := record.value("description"); value != "" {
+		product.Description = value
+	}
+
+	// produk baru tanpa kolom status dibuat sebagai draft
+	if value := record.value("status"); value != "" {
+		if status, err := ParseProductStatus(value); err != nil {
+			result.Errors = append(result.Errors, "invalid status")
+		} else if !row.exists || status != product.Status {
+			product.Status = status
+			row.setStatus = true
+		}
+	}
+
+	if categories := record.list("categories"); len(categories) > 0 {
+		row.hasCategories = true
+		for _, name := range categories {
+			var category Category
+			if err := db.Where("slug = ? OR slug = ?", name, slug.Make(name)).First(&category).Error; err != nil {
+				result.Errors = append(result.Errors, "unknown category "+name)
+				continue
+			}
+			row.categoryIDs = append(row.categoryIDs, category.ID)
+		}
+	}
+
+	for _, source := range record.list("images") {
+		if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+			if _, err := url.ParseRequestURI(source); err != nil {
+				result.Errors = append(result.Errors, "invalid image url "+source)
+				continue
+			}
+		} else if strings.Contains(source, "..") {
+			result.Errors = append(result.Errors, "invalid image path "+source)
+			continue
+		}
+		row.images = append(row.images, source)
+	}
+
+	return row, nil
+}
+
+// applyProductImportRow menyimpan satu baris yang sudah valid. Gambar diambil
+// sebelum transaksi supaya unduhan tidak menahan transaksi.
+func applyProductImportRow(db *gorm.DB, row *productImportRow, opts ProductImportOptions) error {
+	var imagePaths []string
+	for _, source := range row.images {
+		path := source
+		if opts.FetchImage != nil {
+			fetched, err := opts.FetchImage(row.product.ID, source)
+			if err != nil {
+				row.result.Warnings = append(row.result.Warnings, fmt.Sprintf("image %s skipped: %v", source, err))
+				continue
+			}
+			path = fetched
+		}
+		imagePaths = append(imagePaths, path)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		product := &row.product
+		if row.exists {
+			updates := map[string]interface{}{
+				"name":              product.Name,
+				"price":             product.Price,
+				"weight":            product.Weight,
+				"length":            product.Length,
+				"width":             product.Width,
+				"height":            product.Height,
+				"short_description": product.ShortDescription,
+				"description":       product.Description,
+			}
+			if err := tx.Model(&Product{}).Where("id = ?", product.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+			if row.setStock {
+				if _, err := SetProductStock(tx, product.Stock, importStockChange(product.ID, opts)); err != nil {
+					return err
+				}
+			}
+			if row.setStatus {
+				if err := product.UpdatePublishing(tx, product.Status, product.PublishAt, product.UnpublishAt); err != nil {
+					return err
+				}
+			}
+		} else {
+			if product.IsPublished() {
+				now := time.Now()
+				product.PublishedAt = &now
+			}
+			// pemilik produk mengikuti user pertama seperti AdminProductCreate
+			var owner User
+			if err := tx.Select("id").First(&owner).Error; err == nil {
+				product.UserID = owner.ID
+			}
+			if err := CreateProduct(tx, product, "User", "ProductImages", "Categories", "Images"); err != nil {
+				return err
+			}
+			if err := RecordInitialStock(tx, product, importStockChange(product.ID, opts)); err != nil {
+				return err
+			}
+		}
+
+		if row.hasCategories {
+			if err := product.SetCategories(tx, row.categoryIDs); err != nil {
+				return err
+			}
+		}
+
+		for _, path := range imagePaths {
+			var count int64
+			tx.Model(&ProductImage{}).Where("product_id = ? AND path = ?", product.ID, path).Count(&count)
+			if count > 0 {
+				continue
+			}
+			image := ProductImage{ProductID: product.ID, Path: path}
+			if err := CreateProductImage(tx, &image); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func importStockChange(productID string, opts ProductImportOptions) StockChange {
+	change := StockChange{ProductID: productID, Reason: consts.StockReasonImport, Actor: opts.Actor}
+	if opts.JobID != "" {
+		change.ReferenceType = consts.StockReferenceImportJob
+		change.ReferenceID = opts.JobID
+	}
+
+	return change
+}
+
+func productHasWarehouseStock(db *gorm.DB, productID string) bool {
+	var count int64
+	db.Model(&WarehouseStock{}).Where("product_id = ?", productID).Count(&count)
+
+	return count > 0
+}
+
+// ExportProductRecords mengembalikan semua produk publik dalam format import.
+func ExportProductRecords(db *gorm.DB) ([]ProductImportRecord, error) {
+	var products []Product
+	err := db.Debug().
+		Where("is_temporary = ?", false).
+		Preload("ProductImages", OrderedProductImages).
+		Preload("Categories").
+		Order("sku asc, created_at asc").
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]ProductImportRecord, 0, len(products))
+	for i, product := range products {
+		var categories, images []string
+		for _, category := range product.Categories {
+			categories = append(categories, category.Slug)
+		}
+		for _, image := range product.ProductImages {
+			images = append(images, image.Path)
+		}
+
+		records = append(records, ProductImportRecord{
+			Row: i + 2,
+			Values: map[string]string{
+				"sku":               product.Sku,
+				"name":              product.Name,
+				"price":             product.Price.String(),
+				"stock":             strconv.Itoa(product.Stock),
+				"weight":            product.Weight.String(),
+				"length":            product.Length.String(),
+				"width":             product.Width.String(),
+				"height":            product.Height.String(),
+				"short_description": product.ShortDescription,
+				"description":       product.Description,
+				"categories":        strings.Join(categories, ProductImportListSeparator),
+				"images":            strings.Join(images, ProductImportListSeparator),
+				"status":            product.StatusName(),
+			},
+		})
+	}
+
+	return records, nil
+}
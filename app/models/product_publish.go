@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidProductStatus = errors.New("status must be draft, published or archived")
+	ErrInvalidSchedule      = errors.New("unpublish_at must be after publish_at")
+)
+
+// ProductStatusNames adalah nama status produk untuk API, form dan file import.
+var ProductStatusNames = map[int]string{
+	consts.ProductStatusDraft:     "draft",
+	consts.ProductStatusPublished: "published",
+	consts.ProductStatusArchived:  "archived",
+}
+
+// ParseProductStatus menerima nama status (draft/published/archived) atau
+// angkanya.
+func ParseProductStatus(value string) (int, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	for status, name := range ProductStatusNames {
+		if value == name || value == strconv.Itoa(status) {
+			return status, nil
+		}
+	}
+
+	return 0, ErrInvalidProductStatus
+}
+
+func (p *Product) StatusName() string {
+	return ProductStatusNames[p.Status]
+}
+
+func (p *Product) IsPublished() bool {
+	return p.Status == consts.ProductStatusPublished
+}
+
+// PublishedProducts adalah scope untuk query publik: hanya produk published.
+func PublishedProducts(tx *gorm.DB) *gorm.DB {
+	return tx.Where("products.status = ?", consts.ProductStatusPublished)
+}
+
+// UpdatePublishing menyimpan status dan jadwal publish/unpublish produk.
+// Jadwal yang sudah lewat langsung diterapkan; publish manual menghapus
+// jadwal publish, dan produk yang tidak published tidak punya jadwal unpublish.
+func (p *Product) UpdatePublishing(db *gorm.DB, status int, publishAt *time.Time, unpublishAt *time.Time) error {
+	if _, ok := ProductStatusNames[status]; !ok {
+		return ErrInvalidProductStatus
+	}
+	if publishAt != nil && unpublishAt != nil && !unpublishAt.After(*publishAt) {
+		return ErrInvalidSchedule
+	}
+
+	now := time.Now()
+	if publishAt != nil && !publishAt.After(now) {
+		status = consts.ProductStatusPublished
+	}
+	if status == consts.ProductStatusPublished {
+		publishAt = nil
+		if unpublishAt != nil && !unpublishAt.After(now) {
+			status = consts.ProductStatusArchived
+		}
+	}
+	if status != consts.ProductStatusPublished {
+		unpublishAt = nil
+	}
+
+	publishedAt := p.PublishedAt
+	if status == consts.ProductStatusPublished && publishedAt == nil {
+		publishedAt = &now
+	}
+
+	err := db.Debug().Model(&Product{}).Where("id = ?", p.ID).Updates(map[string]interface{}{
+		"status":       status,
+		"publish_at":   publishAt,
+		"unpublish_at": unpublishAt,
+		"published_at": publishedAt,
+	}).Error
+	if err != nil {
+		return err
+	}
+	p.Status = status
+	p.PublishAt = publishAt
+	p.UnpublishAt = unpublishAt
+	p.PublishedAt = publishedAt
+
+	return nil
+}
+
+// ProcessProductSchedules mempublikasikan produk yang PublishAt-nya sudah lewat
+// lalu mengarsipkan produk published yang UnpublishAt-nya sudah lewat.
+func ProcessProductSchedules(db *gorm.DB, now time.Time) (int64, int64, error) {
+	var published, unpublished int64
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Debug().Model(&Product{}).
+			Where("publish_at <= ? AND status <> ?", now, consts.ProductStatusPublished).
+			Updates(map[string]interface{}{
+				"status":       consts.ProductStatusPublished,
+				"publish_at":   nil,
+				"published_at": gorm.Expr("COALESCE(published_at, ?)", now),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		published = result.RowsAffected
+
+		result = tx.Debug().Model(&Product{}).
+			Where("unpublish_at <= ? AND status = ?", now, consts.ProductStatusPublished).
+			Updates(map[string]interface{}{
+				"status":       consts.ProductStatusArchived,
+				"unpublish_at": nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		unpublished = result.RowsAffected
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return published, unpublished, nil
+}
+
+// PublishLegacyProducts menandai produk yang dibuat sebelum ada status publish
+// sebagai published, supaya katalog lama tetap tampil setelah migrasi.
+func PublishLegacyProducts(db *gorm.DB) (int64, error) {
+	result := db.Debug().Model(&Product{}).
+		Where("status = ? AND is_temporary = ?", consts.ProductStatusDraft, false).
+		Updates(map[string]interface{}{
+			"status":       consts.ProductStatusPublished,
+			"published_at": gorm.Expr("created_at"),
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// PreviewURL adalah link halaman produk untuk admin, termasuk draft dan arsip.
+func (p *Product) PreviewURL() string {
+	return "/products/" + p.Slug + "?preview=1"
+}
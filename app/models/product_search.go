@@ -0,0 +1,213 @@
+package models
+
+import (
+	"html"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Penanda highlight dari ts_headline. Sengaja bukan tag HTML supaya isi
+// deskripsi tetap di-escape sebelum <mark> dipasang (lihat HighlightSnippet).
+const (
+	snippetStart = "[[hl]]"
+	snippetStop  = "[[/hl]]"
+)
+
+// productSearchVector memberi bobot A untuk nama dan SKU, B untuk deskripsi
+// singkat dan C untuk deskripsi. Konfigurasi 'simple' dipakai karena katalog
+// berbahasa campuran Indonesia/Inggris.
+const productSearchVector = `setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+	setweight(to_tsvector('simple', coalesce(sku, '')), 'A') ||
+	setweight(to_tsvector('simple', coalesce(short_description, '')), 'B') ||
+	setweight(to_tsvector('simple', coalesce(description, '')), 'C')`
+
+// ZeroResultSearch mencatat kata kunci yang tidak menghasilkan produk apa pun,
+// dikelompokkan per kata kunci yang sudah dinormalisasi.
+type ZeroResultSearch struct {
+	ID             string    `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	Query          string    `gorm:"size:255;not null;uniqueIndex" json:"query"`
+	Hits           int       `gorm:"default:1" json:"hits"`
+	LastUserID     string    `gorm:"size:36" json:"last_user_id"`
+	LastSearchedAt time.Time `gorm:"index" json:"last_searched_at"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (z *ZeroResultSearch) BeforeCreate(db *gorm.DB) error {
+	if z.ID == "" {
+		z.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// ProductSearchResult adalah satu produk hasil pencarian beserta skornya.
+type ProductSearchResult struct {
+	Product    Product `json:"product"`
+	Rank       float64 `json:"rank"`
+	Similarity float64 `json:"similarity"`
+	// Snippet sudah berupa HTML aman dengan kata yang cocok dibungkus <mark>
+	Snippet string `json:"snippet"`
+}
+
+type productSearchRow struct {
+	ID         string
+	Rank       float64
+	Similarity float64
+	Snippet    string
+}
+
+// SetupProductSearch menyiapkan kolom tsvector (generated), indeks GIN dan
+// ekstensi pg_trgm. Aman dijalankan berulang kali setelah AutoMigrate.
+func SetupProductSearch(db *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (` + productSearchVector + `) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN (sku gin_trgm_ops)`,
+	}
+
+	for _, statement := range statements {
+		if err := db.Debug().Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// NormalizeSearchQuery merapikan spasi dan huruf besar kata kunci.
+func NormalizeSearchQuery(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
+
+// SearchProducts mencari produk publik dengan full-text search, ditambah
+// kemiripan trigram pada nama dan SKU (operator % dan <%, ambang bawaan
+// pg_trgm 0.3) untuk toleransi salah ketik. Hasil diurutkan berdasarkan
+// gabungan rank dan kemiripan.
+func (p *Product) SearchProducts(db *gorm.DB, query string, perPage int, page int) ([]ProductSearchResult, int64, error) {
+	query = NormalizeSearchQuery(query)
+	if query == "" {
+		return []ProductSearchResult{}, 0, nil
+	}
+
+	match := `p.is_temporary = false AND p.delete_at IS NULL AND p.status = @published AND (
+		p.search_vector @@ websearch_to_tsquery('simple', @q)
+		OR p.name % @q OR @q <% p.name
+		OR p.sku % @q
+	)`
+	args := map[string]interface{}{
+		"q":         query,
+		"published": consts.ProductStatusPublished,
+		"headline":  `StartSel="` + snippetStart + `", StopSel="` + snippetStop + `", MaxFragments=2, MaxWords=25, MinWords=8`,
+	}
+
+	var count int64
+	if err := db.Debug().Raw(`SELECT count(*) FROM products p WHERE `+match, args).Scan(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if count == 0 {
+		return []ProductSearchResult{}, 0, nil
+	}
+
+	args["limit"] = perPage
+	args["offset"] = (page - 1) * perPage
+
+	var rows []productSearchRow
+	err := db.Debug().Raw(`SELECT p.id,
+		ts_rank_cd(p.search_vector, websearch_to_tsquery('simple', @q)) AS rank,
+		GREATEST(similarity(p.name, @q), word_similarity(@q, p.name), similarity(p.sku, @q)) AS similarity,
+		ts_headline('simple', coalesce(nullif(p.short_description, ''), p.description, ''), websearch_to_tsquery('simple', @q), @headline) AS snippet
+		FROM products p
+		WHERE `+match+`
+		ORDER BY (ts_rank_cd(p.search_vector, websearch_to_tsquery('simple', @q)) * 2 +
+			GREATEST(similarity(p.name, @q), word_similarity(@q, p.name), similarity(p.sku, @q))) DESC,
+			p.created_at DESC
+		LIMIT @limit OFFSET @offset`, args).Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.ID)
+	}
+
+	var products []Product
+	if err := db.Debug().Preload("ProductImages", OrderedProductImages).Where("id IN ?", ids).Find(&products).Error; err != nil {
+		return nil, 0, err
+	}
+	productsByID := map[string]Product{}
+	for _, product := range products {
+		productsByID[product.ID] = product
+	}
+
+	results := make([]ProductSearchResult, 0, len(rows))
+	for _, row := range rows {
+		product, ok := productsByID[row.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, ProductSearchResult{
+			Product:    product,
+			Rank:       row.Rank,
+			Similarity: row.Similarity,
+			Snippet:    HighlightSnippet(row.Snippet),
+		})
+	}
+
+	return results, count, nil
+}
+
+// HighlightSnippet meng-escape snippet lalu mengganti penanda ts_headline
+// dengan <mark>.
+func HighlightSnippet(snippet string) string {
+	snippet = html.EscapeString(strings.TrimSpace(snippet))
+	snippet = strings.ReplaceAll(snippet, html.EscapeString(snippetStart), "<mark>")
+	snippet = strings.ReplaceAll(snippet, html.EscapeString(snippetStop), "</mark>")
+
+	return snippet
+}
+
+// LogZeroResultSearch menambah hitungan kata kunci yang tidak menemukan produk.
+func LogZeroResultSearch(db *gorm.DB, query string, userID string) error {
+	query = NormalizeSearchQuery(query)
+	if query == "" {
+		return nil
+	}
+	if runes := []rune(query); len(runes) > 255 {
+		query = string(runes[:255])
+	}
+
+	now := time.Now()
+	entry := ZeroResultSearch{
+		Query:          query,
+		Hits:           1,
+		LastUserID:     userID,
+		LastSearchedAt: now,
+	}
+
+	return db.Debug().Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "query"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"hits":             gorm.Expr("zero_result_searches.hits + 1"),
+			"last_user_id":     userID,
+			"last_searched_at": now,
+			"updated_at":       now,
+		}),
+	}).Create(&entry).Error
+}
+
+// GetZeroResultSearches mengembalikan kata kunci tanpa hasil, terbanyak dulu.
+func GetZeroResultSearches(db *gorm.DB, limit int) ([]ZeroResultSearch, error) {
+	var searches []ZeroResultSearch
+	err := db.Debug().Order("hits desc, last_searched_at desc").Limit(limit).Find(&searches).Error
+
+	return searches, err
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gosimple/slug"
+	"gorm.io/gorm"
+)
+
+var ErrSlugTaken = errors.New("slug is already used by another product")
+
+// ProductSlugHistory menyimpan slug lama produk supaya URL lama tetap
+// diarahkan (301) ke slug yang sekarang.
+type ProductSlugHistory struct {
+	ID        string    `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	ProductID string    `gorm:"size:36;index" json:"product_id"`
+	Product   Product   `json:"-"`
+	Slug      string    `gorm:"size:255;not null;uniqueIndex" json:"slug"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (h *ProductSlugHistory) BeforeCreate(db *gorm.DB) error {
+	if h.ID == "" {
+		h.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// ChangeSlug mengganti slug produk dan mencatat slug lama di riwayat. Slug
+// yang sedang dipakai produk lain ditolak; slug yang ada di riwayat produk
+// lain diambil alih.
+func (p *Product) ChangeSlug(db *gorm.DB, text string) error {
+	newSlug := slug.Make(text)
+	if newSlug == "" || newSlug == p.Slug {
+		return nil
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var taken int64
+		if err := tx.Unscoped().Model(&Product{}).Where("slug = ? AND id <> ?", newSlug, p.ID).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrSlugTaken
+		}
+
+		if err := tx.Where("slug = ?", newSlug).Delete(&ProductSlugHistory{}).Error; err != nil {
+			return err
+		}
+		if p.Slug != "" {
+			if err := tx.Where("slug = ?", p.Slug).Delete(&ProductSlugHistory{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Create(&ProductSlugHistory{ProductID: p.ID, Slug: p.Slug}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&Product{}).Where("id = ?", p.ID).Update("slug", newSlug).Error; err != nil {
+			return err
+		}
+		p.Slug = newSlug
+
+		return nil
+	})
+	// produk lain menyimpan slug yang sama setelah pengecekan di atas
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return ErrSlugTaken
+	}
+
+	return err
+}
+
+// FindBySlugHistory mencari produk dari slug lamanya.
+func (p *Product) FindBySlugHistory(db *gorm.DB, oldSlug string) (*Product, error) {
+	var history ProductSlugHistory
+	if err := db.Debug().Where("slug = ?", oldSlug).First(&history).Error; err != nil {
+		return nil, err
+	}
+
+	var product Product
+	if err := db.Debug().Where("id = ?", history.ProductID).First(&product).Error; err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// BackfillProductSlugs mengisi slug produk yang masih kosong dan membuat ulang
+// slug yang dipakai lebih dari satu produk; produk terlama mempertahankan
+// slugnya. Dijalankan sebelum unique index products.slug dibuat.
+func BackfillProductSlugs(db *gorm.DB) (int, error) {
+	var products []Product
+	if err := db.Debug().Unscoped().Select("id", "name", "slug").Order("created_at, id").Find(&products).Error; err != nil {
+		return 0, err
+	}
+
+	seen := map[string]bool{}
+	var pending []Product
+	for _, product := range products {
+		if product.Slug == "" || seen[product.Slug] {
+			pending = append(pending, product)
+			continue
+		}
+		seen[product.Slug] = true
+	}
+
+	for i, product := range pending {
+		source := product.Slug
+		if source == "" {
+			source = product.Name
+		}
+		err := retrySlugConflict(db, func(tx *gorm.DB) error {
+			newSlug, err := UniqueSlug(tx, &Product{}, source, product.ID)
+			if err != nil {
+				return err
+			}
+			return tx.Debug().Unscoped().Model(&Product{}).Where("id = ?", product.ID).Update("slug", newSlug).Error
+		})
+		if err != nil {
+			return i, err
+		}
+	}
+
+	return len(pending), nil
+}
@@ -1,5 +1,7 @@
 package models
 
+import "github.com/shopspring/decimal"
+
 type ProvinceResponse struct {
 	Meta Meta       `json:"meta"`
 	Data []Province `json:"data"`
@@ -47,9 +49,52 @@ type ShippingFeeParams struct {
 	Destination string `json:"destination"`
 	Weight      int    `json:"weight"`
 	Courier     string `json:"courier"`
+	// dipakai aturan ongkir internal (ShippingMethod), tidak dikirim ke RajaOngkir
+	ProvinceID string          `json:"province_id"`
+	Subtotal   decimal.Decimal `json:"subtotal"`
+	// gudang asal pengiriman; Origin diisi dari kota gudang ini
+	WarehouseID string `json:"warehouse_id"`
+	// jika diisi, Weight dihitung ulang per kurir sebagai berat yang ditagih
+	Parcel *Parcel `json:"-"`
 }
 
 type ShippingFeeOption struct {
-	Service string `json:"service"`
-	Fee     int64  `json:"fee"`
+	Courier     string `json:"courier"`
+	ServiceCode string `json:"service_code"`
+	Service     string `json:"service"`
+	Description string `json:"description"`
+	Fee         int64  `json:"fee"`
+	Etd         string `json:"etd"`
+}
+
+// EtdDays mengambil rentang estimasi hari dari Etd seperti "2-3 day", "1 HARI"
+// atau "3". Etd yang tidak bisa dibaca dikembalikan sebagai -1, -1.
+func (o ShippingFeeOption) EtdDays() (int, int) {
+	var numbers []int
+	current := -1
+	for _, r := range o.Etd {
+		if r >= '0' && r <= '9' {
+			if current < 0 {
+				current = 0
+			}
+			current = current*10 + int(r-'0')
+			continue
+		}
+		if current >= 0 {
+			numbers = append(numbers, current)
+			current = -1
+		}
+	}
+	if current >= 0 {
+		numbers = append(numbers, current)
+	}
+
+	switch len(numbers) {
+	case 0:
+		return -1, -1
+	case 1:
+		return numbers[0], numbers[0]
+	default:
+		return numbers[0], numbers[1]
+	}
 }
\ No newline at end of file
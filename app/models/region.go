@@ -0,0 +1,211 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	RegionLevelProvince = "province"
+	RegionLevelCity     = "city"
+	RegionLevelDistrict = "district"
+	RegionLevelPostcode = "postcode"
+)
+
+// RegionProvince, RegionCity dan RegionDistrict memakai ID yang sama dengan
+// RajaOngkir supaya ID yang sudah tersimpan di Address/OrderCustomer tetap valid.
+type RegionProvince struct {
+	ID        string    `gorm:"size:20;not null;uniqueIndex;primary_key" json:"id"`
+	Name      string    `gorm:"size:100;index" json:"name"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+type RegionCity struct {
+	ID         string    `gorm:"size:20;not null;uniqueIndex;primary_key" json:"id"`
+	ProvinceID string    `gorm:"size:20;index" json:"province_id"`
+	Name       string    `gorm:"size:100;index" json:"name"`
+	ZipCode    string    `gorm:"size:10" json:"zip_code"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+}
+
+type RegionDistrict struct {
+	ID        string    `gorm:"size:20;not null;uniqueIndex;primary_key" json:"id"`
+	CityID    string    `gorm:"size:20;index" json:"city_id"`
+	Name      string    `gorm:"size:100;index" json:"name"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+type RegionPostcode struct {
+	CityID     string    `gorm:"size:20;primaryKey" json:"city_id"`
+	Code       string    `gorm:"size:10;primaryKey" json:"code"`
+	DistrictID string    `gorm:"size:20;index" json:"district_id"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+}
+
+// RegionRecord adalah satu baris dataset import, dipakai untuk CSV maupun JSON
+// dengan kolom level, id, parent_id, name, postal_code.
+type RegionRecord struct {
+	Level      string `json:"level"`
+	ID         string `json:"id"`
+	ParentID   string `json:"parent_id"`
+	Name       string `json:"name"`
+	PostalCode string `json:"postal_code"`
+}
+
+// RegionNames adalah nama wilayah yang sudah di-resolve dari ID tersimpan.
+type RegionNames struct {
+	Province string `json:"province"`
+	City     string `json:"city"`
+	District string `json:"district"`
+}
+
+func (n RegionNames) String() string {
+	var parts []string
+	for _, part := range []string{n.District, n.City, n.Province} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+func (r *RegionProvince) GetProvinces(db *gorm.DB) ([]RegionProvince, error) {
+	var provinces []RegionProvince
+
+	err := db.Debug().Order("name ASC").Find(&provinces).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return provinces, nil
+}
+
+func (r *RegionCity) GetCitiesByProvinceID(db *gorm.DB, provinceID string) ([]RegionCity, error) {
+	var cities []RegionCity
+
+	err := db.Debug().Where("province_id = ?", provinceID).Order("name ASC").Find(&cities).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return cities, nil
+}
+
+func (r *RegionDistrict) GetDistrictsByCityID(db *gorm.DB, cityID string) ([]RegionDistrict, error) {
+	var districts []RegionDistrict
+
+	err := db.Debug().Where("city_id = ?", cityID).Order("name ASC").Find(&districts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return districts, nil
+}
+
+func (r *RegionPostcode) GetPostcodesByCityID(db *gorm.DB, cityID string) ([]RegionPostcode, error) {
+	var postcodes []RegionPostcode
+
+	err := db.Debug().Where("city_id = ?", cityID).Order("code ASC").Find(&postcodes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return postcodes, nil
+}
+
+// ImportRegions menyimpan (upsert) dataset wilayah. Baris dengan level tidak
+// dikenal atau tanpa ID dilewati; jumlah baris yang tersimpan dikembalikan.
+func ImportRegions(db *gorm.DB, records []RegionRecord) (int, error) {
+	imported := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		upsert := tx.Clauses(clause.OnConflict{UpdateAll: true})
+		for _, record := range records {
+			id := strings.TrimSpace(record.ID)
+			parentID := strings.TrimSpace(record.ParentID)
+			name := strings.TrimSpace(record.Name)
+			postalCode := strings.TrimSpace(record.PostalCode)
+
+			var err error
+			switch strings.ToLower(strings.TrimSpace(record.Level)) {
+			case RegionLevelProvince:
+				if id == "" {
+					continue
+				}
+				err = upsert.Create(&RegionProvince{ID: id, Name: name}).Error
+			case RegionLevelCity:
+				if id == "" {
+					continue
+				}
+				err = upsert.Create(&RegionCity{ID: id, ProvinceID: parentID, Name: name, ZipCode: postalCode}).Error
+			case RegionLevelDistrict:
+				if id == "" {
+					continue
+				}
+				err = upsert.Create(&RegionDistrict{ID: id, CityID: parentID, Name: name}).Error
+			case RegionLevelPostcode:
+				// parent_id adalah city ID, id (opsional) adalah district ID
+				code := postalCode
+				if code == "" {
+					code = name
+				}
+				if parentID == "" || code == "" {
+					continue
+				}
+				err = upsert.Create(&RegionPostcode{CityID: parentID, Code: code, DistrictID: id}).Error
+			default:
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			imported++
+		}
+
+		return nil
+	})
+
+	return imported, err
+}
+
+// ResolveRegionNames mengubah ID provinsi/kota/kecamatan menjadi nama. ID yang
+// belum ada di tabel wilayah dikembalikan apa adanya.
+func ResolveRegionNames(db *gorm.DB, provinceID string, cityID string, districtID string) RegionNames {
+	names := RegionNames{Province: provinceID, City: cityID, District: districtID}
+
+	if provinceID != "" {
+		var province RegionProvince
+		if err := db.Where("id = ?", provinceID).First(&province).Error; err == nil {
+			names.Province = province.Name
+		}
+	}
+	if cityID != "" {
+		var city RegionCity
+		if err := db.Where("id = ?", cityID).First(&city).Error; err == nil {
+			names.City = city.Name
+		}
+	}
+	if districtID != "" {
+		var district RegionDistrict
+		if err := db.Where("id = ?", districtID).First(&district).Error; err == nil {
+			names.District = district.Name
+		}
+	}
+
+	return names
+}
+
+func (a *Address) RegionNames(db *gorm.DB) RegionNames {
+	return ResolveRegionNames(db, a.ProvinceID, a.CityID, a.DistrictID)
+}
+
+func (o *OrderCustomer) RegionNames(db *gorm.DB) RegionNames {
+	return ResolveRegionNames(db, o.ProvinceID, o.CityID, "")
+}
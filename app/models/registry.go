@@ -11,6 +11,11 @@ func RegisterModels() []Model {
 		{Model: Address{}},
 		{Model: Product{}},
 		{Model: ProductImage{}},
+		{Model: ProductSlugHistory{}},
+		{Model: ProductReview{}},
+		{Model: ProductReviewImage{}},
+		{Model: ProductImportJob{}},
+		{Model: ZeroResultSearch{}},
 		{Model: Section{}},
 		{Model: Category{}},
 		{Model: Order{}},
@@ -18,7 +23,23 @@ func RegisterModels() []Model {
 		{Model: OrderCustomer{}},
 		{Model: Payment{}},
 		{Model: Shipment{}},
+		{Model: ShipmentEvent{}},
+		{Model: ShippingMethod{}},
+		{Model: PackagingBox{}},
+		{Model: Warehouse{}},
+		{Model: WarehouseStock{}},
+		{Model: StockTransfer{}},
+		{Model: StockMovement{}},
 		{Model: Cart{}},
 		{Model: CartItem{}},
+		{Model: Currency{}},
+		{Model: Wallet{}},
+		{Model: WalletTransaction{}},
+		{Model: GiftCard{}},
+		{Model: GiftCardRedemption{}},
+		{Model: RegionProvince{}},
+		{Model: RegionCity{}},
+		{Model: RegionDistrict{}},
+		{Model: RegionPostcode{}},
 	}
 }
\ No newline at end of file
@@ -0,0 +1,351 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrReviewNotAllowed    = errors.New("only customers with a delivered order for this product can review it")
+	ErrInvalidRating       = errors.New("rating must be between 1 and 5")
+	ErrInvalidReviewStatus = errors.New("invalid review status")
+)
+
+// ProductReview adalah ulasan pelanggan untuk satu item order yang sudah
+// diterima. Ulasan baru berstatus pending sampai dimoderasi admin.
+type ProductReview struct {
+	ID                 string               `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	ProductID          string               `gorm:"size:36;index" json:"product_id"`
+	Product            Product              `json:"-"`
+	UserID             string               `gorm:"size:36;index" json:"user_id"`
+	User               User                 `json:"-"`
+	OrderItemID        string               `gorm:"size:36;uniqueIndex" json:"order_item_id"`
+	Rating             int                  `gorm:"not null" json:"rating"`
+	Title              string               `gorm:"size:255" json:"title"`
+	Body               string               `gorm:"type:text" json:"body"`
+	Status             string               `gorm:"size:20;index;default:'pending'" json:"status"`
+	IsVerifiedPurchase bool                 `gorm:"default:false" json:"is_verified_purchase"`
+	AdminReply         string               `gorm:"type:text" json:"admin_reply"`
+	RepliedAt          *time.Time           `json:"replied_at"`
+	ModeratedBy        string               `gorm:"size:36" json:"moderated_by"`
+	ModeratedAt        *time.Time           `json:"moderated_at"`
+	Images             []ProductReviewImage `json:"images"`
+	// ReviewerName dan ProductName diisi saat memuat ulasan untuk ditampilkan
+	ReviewerName string    `gorm:"-" json:"reviewer_name"`
+	ProductName  string    `gorm:"-" json:"product_name,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type ProductReviewImage struct {
+	ID              string    `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	ProductReviewID string    `gorm:"size:36;index" json:"product_review_id"`
+	Path            string    `gorm:"size:255" json:"path"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// RatingSummary adalah ringkasan rating satu produk.
+type RatingSummary struct {
+	Average decimal.Decimal `json:"average"`
+	Count   int             `json:"count"`
+	// Stars berisi jumlah ulasan per bintang, indeks 0 = 1 bintang
+	Stars [5]int64 `json:"stars"`
+}
+
+func (r *ProductReview) BeforeCreate(db *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	if r.Status == "" {
+		r.Status = consts.ReviewStatusPending
+	}
+
+	return nil
+}
+
+func (i *ProductReviewImage) BeforeCreate(db *gorm.DB) error {
+	if i.ID == "" {
+		i.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// FindReviewableOrderItem mengembalikan item order terkirim milik user untuk
+// produk ini yang belum diulas.
+func FindReviewableOrderItem(db *gorm.DB, userID string, productID string) (*OrderItem, error) {
+	var item OrderItem
+	err := db.Debug().Model(&OrderItem{}).
+		Joins("JOIN orders ON orders.id = order_items.order_id AND orders.delete_at IS NULL").
+		Where("order_items.product_id = ? AND orders.user_id = ? AND orders.status = ?", productID, userID, consts.OrderStatusDelivered).
+		Where("order_items.id NOT IN (SELECT order_item_id FROM product_reviews)").
+		Order("orders.order_date desc").
+		First(&item).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// CreateProductReview menyimpan ulasan baru (pending) untuk item order yang
+// sudah diterima user.
+func CreateProductReview(db *gorm.DB, userID string, productID string, rating int, title string, body string, imagePaths []string) (*ProductReview, error) {
+	if rating < 1 || rating > 5 {
+		return nil, ErrInvalidRating
+	}
+
+	item, err := FindReviewableOrderItem(db, userID, productID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReviewNotAllowed
+		}
+		return nil, err
+	}
+
+	review := &ProductReview{
+		ProductID:          productID,
+		UserID:             userID,
+		OrderItemID:        item.ID,
+		Rating:             rating,
+		Title:              strings.TrimSpace(title),
+		Body:               strings.TrimSpace(body),
+		IsVerifiedPurchase: true,
+	}
+	for _, path := range imagePaths {
+		review.Images = append(review.Images, ProductReviewImage{Path: path})
+	}
+
+	if err := db.Debug().Omit("Product", "User").Create(review).Error; err != nil {
+		return nil, err
+	}
+
+	return review, nil
+}
+
+// FindReviewByID memuat ulasan beserta fotonya.
+func FindReviewByID(db *gorm.DB, id string) (*ProductReview, error) {
+	var review ProductReview
+	if err := db.Debug().Preload("Images").Where("id = ?", id).First(&review).Error; err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+// Moderate mengubah status ulasan lalu menghitung ulang rating produk.
+func (r *ProductReview) Moderate(db *gorm.DB, status string, adminID string) error {
+	switch status {
+	case consts.ReviewStatusPending, consts.ReviewStatusApproved, consts.ReviewStatusRejected:
+	default:
+		return ErrInvalidReviewStatus
+	}
+
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&ProductReview{}).Where("id = ?", r.ID).Updates(map[string]interface{}{
+			"status":       status,
+			"moderated_by": adminID,
+			"moderated_at": now,
+		}).Error
+		if err != nil {
+			return err
+		}
+		r.Status = status
+		r.ModeratedBy = adminID
+		r.ModeratedAt = &now
+
+		return RefreshProductRating(tx, r.ProductID)
+	})
+}
+
+// Reply menyimpan balasan admin; balasan kosong menghapus balasan.
+func (r *ProductReview) Reply(db *gorm.DB, reply string) error {
+	reply = strings.TrimSpace(reply)
+	var repliedAt *time.Time
+	if reply != "" {
+		now := time.Now()
+		repliedAt = &now
+	}
+
+	err := db.Debug().Model(&ProductReview{}).Where("id = ?", r.ID).Updates(map[string]interface{}{
+		"admin_reply": reply,
+		"replied_at":  repliedAt,
+	}).Error
+	if err != nil {
+		return err
+	}
+	r.AdminReply = reply
+	r.RepliedAt = repliedAt
+
+	return nil
+}
+
+// Delete menghapus ulasan beserta fotonya dan memperbarui rating produk.
+func (r *ProductReview) Delete(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_review_id = ?", r.ID).Delete(&ProductReviewImage{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&ProductReview{}, "id = ?", r.ID).Error; err != nil {
+			return err
+		}
+
+		return RefreshProductRating(tx, r.ProductID)
+	})
+}
+
+// RefreshProductRating menghitung ulang rata-rata dan jumlah ulasan yang
+// disetujui lalu menyimpannya di produk untuk query listing.
+func RefreshProductRating(db *gorm.DB, productID string) error {
+	var aggregate struct {
+		Average float64
+		Count   int
+	}
+	err := db.Debug().Model(&ProductReview{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, consts.ReviewStatusApproved).
+		Scan(&aggregate).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Debug().Model(&Product{}).Where("id = ?", productID).Updates(map[string]interface{}{
+		"rating_average": decimal.NewFromFloat(aggregate.Average).Round(2),
+		"rating_count":   aggregate.Count,
+	}).Error
+}
+
+// GetRatingSummary mengembalikan ringkasan rating beserta sebaran bintang.
+func GetRatingSummary(db *gorm.DB, product *Product) (*RatingSummary, error) {
+	summary := &RatingSummary{Average: product.RatingAverage, Count: product.RatingCount}
+
+	var rows []struct {
+		Rating int
+		Count  int64
+	}
+	err := db.Debug().Model(&ProductReview{}).
+		Select("rating, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", product.ID, consts.ReviewStatusApproved).
+		Group("rating").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if row.Rating >= 1 && row.Rating <= 5 {
+			summary.Stars[row.Rating-1] = row.Count
+		}
+	}
+
+	return summary, nil
+}
+
+// StarCount mengembalikan jumlah ulasan dengan rating star (1-5).
+func (s *RatingSummary) StarCount(star int) int64 {
+	if star < 1 || star > 5 {
+		return 0
+	}
+
+	return s.Stars[star-1]
+}
+
+// NormalizeReviewSort mengembalikan urutan ulasan yang valid, default terbaru.
+func NormalizeReviewSort(sort string) string {
+	switch sort {
+	case consts.ReviewSortOldest, consts.ReviewSortHighest, consts.ReviewSortLowest:
+		return sort
+	}
+
+	return consts.ReviewSortNewest
+}
+
+// GetApprovedReviews mengembalikan ulasan yang disetujui untuk satu produk.
+func GetApprovedReviews(db *gorm.DB, productID string, sort string, perPage int, page int) ([]ProductReview, int64, error) {
+	var reviews []ProductReview
+	var count int64
+
+	scope := func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("product_id = ? AND status = ?", productID, consts.ReviewStatusApproved)
+	}
+	if err := db.Model(&ProductReview{}).Scopes(scope).Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "created_at desc"
+	switch NormalizeReviewSort(sort) {
+	case consts.ReviewSortOldest:
+		order = "created_at asc"
+	case consts.ReviewSortHighest:
+		order = "rating desc, created_at desc"
+	case consts.ReviewSortLowest:
+		order = "rating asc, created_at desc"
+	}
+
+	err := db.Debug().Scopes(scope).
+		Preload("Images").
+		Preload("User").
+		Order(order).
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	fillReviewerNames(reviews)
+
+	return reviews, count, nil
+}
+
+// GetReviewsByStatus dipakai admin untuk antrian moderasi. Status kosong
+// berarti semua status.
+func GetReviewsByStatus(db *gorm.DB, status string, perPage int, page int) ([]ProductReview, int64, error) {
+	var reviews []ProductReview
+	var count int64
+
+	query := db.Model(&ProductReview{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Debug().
+		Preload("Images").
+		Preload("User").
+		Preload("Product").
+		Order("created_at desc").
+		Limit(perPage).
+		Offset((page - 1) * perPage).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	fillReviewerNames(reviews)
+	for i := range reviews {
+		reviews[i].ProductName = reviews[i].Product.Name
+	}
+
+	return reviews, count, nil
+}
+
+// fillReviewerNames menampilkan nama depan dan inisial nama belakang saja.
+func fillReviewerNames(reviews []ProductReview) {
+	for i := range reviews {
+		name := strings.TrimSpace(reviews[i].User.FirstName)
+		if last := []rune(strings.TrimSpace(reviews[i].User.LastName)); len(last) > 0 {
+			name += " " + strings.ToUpper(string(last[0])) + "."
+		}
+		if name == "" {
+			name = "Pelanggan"
+		}
+		reviews[i].ReviewerName = name
+	}
+}
@@ -1,12 +1,74 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
 
 type Section struct {
 	ID         string `gorm:"size:36;not null;uniqueIndex;primary_key"`
 	Name       string `gorm:"size:100;"`
-	Slug       string `gorm:"size:100;"`
+	Slug       string `gorm:"size:100;index"`
+	Position   int    `gorm:"default:0"`
 	CreatedAt  time.Time
 	UpdateAt   time.Time
 	Categories []Category
-}
\ No newline at end of file
+}
+
+func (s *Section) BeforeCreate(db *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+// GetSections mengembalikan semua section beserta pohon kategorinya.
+func (s *Section) GetSections(db *gorm.DB) ([]Section, error) {
+	var sections []Section
+	err := db.Debug().
+		Preload("Categories", func(tx *gorm.DB) *gorm.DB { return tx.Order("position asc, name asc") }).
+		Order("position asc, name asc").
+		Find(&sections).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sections {
+		sections[i].Categories = BuildCategoryTree(sections[i].Categories)
+	}
+
+	return sections, nil
+}
+
+func (s *Section) FindByID(db *gorm.DB, id string) (*Section, error) {
+	var section Section
+	if err := db.Debug().Where("id = ?", id).First(&section).Error; err != nil {
+		return nil, err
+	}
+
+	return &section, nil
+}
+
+func (s *Section) FindBySlug(db *gorm.DB, slug string) (*Section, error) {
+	var section Section
+	err := db.Debug().
+		Preload("Categories", func(tx *gorm.DB) *gorm.DB { return tx.Order("position asc, name asc") }).
+		Where("slug = ?", slug).
+		First(&section).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &section, nil
+}
+
+// CategoryIDs mengembalikan ID semua kategori di section ini.
+func (s *Section) CategoryIDs(db *gorm.DB) []string {
+	var ids []string
+	db.Model(&Category{}).Where("section_id = ?", s.ID).Pluck("id", &ids)
+
+	return ids
+}
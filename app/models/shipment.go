@@ -1,12 +1,33 @@
 package models
 
 import (
+	"errors"
 	"time"
 
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrShipmentExists        = errors.New("order already has a shipment")
+	ErrOrderNotShippable     = errors.New("order is not ready to ship")
+	ErrInvalidShipmentStatus = errors.New("invalid shipment status")
+	ErrShipmentAlreadyClosed = errors.New("shipment is already delivered or returned")
+)
+
+// shipmentStatusLabels menjaga urutan dan label status untuk timeline pelanggan.
+var shipmentStatusLabels = map[string]string{
+	consts.ShipmentStatusLabelCreated:   "Label dibuat",
+	consts.ShipmentStatusPickedUp:       "Diambil kurir",
+	consts.ShipmentStatusInTransit:      "Dalam perjalanan",
+	consts.ShipmentStatusOutForDelivery: "Sedang diantar",
+	consts.ShipmentStatusDelivered:      "Terkirim",
+	consts.ShipmentStatusFailedDelivery: "Gagal diantar",
+	consts.ShipmentStatusReturned:       "Dikembalikan",
+}
+
 type Shipment struct {
 	ID          string `gorm:"size:36;not null;uniqueIndex;primary_key"`
 	User        User
@@ -14,6 +35,8 @@ type Shipment struct {
 	Order       Order
 	OrderID     string `gorm:"size:36;index"`
 	TrackNumber string `gorm:"size:255;index"`
+	Courier     string `gorm:"size:100"`
+	Service     string `gorm:"size:100"`
 	Status      string `gorm:"size:36;index"`
 	TotalQty    int
 	TotalWeight decimal.Decimal `gorm:"type:decimal(10,2);"`
@@ -28,7 +51,316 @@ type Shipment struct {
 	PostCode    string          `gorm:"size:100;"`
 	ShippedBy   string          `gorm:"size:36;"`
 	ShippedAt   time.Time
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	DeletedAt   gorm.DeletedAt
-}
\ No newline at end of file
+	DeliveredAt *time.Time
+	// jadwal polling resi ke kurir; dikosongkan lagi setelah shipment selesai
+	LastPolledAt *time.Time
+	NextPollAt   *time.Time `gorm:"index"`
+	PollFailures int
+	Events       []ShipmentEvent
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	DeletedAt    gorm.DeletedAt
+}
+
+// ShipmentEvent adalah satu titik di timeline pengiriman.
+type ShipmentEvent struct {
+	ID          string    `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	ShipmentID  string    `gorm:"size:36;index" json:"shipment_id"`
+	Status      string    `gorm:"size:36" json:"status"`
+	Description string    `gorm:"size:255" json:"description"`
+	Location    string    `gorm:"size:255" json:"location"`
+	Source      string    `gorm:"size:20;default:'manual'" json:"source"`
+	OccurredAt  time.Time `gorm:"index" json:"occurred_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (s *Shipment) BeforeCreate(db *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func (e *ShipmentEvent) BeforeCreate(db *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func IsValidShipmentStatus(status string) bool {
+	_, ok := shipmentStatusLabels[status]
+	return ok
+}
+
+func ShipmentStatusLabel(status string) string {
+	if label, ok := shipmentStatusLabels[status]; ok {
+		return label
+	}
+
+	return status
+}
+
+func (s *Shipment) StatusLabel() string {
+	return ShipmentStatusLabel(s.Status)
+}
+
+func (s *Shipment) IsClosed() bool {
+	return s.Status == consts.ShipmentStatusDelivered || s.Status == consts.ShipmentStatusReturned
+}
+
+// CreateFromOrder membuat shipment untuk order yang sudah lunas (atau COD) dengan
+// alamat dan total qty/berat yang di-snapshot dari order.
+func (s *Shipment) CreateFromOrder(db *gorm.DB, order *Order, trackNumber string, courier string, service string, shippedBy string) (*Shipment, error) {
+	if order.Status == consts.OrderStatusCancelled || (!order.IsPaid() && !order.IsCOD()) {
+		return nil, ErrOrderNotShippable
+	}
+
+	var existing int64
+	db.Model(&Shipment{}).Where("order_id = ?", order.ID).Count(&existing)
+	if existing > 0 {
+		return nil, ErrShipmentExists
+	}
+
+	if courier == "" {
+		courier = order.ShippingCourier
+	}
+	if service == "" {
+		service = order.ShippingServiceName
+	}
+
+	now := time.Now()
+	shipment := &Shipment{
+		UserID:      order.UserID,
+		OrderID:     order.ID,
+		TrackNumber: trackNumber,
+		Courier:     courier,
+		Service:     service,
+		Status:      consts.ShipmentStatusLabelCreated,
+		ShippedBy:   shippedBy,
+		ShippedAt:   now,
+		TotalWeight: decimal.Zero,
+	}
+
+	for _, item := range order.OrderItems {
+		shipment.TotalQty += item.Qty
+		shipment.TotalWeight = shipment.TotalWeight.Add(item.Weight.Mul(decimal.NewFromInt(int64(item.Qty))))
+	}
+
+	if customer := order.OrderCustomer; customer != nil {
+		shipment.FirstName = customer.FirstName
+		shipment.LastName = customer.LastName
+		shipment.CityID = customer.CityID
+		shipment.ProvinceID = customer.ProvinceID
+		shipment.Address1 = customer.Address1
+		shipment.Address2 = customer.Address2
+		shipment.Phone = customer.Phone
+		shipment.Email = customer.Email
+		shipment.PostCode = customer.PostCode
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(shipment).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(&ShipmentEvent{
+			ShipmentID:  shipment.ID,
+			Status:      consts.ShipmentStatusLabelCreated,
+			Description: ShipmentStatusLabel(consts.ShipmentStatusLabelCreated),
+			OccurredAt:  now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return shipment, nil
+}
+
+func (s *Shipment) FindByID(db *gorm.DB, id string) (*Shipment, error) {
+	var shipment Shipment
+
+	err := db.Debug().
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("occurred_at ASC") }).
+		Where("id = ?", id).
+		First(&shipment).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &shipment, nil
+}
+
+func (s *Shipment) FindByOrderID(db *gorm.DB, orderID string) (*Shipment, error) {
+	var shipment Shipment
+
+	err := db.Debug().
+		Preload("Events", func(db *gorm.DB) *gorm.DB { return db.Order("occurred_at ASC") }).
+		Where("order_id = ?", orderID).
+		First(&shipment).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &shipment, nil
+}
+
+// UpdateStatus menambahkan event ke timeline dan memperbarui status shipment.
+// Ketika shipment terkirim, order ikut ditandai DELIVERED.
+func (s *Shipment) UpdateStatus(db *gorm.DB, status string, description string, location string, occurredAt time.Time) error {
+	if !IsValidShipmentStatus(status) {
+		return ErrInvalidShipmentStatus
+	}
+	if s.IsClosed() {
+		return ErrShipmentAlreadyClosed
+	}
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+	if description == "" {
+		description = ShipmentStatusLabel(status)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		event := ShipmentEvent{
+			ShipmentID:  s.ID,
+			Status:      status,
+			Description: description,
+			Location:    location,
+			Source:      "manual",
+			OccurredAt:  occurredAt,
+		}
+		if err := tx.Create(&event).Error; err != nil {
+			return err
+		}
+		s.Events = append(s.Events, event)
+
+		return s.applyStatus(tx, status, occurredAt)
+	})
+}
+
+// applyStatus menyimpan status baru; shipment yang terkirim ikut menandai order
+// DELIVERED dan shipment yang diretur mengembalikan stok order.
+func (s *Shipment) applyStatus(tx *gorm.DB, status string, occurredAt time.Time) error {
+	updates := map[string]interface{}{"status": status}
+	if status == consts.ShipmentStatusDelivered {
+		updates["delivered_at"] = occurredAt
+		s.DeliveredAt = &occurredAt
+	}
+	if err := tx.Model(&Shipment{}).Where("id = ?", s.ID).Updates(updates).Error; err != nil {
+		return err
+	}
+	s.Status = status
+
+	if status == consts.ShipmentStatusDelivered {
+		return tx.Model(&Order{}).
+			Where("id = ? AND status <> ?", s.OrderID, consts.OrderStatusCancelled).
+			Update("status", consts.OrderStatusDelivered).Error
+	}
+	// paket yang kembali ke pengirim masuk lagi ke stok
+	if status == consts.ShipmentStatusReturned {
+		var order Order
+		if err := tx.Unscoped().Select("id", "code").Where("id = ?", s.OrderID).First(&order).Error; err != nil {
+			return err
+		}
+		return RestockOrder(tx, &order, consts.StockReasonReturn, "")
+	}
+
+	return nil
+}
+
+// GetShipmentsDueForPolling mengembalikan shipment yang belum selesai, punya
+// nomor resi, dan jadwal polling-nya sudah lewat.
+func (s *Shipment) GetShipmentsDueForPolling(db *gorm.DB, now time.Time, limit int) ([]Shipment, error) {
+	var shipments []Shipment
+
+	err := db.Debug().
+		Where("status NOT IN ?", []string{consts.ShipmentStatusDelivered, consts.ShipmentStatusReturned}).
+		Where("track_number <> ''").
+		Where("next_poll_at IS NULL OR next_poll_at <= ?", now).
+		Order("next_poll_at ASC NULLS FIRST").
+		Limit(limit).
+		Find(&shipments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return shipments, nil
+}
+
+// SyncTrackingEvents menyimpan event dari kurir yang belum tercatat (berdasarkan
+// waktu dan deskripsi) lalu memperbarui status jika berubah. Mengembalikan
+// status lama dan apakah status berubah.
+func (s *Shipment) SyncTrackingEvents(db *gorm.DB, events []ShipmentEvent, status string) (string, bool, error) {
+	previous := s.Status
+	changed := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, event := range events {
+			var exists int64
+			tx.Model(&ShipmentEvent{}).
+				Where("shipment_id = ? AND occurred_at = ? AND description = ?", s.ID, event.OccurredAt, event.Description).
+				Count(&exists)
+			if exists > 0 {
+				continue
+			}
+
+			event.ID = ""
+			event.ShipmentID = s.ID
+			event.Source = "courier"
+			if err := tx.Create(&event).Error; err != nil {
+				return err
+			}
+			s.Events = append(s.Events, event)
+		}
+
+		if status == "" || status == s.Status || s.IsClosed() || !IsValidShipmentStatus(status) {
+			return nil
+		}
+
+		occurredAt := time.Now()
+		if len(events) > 0 && !events[len(events)-1].OccurredAt.IsZero() {
+			occurredAt = events[len(events)-1].OccurredAt
+		}
+		changed = true
+
+		return s.applyStatus(tx, status, occurredAt)
+	})
+
+	return previous, changed, err
+}
+
+// SchedulePoll mencatat hasil polling. failed=true menaikkan backoff eksponensial
+// (base * 2^gagal, maksimal maxWait); shipment yang selesai tidak dijadwalkan lagi.
+func (s *Shipment) SchedulePoll(db *gorm.DB, now time.Time, failed bool, base time.Duration, maxWait time.Duration) error {
+	if failed {
+		s.PollFailures++
+	} else {
+		s.PollFailures = 0
+	}
+
+	wait := base
+	for i := 0; i < s.PollFailures && wait < maxWait; i++ {
+		wait *= 2
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+
+	var nextPollAt *time.Time
+	if !s.IsClosed() {
+		next := now.Add(wait)
+		nextPollAt = &next
+	}
+	s.LastPolledAt = &now
+	s.NextPollAt = nextPollAt
+
+	return db.Model(&Shipment{}).Where("id = ?", s.ID).Updates(map[string]interface{}{
+		"last_polled_at": s.LastPolledAt,
+		"next_poll_at":   s.NextPollAt,
+		"poll_failures":  s.PollFailures,
+	}).Error
+}
@@ -0,0 +1,193 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// ShippingMethod adalah aturan ongkir yang diatur admin dan dihitung tanpa
+// RajaOngkir. Code dipakai sebagai kode kurir di keranjang dan order.
+//
+// Type:
+//   - flat: ongkir tetap sebesar Rate
+//   - weight_table: ongkir dari Tiers berdasarkan berat, lebihnya dikenai ExtraPerKg
+//   - free: gratis jika subtotal keranjang >= MinSubtotal
+//   - local: kurir toko sendiri, hanya jika kota tujuan sama dengan kota asal
+//   - surcharge: bukan opsi sendiri; Rate ditambahkan ke semua opsi untuk tujuan yang cocok
+//
+// ProvinceIDs dan CityIDs (dipisah koma) membatasi tujuan; kosong berarti semua.
+type ShippingMethod struct {
+	ID          string          `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	Code        string          `gorm:"size:50;not null;uniqueIndex" json:"code"`
+	Name        string          `gorm:"size:100" json:"name"`
+	Type        string          `gorm:"size:20;index" json:"type"`
+	IsActive    bool            `json:"is_active"`
+	Rate        decimal.Decimal `gorm:"type:decimal(16,2)" json:"rate"`
+	ExtraPerKg  decimal.Decimal `gorm:"type:decimal(16,2)" json:"extra_per_kg"`
+	MinSubtotal decimal.Decimal `gorm:"type:decimal(16,2)" json:"min_subtotal"`
+	MaxWeight   int             `json:"max_weight"`
+	ProvinceIDs string          `gorm:"size:255" json:"province_ids"`
+	CityIDs     string          `gorm:"type:text" json:"city_ids"`
+	Tiers       datatypes.JSON  `json:"tiers"`
+	Etd         string          `gorm:"size:50" json:"etd"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// ShippingRateTier berlaku untuk berat (gram) sampai dengan MaxWeight.
+type ShippingRateTier struct {
+	MaxWeight int             `json:"max_weight"`
+	Cost      decimal.Decimal `json:"cost"`
+}
+
+func (m *ShippingMethod) BeforeCreate(db *gorm.DB) error {
+	if m.ID == "" {
+		m.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func IsValidShippingMethodType(methodType string) bool {
+	switch methodType {
+	case consts.ShippingMethodTypeFlat, consts.ShippingMethodTypeWeightTable, consts.ShippingMethodTypeFree,
+		consts.ShippingMethodTypeLocal, consts.ShippingMethodTypeSurcharge:
+		return true
+	}
+
+	return false
+}
+
+func (m *ShippingMethod) GetActiveShippingMethods(db *gorm.DB) ([]ShippingMethod, error) {
+	var methods []ShippingMethod
+
+	err := db.Debug().Where("is_active = ?", true).Order("code ASC").Find(&methods).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return methods, nil
+}
+
+func (m *ShippingMethod) FindByCode(db *gorm.DB, code string) (*ShippingMethod, error) {
+	var method ShippingMethod
+
+	err := db.Debug().Where("code = ?", strings.ToLower(code)).First(&method).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &method, nil
+}
+
+// GetTiers mengembalikan tier terurut berdasarkan MaxWeight; MaxWeight harus
+// positif dan tidak boleh sama antar tier.
+func (m *ShippingMethod) GetTiers() ([]ShippingRateTier, error) {
+	var tiers []ShippingRateTier
+	if len(m.Tiers) == 0 {
+		return tiers, nil
+	}
+
+	if err := json.Unmarshal(m.Tiers, &tiers); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tiers, func(i, j int) bool {
+		return tiers[i].MaxWeight < tiers[j].MaxWeight
+	})
+	for i, tier := range tiers {
+		if tier.MaxWeight <= 0 {
+			return nil, errors.New("tier max_weight must be positive")
+		}
+		if i > 0 && tier.MaxWeight == tiers[i-1].MaxWeight {
+			return nil, fmt.Errorf("duplicate tier max_weight %d", tier.MaxWeight)
+		}
+	}
+
+	return tiers, nil
+}
+
+func listContains(list string, value string) bool {
+	if strings.TrimSpace(list) == "" {
+		return true
+	}
+
+	for _, item := range strings.Split(list, ",") {
+		if strings.TrimSpace(item) == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchesDestination melaporkan apakah tujuan masuk zona aturan ini.
+func (m *ShippingMethod) MatchesDestination(provinceID string, cityID string) bool {
+	return listContains(m.ProvinceIDs, provinceID) && listContains(m.CityIDs, cityID)
+}
+
+// Quote menghitung ongkir untuk params. ok=false berarti metode ini tidak
+// tersedia untuk tujuan, berat atau subtotal tersebut.
+func (m *ShippingMethod) Quote(params ShippingFeeParams) (decimal.Decimal, bool) {
+	if !m.IsActive || m.Type == consts.ShippingMethodTypeSurcharge {
+		return decimal.Zero, false
+	}
+	if !m.MatchesDestination(params.ProvinceID, params.Destination) {
+		return decimal.Zero, false
+	}
+	if m.MaxWeight > 0 && params.Weight > m.MaxWeight {
+		return decimal.Zero, false
+	}
+
+	switch m.Type {
+	case consts.ShippingMethodTypeFlat:
+		return m.Rate, true
+	case consts.ShippingMethodTypeFree:
+		if params.Subtotal.LessThan(m.MinSubtotal) {
+			return decimal.Zero, false
+		}
+		return decimal.Zero, true
+	case consts.ShippingMethodTypeLocal:
+		if params.Origin == "" || params.Origin != params.Destination {
+			return decimal.Zero, false
+		}
+		return m.Rate, true
+	case consts.ShippingMethodTypeWeightTable:
+		tiers, err := m.GetTiers()
+		if err != nil || len(tiers) == 0 {
+			return decimal.Zero, false
+		}
+		for _, tier := range tiers {
+			if params.Weight <= tier.MaxWeight {
+				return tier.Cost, true
+			}
+		}
+		last := tiers[len(tiers)-1]
+		if !m.ExtraPerKg.IsPositive() {
+			return decimal.Zero, false
+		}
+		extraKg := (params.Weight - last.MaxWeight + 999) / 1000
+		return last.Cost.Add(m.ExtraPerKg.Mul(decimal.NewFromInt(int64(extraKg)))), true
+	}
+
+	return decimal.Zero, false
+}
+
+// Surcharge mengembalikan tambahan biaya jika aturan surcharge cocok dengan tujuan.
+func (m *ShippingMethod) Surcharge(params ShippingFeeParams) decimal.Decimal {
+	if !m.IsActive || m.Type != consts.ShippingMethodTypeSurcharge || !m.MatchesDestination(params.ProvinceID, params.Destination) {
+		return decimal.Zero
+	}
+
+	return m.Rate
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gosimple/slug"
+	"gorm.io/gorm"
+)
+
+// slugRetries adalah jumlah percobaan ulang saat insert/update kalah cepat
+// dengan request lain yang memakai slug yang sama.
+const slugRetries = 5
+
+// UniqueSlug membuat slug dari text dan menambahkan akhiran -2, -3, ... bila
+// slug sudah dipakai baris lain di tabel model (excludeID dikecualikan). Baris
+// yang di-soft delete ikut dihitung karena unique index tetap memuatnya.
+func UniqueSlug(db *gorm.DB, model interface{}, text string, excludeID string) (string, error) {
+	base := slug.Make(text)
+	if base == "" {
+		base = "item"
+	}
+
+	candidate := base
+	for i := 2; ; i++ {
+		var count int64
+		query := db.Unscoped().Model(model).Where("slug = ?", candidate)
+		if excludeID != "" {
+			query = query.Where("id <> ?", excludeID)
+		}
+		if err := query.Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// retrySlugConflict menjalankan fn di savepoint dan mengulanginya bila unique
+// index slug menolak, karena UniqueSlug hanya memeriksa sebelum query ditulis.
+func retrySlugConflict(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	var err error
+	for attempt := 0; attempt < slugRetries; attempt++ {
+		err = db.Transaction(fn)
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			return err
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInsufficientWalletBalance = errors.New("saldo wallet tidak mencukupi")
+
+// Wallet menyimpan saldo berjalan per user. Saldo hanya boleh diubah lewat
+// Credit/Debit sehingga selalu sama dengan jumlah WalletTransaction user tersebut.
+type Wallet struct {
+	UserID    string          `gorm:"size:36;not null;uniqueIndex;primary_key"`
+	Balance   decimal.Decimal `gorm:"type:decimal(16,2)"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// WalletTransaction adalah ledger append-only; Amount positif untuk kredit dan negatif untuk debit.
+type WalletTransaction struct {
+	ID           string          `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	UserID       string          `gorm:"size:36;index" json:"user_id"`
+	Amount       decimal.Decimal `gorm:"type:decimal(16,2)" json:"amount"`
+	BalanceAfter decimal.Decimal `gorm:"type:decimal(16,2)" json:"balance_after"`
+	Reason       string          `gorm:"size:50;index" json:"reason"`
+	Reference    string          `gorm:"size:100;index" json:"reference"`
+	Note         string          `gorm:"size:255" json:"note"`
+	CreatedBy    string          `gorm:"size:36" json:"created_by"`
+	CreatedAt    time.Time       `json:"created_at"`
+}
+
+func (t *WalletTransaction) BeforeCreate(db *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func (w *Wallet) GetBalance(db *gorm.DB, userID string) decimal.Decimal {
+	var wallet Wallet
+	if err := db.Where("user_id = ?", userID).First(&wallet).Error; err != nil {
+		return decimal.Zero
+	}
+
+	return wallet.Balance
+}
+
+func (w *Wallet) GetTransactions(db *gorm.DB, userID string, limit int) ([]WalletTransaction, error) {
+	var transactions []WalletTransaction
+
+	err := db.Debug().
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&transactions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+func (w *Wallet) Credit(db *gorm.DB, userID string, amount decimal.Decimal, reason string, reference string, note string, actor string) (*WalletTransaction, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be positive")
+	}
+
+	return w.apply(db, userID, amount, reason, reference, note, actor)
+}
+
+// Debit mengurangi saldo dan gagal dengan ErrInsufficientWalletBalance jika saldo
+// (yang dibaca dengan row lock) tidak cukup, sehingga dua checkout paralel tidak bisa
+// memakai saldo yang sama.
+func (w *Wallet) Debit(db *gorm.DB, userID string, amount decimal.Decimal, reason string, reference string, note string, actor string) (*WalletTransaction, error) {
+	if !amount.IsPositive() {
+		return nil, errors.New("amount must be positive")
+	}
+
+	return w.apply(db, userID, amount.Neg(), reason, reference, note, actor)
+}
+
+// RefundOrder mengembalikan saldo wallet yang dipakai order yang dibatalkan.
+// Aman dipanggil berulang: refund hanya dibuat sekali per order.
+func (w *Wallet) RefundOrder(db *gorm.DB, order *Order, actor string) error {
+	if !order.WalletAmount.IsPositive() {
+		return nil
+	}
+
+	var refunded int64
+	if err := db.Model(&WalletTransaction{}).
+		Where("user_id = ? AND reason = ? AND reference = ?", order.UserID, consts.WalletReasonOrderCancelled, order.ID).
+		Count(&refunded).Error; err != nil {
+		return err
+	}
+	if refunded > 0 {
+		return nil
+	}
+
+	_, err := w.Credit(db, order.UserID, order.WalletAmount, consts.WalletReasonOrderCancelled, order.ID, "Pembatalan order "+order.Code, actor)
+
+	return err
+}
+
+func (w *Wallet) apply(db *gorm.DB, userID string, amount decimal.Decimal, reason string, reference string, note string, actor string) (*WalletTransaction, error) {
+	var entry *WalletTransaction
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// pastikan baris wallet ada sebelum dikunci
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Wallet{UserID: userID, Balance: decimal.Zero}).Error; err != nil {
+			return err
+		}
+
+		var wallet Wallet
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userID).
+			First(&wallet).Error; err != nil {
+			return err
+		}
+
+		balance := wallet.Balance.Add(amount)
+		if balance.IsNegative() {
+			return ErrInsufficientWalletBalance
+		}
+
+		if err := tx.Model(&Wallet{}).Where("user_id = ?", userID).Update("balance", balance).Error; err != nil {
+			return err
+		}
+
+		entry = &WalletTransaction{
+			UserID:       userID,
+			Amount:       amount,
+			BalanceAfter: balance,
+			Reason:       reason,
+			Reference:    reference,
+			Note:         note,
+			CreatedBy:    actor,
+		}
+
+		return tx.Create(entry).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,326 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNoFulfilmentWarehouse      = errors.New("stok tidak tersedia di gudang mana pun untuk isi keranjang ini")
+	ErrInsufficientWarehouseStock = errors.New("stok gudang tidak mencukupi")
+	ErrInvalidStockTransfer       = errors.New("transfer stok tidak valid")
+)
+
+// Warehouse adalah lokasi pengiriman dengan kota asal sendiri. CityID dipakai
+// sebagai origin saat menghitung ongkir.
+type Warehouse struct {
+	ID         string `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	Code       string `gorm:"size:50;not null;uniqueIndex" json:"code"`
+	Name       string `gorm:"size:100" json:"name"`
+	Address    string `gorm:"size:255" json:"address"`
+	Phone      string `gorm:"size:50" json:"phone"`
+	CityID     string `gorm:"size:20;index" json:"city_id"`
+	ProvinceID string `gorm:"size:20" json:"province_id"`
+	PostCode   string `gorm:"size:10" json:"post_code"`
+	// Priority lebih kecil dipilih lebih dulu bila jarak ke tujuan setara
+	Priority  int       `gorm:"default:0" json:"priority"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WarehouseStock adalah stok satu produk di satu gudang. Product.Stock menyimpan
+// total dari semua gudang untuk produk yang sudah punya stok per lokasi.
+type WarehouseStock struct {
+	ID          string    `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	WarehouseID string    `gorm:"size:36;not null;uniqueIndex:idx_warehouse_product" json:"warehouse_id"`
+	ProductID   string    `gorm:"size:36;not null;uniqueIndex:idx_warehouse_product;index" json:"product_id"`
+	Product     Product   `json:"-"`
+	Qty         int       `gorm:"not null;default:0" json:"qty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type StockTransfer struct {
+	ID              string    `gorm:"size:36;not null;uniqueIndex;primary_key" json:"id"`
+	ProductID       string    `gorm:"size:36;index" json:"product_id"`
+	FromWarehouseID string    `gorm:"size:36;index" json:"from_warehouse_id"`
+	ToWarehouseID   string    `gorm:"size:36;index" json:"to_warehouse_id"`
+	Qty             int       `json:"qty"`
+	Note            string    `gorm:"size:255" json:"note"`
+	CreatedBy       string    `gorm:"size:36" json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+func (w *Warehouse) BeforeCreate(db *gorm.DB) error {
+	if w.ID == "" {
+		w.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func (s *WarehouseStock) BeforeCreate(db *gorm.DB) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func (t *StockTransfer) BeforeCreate(db *gorm.DB) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+
+	return nil
+}
+
+func (w *Warehouse) GetActiveWarehouses(db *gorm.DB) ([]Warehouse, error) {
+	var warehouses []Warehouse
+	err := db.Debug().Where("is_active = ?", true).Order("priority asc, name asc").Find(&warehouses).Error
+
+	return warehouses, err
+}
+
+// FindByID mencari gudang berdasarkan ID atau kode.
+func (w *Warehouse) FindByID(db *gorm.DB, id string) (*Warehouse, error) {
+	var warehouse Warehouse
+	if err := db.Debug().Where("id = ? OR code = ?", id, id).First(&warehouse).Error; err != nil {
+		return nil, err
+	}
+
+	return &warehouse, nil
+}
+
+func (w *Warehouse) GetStocks(db *gorm.DB) ([]WarehouseStock, error) {
+	var stocks []WarehouseStock
+	err := db.Debug().Preload("Product").Where("warehouse_id = ?", w.ID).Order("updated_at desc").Find(&stocks).Error
+
+	return stocks, err
+}
+
+// GetProductStocks mengembalikan stok produk per gudang.
+func GetProductStocks(db *gorm.DB, productID string) ([]WarehouseStock, error) {
+	var stocks []WarehouseStock
+	err := db.Debug().Where("product_id = ?", productID).Find(&stocks).Error
+
+	return stocks, err
+}
+
+// SetStock menetapkan jumlah stok produk di gudang (hasil stock opname),
+// memperbarui total Product.Stock dan mencatat selisihnya di ledger.
+func (w *Warehouse) SetStock(db *gorm.DB, productID string, qty int, actor string) (*WarehouseStock, error) {
+	if qty < 0 {
+		return nil, ErrInsufficientWarehouseStock
+	}
+
+	var stock WarehouseStock
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var current WarehouseStock
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("warehouse_id = ? AND product_id = ?", w.ID, productID).
+			First(&current).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		// baris baru dengan stok 0 tetap dibuat supaya produk ikut dikelola per gudang
+		if delta := qty - current.Qty; delta != 0 || err != nil {
+			if _, err := adjustStock(tx, StockChange{
+				ProductID:   productID,
+				WarehouseID: w.ID,
+				Qty:         delta,
+				Reason:      consts.StockReasonAdjustment,
+				Note:        "stock opname " + w.Code,
+				Actor:       actor,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return tx.Where("warehouse_id = ? AND product_id = ?", w.ID, productID).First(&stock).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &stock, nil
+}
+
+// SyncProductStock mengisi Product.Stock dengan total stok semua gudang. Produk
+// tanpa stok per gudang tidak diubah.
+func SyncProductStock(db *gorm.DB, productID string) error {
+	var rows int64
+	if err := db.Model(&WarehouseStock{}).Where("product_id = ?", productID).Count(&rows).Error; err != nil {
+		return err
+	}
+	if rows == 0 {
+		return nil
+	}
+
+	return db.Exec("UPDATE products SET stock = (SELECT COALESCE(SUM(qty), 0) FROM warehouse_stocks WHERE product_id = ?) WHERE id = ?", productID, productID).Error
+}
+
+// TransferStock memindahkan stok antar gudang. Total Product.Stock tidak berubah.
+func TransferStock(db *gorm.DB, productID string, fromID string, toID string, qty int, note string, actor string) (*StockTransfer, error) {
+	if qty <= 0 || fromID == "" || toID == "" || fromID == toID {
+		return nil, ErrInvalidStockTransfer
+	}
+
+	transfer := &StockTransfer{
+		ProductID:       productID,
+		FromWarehouseID: fromID,
+		ToWarehouseID:   toID,
+		Qty:             qty,
+		Note:            note,
+		CreatedBy:       actor,
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var source WarehouseStock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("warehouse_id = ? AND product_id = ?", fromID, productID).
+			First(&source).Error; err != nil {
+			return ErrInsufficientWarehouseStock
+		}
+		if source.Qty < qty {
+			return ErrInsufficientWarehouseStock
+		}
+
+		if err := tx.Model(&WarehouseStock{}).Where("id = ?", source.ID).
+			Update("qty", gorm.Expr("qty - ?", qty)).Error; err != nil {
+			return err
+		}
+
+		target := WarehouseStock{WarehouseID: toID, ProductID: productID, Qty: qty}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "warehouse_id"}, {Name: "product_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"qty": gorm.Expr("warehouse_stocks.qty + ?", qty), "updated_at": time.Now()}),
+		}).Create(&target).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(transfer).Error; err != nil {
+			return err
+		}
+
+		return recordTransferMovements(tx, transfer)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transfer, nil
+}
+
+// recordTransferMovements mencatat transfer sebagai dua baris ledger (keluar
+// dan masuk) dengan saldo Product.Stock yang tidak berubah.
+func recordTransferMovements(tx *gorm.DB, transfer *StockTransfer) error {
+	var balance int
+	if err := tx.Model(&Product{}).Select("stock").Where("id = ?", transfer.ProductID).Scan(&balance).Error; err != nil {
+		return err
+	}
+
+	for _, leg := range []struct {
+		warehouseID string
+		qty         int
+	}{
+		{transfer.FromWarehouseID, -transfer.Qty},
+		{transfer.ToWarehouseID, transfer.Qty},
+	} {
+		if err := tx.Create(&StockMovement{
+			ProductID:     transfer.ProductID,
+			WarehouseID:   leg.warehouseID,
+			Qty:           leg.qty,
+			Balance:       balance,
+			Reason:        consts.StockReasonTransfer,
+			ReferenceType: consts.StockReferenceStockTransfer,
+			ReferenceID:   transfer.ID,
+			Note:          transfer.Note,
+			CreatedBy:     transfer.CreatedBy,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SelectFulfilmentWarehouse memilih gudang aktif yang bisa memenuhi seluruh item
+// (productID -> qty): gudang di kota tujuan, lalu di provinsi tujuan, lalu menurut
+// Priority. Produk yang tidak punya stok per gudang tidak ikut dibatasi.
+// Mengembalikan nil tanpa error bila belum ada gudang sama sekali.
+func SelectFulfilmentWarehouse(db *gorm.DB, items map[string]int, cityID string, provinceID string) (*Warehouse, error) {
+	warehouseModel := Warehouse{}
+	warehouses, err := warehouseModel.GetActiveWarehouses(db)
+	if err != nil {
+		return nil, err
+	}
+	if len(warehouses) == 0 {
+		return nil, nil
+	}
+
+	var productIDs []string
+	for productID := range items {
+		productIDs = append(productIDs, productID)
+	}
+
+	var stocks []WarehouseStock
+	if len(productIDs) > 0 {
+		if err := db.Debug().Where("product_id IN ?", productIDs).Find(&stocks).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	tracked := map[string]bool{}
+	available := map[string]int{}
+	for _, stock := range stocks {
+		tracked[stock.ProductID] = true
+		available[stock.WarehouseID+"/"+stock.ProductID] = stock.Qty
+	}
+
+	var candidates []Warehouse
+	for _, warehouse := range warehouses {
+		ok := true
+		for productID, qty := range items {
+			if tracked[productID] && available[warehouse.ID+"/"+productID] < qty {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			candidates = append(candidates, warehouse)
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, ErrNoFulfilmentWarehouse
+	}
+
+	distance := func(w Warehouse) int {
+		switch {
+		case cityID != "" && w.CityID == cityID:
+			return 0
+		case provinceID != "" && w.ProvinceID == provinceID:
+			return 1
+		default:
+			return 2
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return distance(candidates[i]) < distance(candidates[j])
+	})
+
+	return &candidates[0], nil
+}
+
+// NormalizeWarehouseCode membuat kode gudang huruf besar tanpa spasi.
+func NormalizeWarehouseCode(code string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), " ", "-"))
+}
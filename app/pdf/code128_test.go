@@ -0,0 +1,97 @@
+package pdf
+
+import (
+	"reflect"
+	"testing"
+)
+
+// symbols memecah lebar modul kembali menjadi nilai simbol Code 128.
+func symbols(t *testing.T, widths []int) []int {
+	t.Helper()
+	lookup := map[string]int{}
+	for value, pattern := range code128Patterns {
+		lookup[pattern] = value
+	}
+
+	var values []int
+	for i := 0; i < len(widths); {
+		size := 6
+		if len(widths)-i == 7 {
+			size = 7
+		}
+		pattern := ""
+		for _, w := range widths[i : i+size] {
+			pattern += string(rune('0' + w))
+		}
+		value, ok := lookup[pattern]
+		if !ok {
+			t.Fatalf("unknown pattern %s at %d", pattern, i)
+		}
+		values = append(values, value)
+		i += size
+	}
+
+	return values
+}
+
+func TestCode128Patterns(t *testing.T) {
+	seen := map[string]bool{}
+	for value, pattern := range code128Patterns {
+		modules := 0
+		for _, c := range pattern {
+			modules += int(c - '0')
+		}
+		want := 11
+		if value == code128Stop {
+			want = 13
+		}
+		if modules != want {
+			t.Errorf("pattern %d (%s) has %d modules, want %d", value, pattern, modules, want)
+		}
+		if seen[pattern] {
+			t.Errorf("pattern %s is used twice", pattern)
+		}
+		seen[pattern] = true
+	}
+
+	// nilai dari tabel standar Code 128
+	known := map[int]string{0: "212222", 33: "111323", 55: "311321", 102: "411131", 103: "211412", 104: "211214", 106: "2331112"}
+	for value, pattern := range known {
+		if code128Patterns[value] != pattern {
+			t.Errorf("pattern %d = %s, want %s", value, code128Patterns[value], pattern)
+		}
+	}
+}
+
+func TestCode128Checksum(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []int
+	}{
+		// contoh perhitungan checksum yang umum dipakai: 879 mod 103 = 55
+		{"PJJ123C", []int{104, 48, 42, 42, 17, 18, 19, 35, 55, 106}},
+		// 104 + 33*1 = 137, 137 mod 103 = 34
+		{"A", []int{104, 33, 34, 106}},
+		{"JNE0123456789", nil},
+	}
+
+	for _, tt := range tests {
+		widths, err := Code128(tt.value)
+		if err != nil {
+			t.Fatalf("Code128(%q): %v", tt.value, err)
+		}
+		got := symbols(t, widths)
+		if len(got) != len(tt.value)+3 || got[0] != code128StartB || got[len(got)-1] != code128Stop {
+			t.Errorf("Code128(%q) symbols = %v", tt.value, got)
+		}
+		if tt.want != nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Code128(%q) symbols = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "résumé", "line\nbreak"} {
+		if _, err := Code128(value); err == nil {
+			t.Errorf("Code128(%q) should fail", value)
+		}
+	}
+}
@@ -0,0 +1,283 @@
+// Package pdf adalah penulis PDF minimal untuk dokumen cetak toko (packing slip,
+// label pengiriman): teks Helvetica, garis, kotak, gambar JPEG dan barcode Code 128.
+// Koordinat memakai point (1/72 inci) dengan titik (0,0) di kiri atas halaman.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"strings"
+)
+
+// Ukuran halaman dalam point.
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+	A6Width  = 297.64
+	A6Height = 419.53
+)
+
+type Document struct {
+	pages  []*Page
+	images []*Image
+}
+
+type Page struct {
+	Width   float64
+	Height  float64
+	content bytes.Buffer
+	images  map[string]*Image
+}
+
+// Image adalah gambar JPEG yang disematkan sekali dan bisa dipakai di banyak halaman.
+type Image struct {
+	name   string
+	data   []byte
+	width  int
+	height int
+}
+
+func New() *Document {
+	return &Document{}
+}
+
+func (d *Document) AddPage(width float64, height float64) *Page {
+	page := &Page{Width: width, Height: height, images: map[string]*Image{}}
+	d.pages = append(d.pages, page)
+
+	return page
+}
+
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+// AddImage meng-encode img sebagai JPEG untuk disematkan ke dokumen.
+func (d *Document) AddImage(img image.Image) (*Image, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, toRGB(img), &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	embedded := &Image{
+		name:   fmt.Sprintf("Im%d", len(d.images)+1),
+		data:   buf.Bytes(),
+		width:  bounds.Dx(),
+		height: bounds.Dy(),
+	}
+	d.images = append(d.images, embedded)
+
+	return embedded, nil
+}
+
+// toRGB memastikan JPEG yang dihasilkan 3 komponen (DeviceRGB), termasuk untuk
+// gambar grayscale atau dengan alpha (latar transparan dijadikan putih).
+func toRGB(img image.Image) image.Image {
+	bounds := img.Bounds()
+	rgb := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// komposit di atas putih
+			r = (r*a + 0xffff*(0xffff-a)) / 0xffff
+			g = (g*a + 0xffff*(0xffff-a)) / 0xffff
+			b = (b*a + 0xffff*(0xffff-a)) / 0xffff
+			i := rgb.PixOffset(x, y)
+			rgb.Pix[i] = uint8(r >> 8)
+			rgb.Pix[i+1] = uint8(g >> 8)
+			rgb.Pix[i+2] = uint8(b >> 8)
+			rgb.Pix[i+3] = 0xff
+		}
+	}
+
+	return rgb
+}
+
+func (p *Page) y(top float64) float64 {
+	return p.Height - top
+}
+
+// Text menulis satu baris teks dengan baseline di (x, y).
+func (p *Page) Text(x float64, y float64, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+
+	fmt.Fprintf(&p.content, "BT /%s %.2f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, p.y(y), escapeText(text))
+}
+
+// TextWrap menulis teks yang dipecah per kata agar muat di lebar maxWidth dan
+// mengembalikan posisi y setelah baris terakhir.
+func (p *Page) TextWrap(x float64, y float64, maxWidth float64, size float64, bold bool, text string) float64 {
+	lineHeight := size * 1.3
+	for _, line := range WrapText(text, maxWidth, size) {
+		p.Text(x, y, size, bold, line)
+		y += lineHeight
+	}
+
+	return y
+}
+
+// Line menggambar garis dengan ketebalan width.
+func (p *Page) Line(x1 float64, y1 float64, x2 float64, y2 float64, width float64) {
+	fmt.Fprintf(&p.content, "%.2f w %.2f %.2f m %.2f %.2f l S\n", width, x1, p.y(y1), x2, p.y(y2))
+}
+
+// Rect menggambar kotak dengan sudut kiri atas (x, y).
+func (p *Page) Rect(x float64, y float64, w float64, h float64, fill bool) {
+	op := "S"
+	if fill {
+		op = "f"
+	}
+
+	fmt.Fprintf(&p.content, "0.8 w %.2f %.2f %.2f %.2f re %s\n", x, p.y(y+h), w, h, op)
+}
+
+// Image menggambar img dengan sudut kiri atas (x, y) dan ukuran w x h.
+func (p *Page) Image(img *Image, x float64, y float64, w float64, h float64) {
+	p.images[img.name] = img
+	fmt.Fprintf(&p.content, "q %.2f 0 0 %.2f %.2f %.2f cm /%s Do Q\n", w, h, x, p.y(y+h), img.name)
+}
+
+// ImageFit menggambar img di dalam kotak w x h dengan menjaga rasio.
+func (p *Page) ImageFit(img *Image, x float64, y float64, w float64, h float64) {
+	if img.width == 0 || img.height == 0 {
+		return
+	}
+
+	scale := w / float64(img.width)
+	if hs := h / float64(img.height); hs < scale {
+		scale = hs
+	}
+	dw := float64(img.width) * scale
+	dh := float64(img.height) * scale
+
+	p.Image(img, x+(w-dw)/2, y+(h-dh)/2, dw, dh)
+}
+
+// WriteTo menulis dokumen PDF lengkap ke w.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+
+	object := func(body string) int {
+		offsets = append(offsets, buf.Len())
+		id := len(offsets)
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", id, body)
+		return id
+	}
+	stream := func(dict string, data []byte) int {
+		offsets = append(offsets, buf.Len())
+		id := len(offsets)
+		if dict != "" {
+			dict += " "
+		}
+		fmt.Fprintf(&buf, "%d 0 obj\n<< %s/Length %d >>\nstream\n", id, dict, len(data))
+		buf.Write(data)
+		buf.WriteString("\nendstream\nendobj\n")
+		return id
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1: catalog, 2: pages (ditulis setelah halaman diketahui), 3-4: font
+	catalog := object("<< /Type /Catalog /Pages 2 0 R >>")
+	offsets = append(offsets, 0)
+	pagesID := len(offsets)
+	fontRegular := object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	fontBold := object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	imageIDs := map[string]int{}
+	for _, img := range d.images {
+		imageIDs[img.name] = stream(fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode", img.width, img.height), img.data)
+	}
+
+	var kids []string
+	for _, page := range d.pages {
+		contentID := stream("", page.content.Bytes())
+
+		var xobjects []string
+		for name := range page.images {
+			xobjects = append(xobjects, fmt.Sprintf("/%s %d 0 R", name, imageIDs[name]))
+		}
+		resources := fmt.Sprintf("/Font << /F1 %d 0 R /F2 %d 0 R >>", fontRegular, fontBold)
+		if len(xobjects) > 0 {
+			resources += " /XObject << " + strings.Join(xobjects, " ") + " >>"
+		}
+
+		pageID := object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources << %s >> /Contents %d 0 R >>",
+			pagesID, page.Width, page.Height, resources, contentID))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageID))
+	}
+
+	offsets[pagesID-1] = buf.Len()
+	fmt.Fprintf(&buf, "%d 0 obj\n<< /Type /Pages /Kids [%s] /Count %d >>\nendobj\n", pagesID, strings.Join(kids, " "), len(kids))
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, catalog, xref)
+
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
+
+// escapeText mengubah teks ke WinAnsi; karakter di luar Latin-1 diganti "?".
+func escapeText(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n' || r == '\r' || r == '\t':
+			b.WriteByte(' ')
+		case r < 32:
+			continue
+		case r < 128:
+			b.WriteRune(r)
+		case r < 256:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+
+	return b.String()
+}
+
+// TextWidth memperkirakan lebar teks Helvetica (rata-rata 0.52 em per karakter).
+func TextWidth(text string, size float64) float64 {
+	return float64(len([]rune(text))) * size * 0.52
+}
+
+// WrapText memecah teks per kata sesuai perkiraan lebar.
+func WrapText(text string, maxWidth float64, size float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if line != "" && TextWidth(candidate, size) > maxWidth {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
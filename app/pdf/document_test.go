@@ -0,0 +1,86 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWriteToXrefOffsets(t *testing.T) {
+	doc := New()
+	logo := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	logo.Set(1, 1, color.RGBA{R: 200, A: 255})
+	img, err := doc.AddImage(logo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		page := doc.AddPage(A6Width, A6Height)
+		page.Text(10, 20, 12, true, fmt.Sprintf("Label %d (kurir) \\ é", i+1))
+		page.TextWrap(10, 40, 100, 9, false, "Jl. Contoh No. 1, Kecamatan Panjang, Kota Bandung")
+		page.Line(10, 60, 200, 60, 1)
+		page.ImageFit(img, 10, 70, 50, 50)
+		if err := page.Barcode(10, 130, 200, 40, "JNE0123456789"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	n, err := doc.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Fatalf("WriteTo returned %d, wrote %d bytes", n, buf.Len())
+	}
+	out := buf.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or EOF marker")
+	}
+
+	trailer := regexp.MustCompile(`trailer\n<< /Size (\d+) /Root (\d+) 0 R >>\nstartxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	if trailer == nil {
+		t.Fatalf("trailer not found:\n%s", out[bytes.LastIndex(out, []byte("trailer")):])
+	}
+	size, _ := strconv.Atoi(string(trailer[1]))
+	startxref, _ := strconv.Atoi(string(trailer[3]))
+
+	if !bytes.HasPrefix(out[startxref:], []byte(fmt.Sprintf("xref\n0 %d\n", size))) {
+		t.Fatalf("startxref %d does not point to an xref table of size %d", startxref, size)
+	}
+	lines := strings.Split(string(out[startxref:]), "\n")[2:]
+	if lines[0] != "0000000000 65535 f " {
+		t.Fatalf("xref entry 0 = %q", lines[0])
+	}
+
+	// 1 catalog + 1 pages + 2 font + 1 gambar + 2 * (content + page)
+	if want := 1 + 1 + 2 + 1 + 2*2 + 1; size != want {
+		t.Errorf("xref size = %d, want %d", size, want)
+	}
+	for id := 1; id < size; id++ {
+		entry := lines[id]
+		if len(entry) != 19 || !strings.HasSuffix(entry, " 00000 n ") {
+			t.Fatalf("xref entry %d = %q, want 20-byte in-use entry", id, entry)
+		}
+		offset, _ := strconv.Atoi(entry[:10])
+		if header := fmt.Sprintf("%d 0 obj\n", id); !bytes.HasPrefix(out[offset:], []byte(header)) {
+			t.Errorf("xref entry %d points to %q, want %q", id, out[offset:offset+len(header)], header)
+		}
+	}
+
+	// panjang stream harus sama dengan isi di antara stream dan endstream
+	streams := regexp.MustCompile(`/Length (\d+) >>\nstream\n`)
+	for _, match := range streams.FindAllSubmatchIndex(out, -1) {
+		length, _ := strconv.Atoi(string(out[match[2]:match[3]]))
+		if !bytes.HasPrefix(out[match[1]+length:], []byte("\nendstream\n")) {
+			t.Errorf("stream at %d does not end after /Length %d", match[0], length)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package shipping
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache menyimpan respons API berdasarkan key. Entry yang sudah kedaluwarsa
+// tetap dikembalikan dengan fresh=false supaya bisa dipakai saat API mati.
+type Cache interface {
+	Get(key string) (value []byte, fresh bool, ok bool)
+	Set(key string, value []byte, ttl time.Duration)
+}
+
+// DefaultCacheSize adalah jumlah entry maksimal MemoryCache bawaan. Key ongkir
+// memuat origin, tujuan, berat dan kurir sehingga tanpa batas map terus tumbuh.
+const DefaultCacheSize = 10000
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// MemoryCache adalah Cache in-memory yang aman dipakai banyak goroutine. Jika
+// jumlah entry melebihi MaxEntries, entry yang paling lama tidak dipakai (LRU)
+// dibuang.
+type MemoryCache struct {
+	MaxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	now     func() time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		MaxEntries: DefaultCacheSize,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+		now:        time.Now,
+	}
+}
+
+func (c *MemoryCache) Get(key string) ([]byte, bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false, false
+	}
+	c.order.MoveToFront(element)
+	entry := element.Value.(*memoryEntry)
+
+	return entry.value, c.now().Before(entry.expiresAt), true
+}
+
+func (c *MemoryCache) Set(key string, value []byte, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.MaxEntries > 0 && c.order.Len() > c.MaxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryEntry).key)
+	}
+}
+
+// Len mengembalikan jumlah entry yang tersimpan.
+func (c *MemoryCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
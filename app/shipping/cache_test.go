@@ -0,0 +1,47 @@
+package shipping
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMemoryCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := NewMemoryCache()
+	cache.MaxEntries = 3
+
+	for i := 0; i < 3; i++ {
+		cache.Set("cost:"+strconv.Itoa(i), []byte("x"), time.Minute)
+	}
+	// cost:0 dipakai lagi sehingga cost:1 yang paling lama tidak dipakai
+	if _, _, ok := cache.Get("cost:0"); !ok {
+		t.Fatal("cost:0 missing before eviction")
+	}
+	cache.Set("cost:3", []byte("x"), time.Minute)
+
+	if cache.Len() != 3 {
+		t.Fatalf("Len() = %d, want 3", cache.Len())
+	}
+	if _, _, ok := cache.Get("cost:1"); ok {
+		t.Error("cost:1 should have been evicted")
+	}
+	for _, key := range []string{"cost:0", "cost:2", "cost:3"} {
+		if _, _, ok := cache.Get(key); !ok {
+			t.Errorf("%s should still be cached", key)
+		}
+	}
+}
+
+func TestMemoryCacheReturnsStaleEntries(t *testing.T) {
+	now := time.Now()
+	cache := NewMemoryCache()
+	cache.now = func() time.Time { return now }
+
+	cache.Set("provinces", []byte("[]"), time.Minute)
+	now = now.Add(2 * time.Minute)
+
+	value, fresh, ok := cache.Get("provinces")
+	if !ok || fresh || string(value) != "[]" {
+		t.Fatalf("Get() = %q fresh=%v ok=%v, want stale entry", value, fresh, ok)
+	}
+}
@@ -0,0 +1,278 @@
+package shipping
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/models"
+)
+
+// Client membungkus API RajaOngkir (komerce) dengan timeout, retry dan cache.
+type Client struct {
+	BaseURL        string
+	APIKey         string
+	HTTPClient     *http.Client
+	MaxRetries     int
+	RetryWait      time.Duration
+	Cache          Cache
+	DestinationTTL time.Duration
+	QuoteTTL       time.Duration
+}
+
+func NewClient(baseURL string, apiKey string) *Client {
+	return &Client{
+		BaseURL:        baseURL,
+		APIKey:         apiKey,
+		HTTPClient:     &http.Client{Timeout: 6 * time.Second},
+		MaxRetries:     2,
+		RetryWait:      300 * time.Millisecond,
+		Cache:          NewMemoryCache(),
+		DestinationTTL: 24 * time.Hour,
+		QuoteTTL:       30 * time.Minute,
+	}
+}
+
+// NewClientFromEnv membaca API_ONGKIR_BASE_URL dan API_ONGKIR_KEY, serta
+// opsional API_ONGKIR_TIMEOUT (detik), API_ONGKIR_RETRIES,
+// API_ONGKIR_DESTINATION_TTL dan API_ONGKIR_QUOTE_TTL (menit) serta
+// API_ONGKIR_CACHE_SIZE (jumlah entry cache).
+func NewClientFromEnv() *Client {
+	client := NewClient(os.Getenv("API_ONGKIR_BASE_URL"), os.Getenv("API_ONGKIR_KEY"))
+
+	if v, err := strconv.Atoi(os.Getenv("API_ONGKIR_TIMEOUT")); err == nil && v > 0 {
+		client.HTTPClient.Timeout = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("API_ONGKIR_RETRIES")); err == nil && v >= 0 {
+		client.MaxRetries = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("API_ONGKIR_DESTINATION_TTL")); err == nil && v > 0 {
+		client.DestinationTTL = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv("API_ONGKIR_QUOTE_TTL")); err == nil && v > 0 {
+		client.QuoteTTL = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv("API_ONGKIR_CACHE_SIZE")); err == nil && v > 0 {
+		if cache, ok := client.Cache.(*MemoryCache); ok {
+			cache.MaxEntries = v
+		}
+	}
+
+	return client
+}
+
+// Provinces mengembalikan daftar provinsi tujuan.
+func (c *Client) Provinces() ([]models.Province, error) {
+	var provinces []models.Province
+	err := c.cached("provinces", c.DestinationTTL, &provinces, func() ([]byte, error) {
+		return c.get("destination/province")
+	})
+
+	return provinces, err
+}
+
+// Cities mengembalikan daftar kota/kabupaten dalam satu provinsi.
+func (c *Client) Cities(provinceID string) ([]models.City, error) {
+	provinceID = strings.TrimSpace(provinceID)
+	if provinceID == "" {
+		return nil, ErrInvalidParams
+	}
+
+	var cities []models.City
+	err := c.cached("cities:"+provinceID, c.DestinationTTL, &cities, func() ([]byte, error) {
+		return c.get("destination/city/" + url.PathEscape(provinceID))
+	})
+
+	return cities, err
+}
+
+// Districts mengembalikan daftar kecamatan dalam satu kota.
+func (c *Client) Districts(cityID string) ([]map[string]interface{}, error) {
+	cityID = strings.TrimSpace(cityID)
+	if cityID == "" {
+		return nil, ErrInvalidParams
+	}
+
+	var districts []map[string]interface{}
+	err := c.cached("districts:"+cityID, c.DestinationTTL, &districts, func() ([]byte, error) {
+		return c.get("destination/district/" + url.PathEscape(cityID))
+	})
+
+	return districts, err
+}
+
+// Postcodes mengembalikan daftar kode pos sebuah kota. Beberapa versi API hanya
+// mendukung bentuk query `?city_id=`, jadi 404 pada bentuk path dicoba ulang.
+func (c *Client) Postcodes(cityID string) ([]map[string]interface{}, error) {
+	cityID = strings.TrimSpace(cityID)
+	if cityID == "" {
+		return nil, ErrInvalidParams
+	}
+
+	var postcodes []map[string]interface{}
+	err := c.cached("postcodes:"+cityID, c.DestinationTTL, &postcodes, func() ([]byte, error) {
+		data, err := c.get("destination/postcode/" + url.PathEscape(cityID))
+		if IsNotFound(err) {
+			return c.get("destination/postcode?city_id=" + url.QueryEscape(cityID))
+		}
+		return data, err
+	})
+
+	return postcodes, err
+}
+
+// Cost menghitung ongkos kirim domestik. Hasil di-cache per origin, destination,
+// weight dan courier.
+func (c *Client) Cost(params models.ShippingFeeParams) ([]models.OngkirResult, error) {
+	origin := strings.TrimSpace(params.Origin)
+	destination := strings.TrimSpace(params.Destination)
+	courier := strings.TrimSpace(params.Courier)
+	if origin == "" || destination == "" || params.Weight <= 0 || courier == "" {
+		return nil, ErrInvalidParams
+	}
+
+	form := url.Values{}
+	form.Add("origin", origin)
+	form.Add("destination", destination)
+	form.Add("weight", strconv.Itoa(params.Weight))
+	form.Add("courier", courier)
+
+	key := fmt.Sprintf("cost:%s:%s:%d:%s", origin, destination, params.Weight, courier)
+
+	var results []models.OngkirResult
+	err := c.cached(key, c.QuoteTTL, &results, func() ([]byte, error) {
+		return c.do("POST", "calculate/domestic-cost", form)
+	})
+
+	return results, err
+}
+
+// cached mengisi out dari cache jika masih segar, atau dari fetch. Jika API
+// sedang tidak tersedia dan ada data lama di cache, data lama yang dipakai.
+func (c *Client) cached(key string, ttl time.Duration, out interface{}, fetch func() ([]byte, error)) error {
+	stale, fresh, ok := c.Cache.Get(key)
+	if ok && fresh {
+		return json.Unmarshal(stale, out)
+	}
+
+	data, err := fetch()
+	if err != nil {
+		if ok && IsUnavailable(err) {
+			log.Printf("shipping: serving stale %s: %v", key, err)
+			return json.Unmarshal(stale, out)
+		}
+		return err
+	}
+
+	if err := json.Unmarshal(data, out); err != nil {
+		return err
+	}
+	c.Cache.Set(key, data, ttl)
+
+	return nil
+}
+
+func (c *Client) get(path string) ([]byte, error) {
+	return c.do("GET", path, nil)
+}
+
+// do mengirim request dan mengembalikan field `data` dari respons. Error
+// jaringan dan 5xx dicoba ulang sampai MaxRetries kali; 429 langsung dikembalikan.
+func (c *Client) do(method string, path string, form url.Values) ([]byte, error) {
+	if c.BaseURL == "" {
+		return nil, &UnavailableError{Op: path, Err: fmt.Errorf("API_ONGKIR_BASE_URL is not set")}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= c.MaxRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(c.RetryWait * time.Duration(attempt))
+		}
+
+		data, retry, err := c.attempt(method, path, form)
+		if err == nil {
+			return data, nil
+		}
+		if _, ok := IsRateLimited(err); ok {
+			// jangan membanjiri API yang sedang membatasi request
+			return nil, &UnavailableError{Op: path, Err: err}
+		}
+		if !retry {
+			return nil, err
+		}
+		lastErr = err
+	}
+
+	return nil, &UnavailableError{Op: path, Err: lastErr}
+}
+
+func (c *Client) attempt(method string, path string, form url.Values) ([]byte, bool, error) {
+	var body io.Reader
+	if form != nil {
+		body = strings.NewReader(form.Encode())
+	}
+
+	req, err := http.NewRequest(method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Key", c.APIKey)
+	if form != nil {
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, true, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter := time.Minute
+		if v, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && v > 0 {
+			retryAfter = time.Duration(v) * time.Second
+		}
+		return nil, true, &RateLimitError{RetryAfter: retryAfter}
+	}
+	if resp.StatusCode >= 500 {
+		return nil, true, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	var parsed struct {
+		Meta models.Meta     `json:"meta"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &parsed); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return nil, false, &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		}
+		return nil, false, err
+	}
+
+	if resp.StatusCode != http.StatusOK || (parsed.Meta.Code != 0 && parsed.Meta.Code != http.StatusOK) {
+		code := parsed.Meta.Code
+		if code == 0 {
+			code = resp.StatusCode
+		}
+		return nil, false, &APIError{StatusCode: code, Message: parsed.Meta.Message}
+	}
+
+	if len(parsed.Data) == 0 || string(parsed.Data) == "null" {
+		return []byte("[]"), false, nil
+	}
+
+	return parsed.Data, false, nil
+}
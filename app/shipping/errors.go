@@ -0,0 +1,68 @@
+package shipping
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidParams dikembalikan sebelum request dikirim jika parameter tidak lengkap.
+var ErrInvalidParams = errors.New("shipping: invalid params")
+
+// APIError adalah penolakan dari RajaOngkir (meta.code bukan 200 atau status 4xx).
+// Mengulang request yang sama tidak akan mengubah hasilnya.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("shipping: API error %d: %s", e.StatusCode, e.Message)
+}
+
+// UnavailableError berarti RajaOngkir tidak bisa dihubungi (timeout, jaringan,
+// atau 5xx) setelah semua percobaan ulang.
+type UnavailableError struct {
+	Op  string
+	Err error
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("shipping: %s unavailable: %v", e.Op, e.Err)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return e.Err
+}
+
+// RateLimitError berarti API menjawab 429. RetryAfter diambil dari header
+// Retry-After jika ada.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("shipping: rate limited, retry after %s", e.RetryAfter)
+}
+
+// IsRateLimited mengembalikan RateLimitError jika err disebabkan 429.
+func IsRateLimited(err error) (*RateLimitError, bool) {
+	var rateLimited *RateLimitError
+	if errors.As(err, &rateLimited) {
+		return rateLimited, true
+	}
+
+	return nil, false
+}
+
+// IsUnavailable melaporkan apakah err disebabkan API sedang tidak bisa dihubungi.
+func IsUnavailable(err error) bool {
+	var unavailable *UnavailableError
+	return errors.As(err, &unavailable)
+}
+
+// IsNotFound melaporkan apakah API menjawab 404 untuk request tersebut.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == 404
+}
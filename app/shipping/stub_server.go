@@ -0,0 +1,119 @@
+package shipping
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StubServer meniru endpoint RajaOngkir yang dipakai Client (wilayah, ongkir dan
+// waybill) untuk development. Setiap resi maju satu tahap tiap StepInterval
+// sejak pertama kali dilacak, sampai terkirim. Resi berawalan "RTN" berakhir
+// dikembalikan ke pengirim.
+type StubServer struct {
+	StepInterval time.Duration
+
+	mu        sync.Mutex
+	firstSeen map[string]time.Time
+}
+
+func NewStubServer(stepInterval time.Duration) *StubServer {
+	return &StubServer{StepInterval: stepInterval, firstSeen: map[string]time.Time{}}
+}
+
+var stubManifest = []struct {
+	description string
+	city        string
+}{
+	{"SHIPMENT RECEIVED BY COUNTER OFFICER", "JAKARTA"},
+	{"PROCESSED AT SORTING CENTER", "JAKARTA"},
+	{"DEPARTED FROM TRANSIT", "BANDUNG"},
+	{"WITH DELIVERY COURIER", "BANDUNG"},
+	{"DELIVERED TO RECIPIENT", "BANDUNG"},
+}
+
+func (s *StubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+
+	switch {
+	case strings.HasSuffix(path, "track/waybill"):
+		s.waybill(w, r)
+	case strings.HasSuffix(path, "destination/province"):
+		writeStub(w, http.StatusOK, []map[string]interface{}{{"id": 6, "name": "DKI JAKARTA"}, {"id": 9, "name": "JAWA BARAT"}})
+	case strings.Contains(path, "destination/city/"):
+		writeStub(w, http.StatusOK, []map[string]interface{}{{"id": 23, "name": "BANDUNG", "zip_code": "40111"}, {"id": 152, "name": "JAKARTA PUSAT", "zip_code": "10110"}})
+	case strings.HasSuffix(path, "calculate/domestic-cost"):
+		r.ParseForm()
+		courier := r.FormValue("courier")
+		writeStub(w, http.StatusOK, []map[string]interface{}{
+			{"name": strings.ToUpper(courier), "code": courier, "service": "REG", "description": "Layanan Reguler", "cost": 18000, "etd": "2-3 day"},
+			{"name": strings.ToUpper(courier), "code": courier, "service": "YES", "description": "Yakin Esok Sampai", "cost": 32000, "etd": "1 day"},
+		})
+	default:
+		writeStub(w, http.StatusNotFound, nil)
+	}
+}
+
+func (s *StubServer) waybill(w http.ResponseWriter, r *http.Request) {
+	awb := r.URL.Query().Get("awb")
+	if awb == "" {
+		writeStub(w, http.StatusBadRequest, nil)
+		return
+	}
+
+	s.mu.Lock()
+	first, ok := s.firstSeen[awb]
+	if !ok {
+		first = time.Now()
+		s.firstSeen[awb] = first
+	}
+	s.mu.Unlock()
+
+	steps := 1
+	if s.StepInterval > 0 {
+		steps += int(time.Since(first) / s.StepInterval)
+	}
+	if steps > len(stubManifest) {
+		steps = len(stubManifest)
+	}
+
+	var manifest []map[string]string
+	for i := 0; i < steps; i++ {
+		step := stubManifest[i]
+		if strings.HasPrefix(awb, "RTN") && i == len(stubManifest)-1 {
+			step.description = "RETURNED TO SHIPPER"
+		}
+		at := first.Add(time.Duration(i) * s.StepInterval).In(jakarta)
+		manifest = append(manifest, map[string]string{
+			"manifest_code":        "S" + string(rune('0'+i)),
+			"manifest_description": step.description,
+			"manifest_date":        at.Format("2006-01-02"),
+			"manifest_time":        at.Format("15:04:05"),
+			"city_name":            step.city,
+		})
+	}
+
+	delivered := steps == len(stubManifest) && !strings.HasPrefix(awb, "RTN")
+	status := "ON PROCESS"
+	if delivered {
+		status = "DELIVERED"
+	}
+
+	writeStub(w, http.StatusOK, map[string]interface{}{
+		"delivered":       delivered,
+		"summary":         map[string]string{"waybill_number": awb, "status": status},
+		"delivery_status": map[string]string{"status": status},
+		"manifest":        manifest,
+	})
+}
+
+func writeStub(w http.ResponseWriter, code int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"meta": map[string]interface{}{"code": code, "status": http.StatusText(code), "message": http.StatusText(code)},
+		"data": data,
+	})
+}
@@ -0,0 +1,145 @@
+package shipping
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+)
+
+// TrackingEvent adalah satu baris manifest dari kurir. Status sudah dipetakan ke
+// consts.ShipmentStatus*.
+type TrackingEvent struct {
+	Status      string
+	Description string
+	Location    string
+	OccurredAt  time.Time
+}
+
+type TrackingResult struct {
+	Delivered bool
+	Status    string
+	Events    []TrackingEvent
+}
+
+// TrackingProvider mengambil status resi dari kurir. Client memakai endpoint
+// waybill RajaOngkir; untuk development arahkan API_ONGKIR_BASE_URL ke StubServer.
+type TrackingProvider interface {
+	Track(waybill string, courier string) (*TrackingResult, error)
+}
+
+type waybillResponse struct {
+	Delivered bool `json:"delivered"`
+	Summary   struct {
+		Status string `json:"status"`
+	} `json:"summary"`
+	DeliveryStatus struct {
+		Status  string `json:"status"`
+		PodDate string `json:"pod_date"`
+		PodTime string `json:"pod_time"`
+	} `json:"delivery_status"`
+	Manifest []struct {
+		ManifestCode        string `json:"manifest_code"`
+		ManifestDescription string `json:"manifest_description"`
+		ManifestDate        string `json:"manifest_date"`
+		ManifestTime        string `json:"manifest_time"`
+		CityName            string `json:"city_name"`
+	} `json:"manifest"`
+}
+
+// Track tidak di-cache: poller sendiri yang mengatur jadwal request.
+func (c *Client) Track(waybill string, courier string) (*TrackingResult, error) {
+	waybill = strings.TrimSpace(waybill)
+	courier = strings.ToLower(strings.TrimSpace(courier))
+	if waybill == "" || courier == "" {
+		return nil, ErrInvalidParams
+	}
+
+	query := url.Values{}
+	query.Add("awb", waybill)
+	query.Add("courier", courier)
+
+	data, err := c.do("POST", "track/waybill?"+query.Encode(), url.Values{})
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed waybillResponse
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+
+	result := &TrackingResult{Delivered: parsed.Delivered}
+	for _, manifest := range parsed.Manifest {
+		occurredAt, _ := time.ParseInLocation("2006-01-02 15:04:05", manifest.ManifestDate+" "+manifest.ManifestTime, jakarta)
+		result.Events = append(result.Events, TrackingEvent{
+			Status:      NormalizeTrackingStatus(manifest.ManifestDescription),
+			Description: manifest.ManifestDescription,
+			Location:    manifest.CityName,
+			OccurredAt:  occurredAt,
+		})
+	}
+
+	// manifest terakhir lebih spesifik daripada ringkasan ("ON PROCESS")
+	switch {
+	case result.Delivered:
+		result.Status = consts.ShipmentStatusDelivered
+	case len(result.Events) > 0:
+		result.Status = result.Events[len(result.Events)-1].Status
+	default:
+		result.Status = NormalizeTrackingStatus(parsed.DeliveryStatus.Status + " " + parsed.Summary.Status)
+	}
+
+	return result, nil
+}
+
+var jakarta = func() *time.Location {
+	if loc, err := time.LoadLocation("Asia/Jakarta"); err == nil {
+		return loc
+	}
+	return time.FixedZone("WIB", 7*60*60)
+}()
+
+// trackingRules dicek berurutan. Setiap frasa harus cocok sebagai kata utuh
+// sehingga "ANTAR" tidak cocok dengan "ANTARA". Frasa terkirim dibuat spesifik
+// karena status Delivered final: "diterima oleh kurir" atau "diterima di gudang"
+// adalah manifest pickup/hub, bukan paket sampai.
+var trackingRules = []struct {
+	status  string
+	phrases []string
+}{
+	{consts.ShipmentStatusReturned, []string{"RETUR", "RETURN", "RETURNED", "DIKEMBALIKAN KE PENGIRIM"}},
+	{consts.ShipmentStatusFailedDelivery, []string{"GAGAL", "FAILED", "UNDELIVERED", "NOT DELIVERED", "TIDAK BERHASIL DIANTAR"}},
+	{consts.ShipmentStatusDelivered, []string{
+		"DELIVERED", "DITERIMA OLEH PENERIMA", "DITERIMA PENERIMA", "DITERIMA OLEH YBS", "DITERIMA YBS",
+		"TERKIRIM KE PENERIMA", "TELAH SAMPAI DI PENERIMA",
+	}},
+	{consts.ShipmentStatusOutForDelivery, []string{"WITH DELIVERY COURIER", "OUT FOR DELIVERY", "DIANTAR", "SEDANG DIANTAR", "PROSES ANTAR", "DIBAWA KURIR"}},
+	{consts.ShipmentStatusPickedUp, []string{"PICKED UP", "PICKUP", "PICK UP", "DIJEMPUT", "SHIPMENT RECEIVED", "DITERIMA OLEH KURIR", "DITERIMA DI COUNTER"}},
+}
+
+// NormalizeTrackingStatus memetakan deskripsi manifest kurir (bahasa Indonesia
+// maupun Inggris) ke status shipment. Deskripsi yang tidak dikenali dianggap
+// masih dalam perjalanan.
+func NormalizeTrackingStatus(description string) string {
+	words := strings.FieldsFunc(strings.ToUpper(description), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	d := " " + strings.Join(words, " ") + " "
+
+	for _, rule := range trackingRules {
+		for _, phrase := range rule.phrases {
+			if strings.Contains(d, " "+phrase+" ") {
+				return rule.status
+			}
+		}
+	}
+
+	return consts.ShipmentStatusInTransit
+}
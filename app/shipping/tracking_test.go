@@ -0,0 +1,126 @@
+package shipping
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/codeuiprogramming/e-commerce/app/consts"
+)
+
+func TestNormalizeTrackingStatus(t *testing.T) {
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{"", ""},
+		{"SHIPMENT RECEIVED BY COUNTER OFFICER", consts.ShipmentStatusPickedUp},
+		{"Paket telah diterima oleh kurir", consts.ShipmentStatusPickedUp},
+		{"Paket diterima di gudang Jakarta", consts.ShipmentStatusInTransit},
+		{"PROCESSED AT SORTING CENTER", consts.ShipmentStatusInTransit},
+		{"DEPARTED FROM TRANSIT", consts.ShipmentStatusInTransit},
+		{"Pengiriman ANTAR KOTA", consts.ShipmentStatusInTransit},
+		{"Transit di antara hub", consts.ShipmentStatusInTransit},
+		{"WITH DELIVERY COURIER", consts.ShipmentStatusOutForDelivery},
+		{"Paket sedang diantar ke alamat tujuan", consts.ShipmentStatusOutForDelivery},
+		{"DELIVERED TO RECIPIENT", consts.ShipmentStatusDelivered},
+		{"Paket telah diterima oleh penerima (BUDI)", consts.ShipmentStatusDelivered},
+		{"UNDELIVERED - ALAMAT TIDAK DITEMUKAN", consts.ShipmentStatusFailedDelivery},
+		{"Pengantaran gagal, penerima tidak di tempat", consts.ShipmentStatusFailedDelivery},
+		{"RETURNED TO SHIPPER", consts.ShipmentStatusReturned},
+		{"Paket dikembalikan ke pengirim", consts.ShipmentStatusReturned},
+		{"ON PROCESS", consts.ShipmentStatusInTransit},
+	}
+
+	for _, tt := range tests {
+		if got := NormalizeTrackingStatus(tt.description); got != tt.want {
+			t.Errorf("NormalizeTrackingStatus(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
+
+func newStubClient(t *testing.T, stub *StubServer) *Client {
+	t.Helper()
+	server := httptest.NewServer(stub)
+	t.Cleanup(server.Close)
+
+	client := NewClient(server.URL+"/", "test-key")
+	client.MaxRetries = 0
+
+	return client
+}
+
+func TestTrackAgainstStubServer(t *testing.T) {
+	const step = time.Hour
+	tests := []struct {
+		waybill   string
+		elapsed   int
+		status    string
+		delivered bool
+		events    int
+	}{
+		{"JNE001", 0, consts.ShipmentStatusPickedUp, false, 1},
+		{"JNE002", 1, consts.ShipmentStatusInTransit, false, 2},
+		{"JNE003", 3, consts.ShipmentStatusOutForDelivery, false, 4},
+		{"JNE004", 4, consts.ShipmentStatusDelivered, true, 5},
+		{"JNE005", 10, consts.ShipmentStatusDelivered, true, 5},
+		{"RTN001", 4, consts.ShipmentStatusReturned, false, 5},
+	}
+
+	stub := NewStubServer(step)
+	client := newStubClient(t, stub)
+
+	for _, tt := range tests {
+		// mundurkan waktu pertama dilacak supaya resi sudah maju tt.elapsed tahap
+		stub.mu.Lock()
+		stub.firstSeen[tt.waybill] = time.Now().Add(-time.Duration(tt.elapsed)*step - time.Minute)
+		stub.mu.Unlock()
+
+		result, err := client.Track(tt.waybill, "jne")
+		if err != nil {
+			t.Fatalf("Track(%s): %v", tt.waybill, err)
+		}
+		if result.Status != tt.status || result.Delivered != tt.delivered || len(result.Events) != tt.events {
+			t.Errorf("Track(%s) = status %q delivered %v events %d, want %q %v %d",
+				tt.waybill, result.Status, result.Delivered, len(result.Events), tt.status, tt.delivered, tt.events)
+		}
+	}
+}
+
+func TestTrackPollsProgress(t *testing.T) {
+	stub := NewStubServer(50 * time.Millisecond)
+	client := newStubClient(t, stub)
+
+	first, err := client.Track("JNE100", "jne")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != consts.ShipmentStatusPickedUp {
+		t.Fatalf("first poll status = %q, want %q", first.Status, consts.ShipmentStatusPickedUp)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		result, err := client.Track("JNE100", "jne")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Delivered {
+			if result.Status != consts.ShipmentStatusDelivered {
+				t.Fatalf("delivered result has status %q", result.Status)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("waybill not delivered after polling, last status %q", result.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestTrackInvalidParams(t *testing.T) {
+	client := newStubClient(t, NewStubServer(0))
+	if _, err := client.Track(" ", "jne"); err != ErrInvalidParams {
+		t.Fatalf("Track with empty waybill error = %v, want ErrInvalidParams", err)
+	}
+}
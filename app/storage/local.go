@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Local menyimpan file di disk. File baru ditulis ke Root; Fallbacks ikut
+// dibaca dan dihapus karena upload lama tersebar di public/ dan assets/.
+type Local struct {
+	Root      string
+	Fallbacks []string
+	// BaseURL adalah prefix URL publik, dilayani static handler /public/
+	BaseURL string
+	// SignedBaseURL dilayani ServeHTTP untuk URL bertanda tangan
+	SignedBaseURL string
+	SigningKey    []byte
+}
+
+// NewLocal membuat storage disk dengan root dir (default "public").
+func NewLocal(root string) *Local {
+	if root == "" {
+		root = "public"
+	}
+	local := &Local{Root: root, BaseURL: "/public/", SignedBaseURL: "/storage/"}
+	for _, dir := range []string{"public", "assets"} {
+		if filepath.Clean(dir) != filepath.Clean(root) {
+			local.Fallbacks = append(local.Fallbacks, dir)
+		}
+	}
+
+	return local
+}
+
+// Path mengembalikan lokasi file di disk, dicari di Root lalu Fallbacks.
+func (l *Local) Path(key string) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	for _, dir := range append([]string{l.Root}, l.Fallbacks...) {
+		full := filepath.Join(dir, filepath.FromSlash(key))
+		if info, err := os.Stat(full); err == nil && !info.IsDir() {
+			return full, nil
+		}
+	}
+
+	return "", ErrNotFound
+}
+
+func (l *Local) Put(key string, body io.Reader, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	full := filepath.Join(l.Root, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		return err
+	}
+
+	// tulis ke file sementara dulu supaya pembaca tidak melihat file setengah jadi
+	tmp, err := os.CreateTemp(filepath.Dir(full), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	_ = os.Chmod(tmp.Name(), 0644)
+
+	return os.Rename(tmp.Name(), full)
+}
+
+func (l *Local) Get(key string) (io.ReadCloser, error) {
+	full, err := l.Path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return os.Open(full)
+}
+
+func (l *Local) Exists(key string) (bool, error) {
+	_, err := l.Path(key)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+func (l *Local) Delete(key string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	for _, dir := range append([]string{l.Root}, l.Fallbacks...) {
+		err := os.Remove(filepath.Join(dir, filepath.FromSlash(key)))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Local) URL(key string) string {
+	return l.BaseURL + KeyFromPath(key)
+}
+
+// SignedURL membuat URL /storage/{key}?expires=&signature= yang diperiksa
+// ServeHTTP dengan HMAC SigningKey.
+func (l *Local) SignedURL(key string, expires time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if len(l.SigningKey) == 0 {
+		return "", errors.New("storage: signing key is not configured")
+	}
+
+	expiresAt := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiresAt)
+	query.Set("signature", l.signature(key, expiresAt))
+
+	return l.SignedBaseURL + key + "?" + query.Encode(), nil
+}
+
+func (l *Local) signature(key string, expiresAt string) string {
+	mac := hmac.New(sha256.New, l.SigningKey)
+	mac.Write([]byte(key + "\n" + expiresAt))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServeHTTP melayani URL dari SignedURL; dipasang di SignedBaseURL.
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, l.SignedBaseURL)
+	expiresAt := r.URL.Query().Get("expires")
+	unix, err := strconv.ParseInt(expiresAt, 10, 64)
+	if err != nil || len(l.SigningKey) == 0 || time.Now().Unix() > unix ||
+		!hmac.Equal([]byte(l.signature(KeyFromPath(key), expiresAt)), []byte(r.URL.Query().Get("signature"))) {
+		http.Error(w, "invalid or expired link", http.StatusForbidden)
+		return
+	}
+
+	full, err := l.Path(key)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, full)
+}
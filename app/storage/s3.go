@@ -0,0 +1,214 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3 menyimpan file di bucket S3 atau layanan yang kompatibel (MinIO, R2,
+// Spaces) lewat REST API dengan tanda tangan SigV4.
+type S3 struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle memakai {endpoint}/{bucket}/{key}; false memakai
+	// {bucket}.{endpoint}/{key} seperti AWS
+	PathStyle bool
+	// PublicURL adalah prefix URL bucket/CDN publik; kosong berarti URL
+	// memakai presigned URL selama URLExpiry
+	PublicURL  string
+	URLExpiry  time.Duration
+	HTTPClient *http.Client
+}
+
+func NewS3(endpoint string, region string, bucket string, accessKey string, secretKey string) *S3 {
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint != "" && !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	return &S3{
+		Endpoint:   strings.TrimSuffix(endpoint, "/"),
+		Region:     region,
+		Bucket:     bucket,
+		AccessKey:  accessKey,
+		SecretKey:  secretKey,
+		PathStyle:  true,
+		URLExpiry:  time.Hour,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (s *S3) signer() signer {
+	return signer{AccessKey: s.AccessKey, SecretKey: s.SecretKey, Region: s.Region, Service: "s3"}
+}
+
+func (s *S3) objectURL(key string) (*url.URL, error) {
+	u, err := url.Parse(s.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	objectPath := "/" + key
+	if s.PathStyle {
+		objectPath = "/" + s.Bucket + "/" + key
+	} else {
+		u.Host = s.Bucket + "." + u.Host
+	}
+	u.Path = objectPath
+	u.RawPath = uriEncode(objectPath, false)
+
+	return u, nil
+}
+
+// s3Error adalah body error XML dari S3.
+type s3Error struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func responseError(resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var e s3Error
+	if xml.Unmarshal(body, &e) == nil && e.Code != "" {
+		return fmt.Errorf("storage: s3 %s %d: %s: %s", resp.Request.Method, resp.StatusCode, e.Code, e.Message)
+	}
+
+	return fmt.Errorf("storage: s3 %s %d", resp.Request.Method, resp.StatusCode)
+}
+
+func (s *S3) do(method string, key string, body []byte, contentType string) (*http.Response, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body == nil {
+		req.Body = nil
+		req.ContentLength = 0
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.signer().sign(req, hashHex(body), time.Now())
+
+	return s.HTTPClient.Do(req)
+}
+
+// Put mengunggah file. Isi dibaca ke memori untuk hash payload SigV4; upload
+// di aplikasi ini dibatasi beberapa MB.
+func (s *S3) Put(key string, body io.Reader, contentType string) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(http.MethodPut, key, data, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	return nil
+}
+
+func (s *S3) Get(key string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, nil
+	case http.StatusNotFound:
+		resp.Body.Close()
+		return nil, ErrNotFound
+	}
+	defer resp.Body.Close()
+
+	return nil, responseError(resp)
+}
+
+func (s *S3) Exists(key string) (bool, error) {
+	resp, err := s.do(http.MethodHead, key, nil, "")
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+
+	return false, responseError(resp)
+}
+
+// Delete menghapus file; S3 juga mengembalikan sukses untuk key yang tidak ada.
+func (s *S3) Delete(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return responseError(resp)
+	}
+
+	return nil
+}
+
+func (s *S3) URL(key string) string {
+	key = KeyFromPath(key)
+	if s.PublicURL != "" {
+		return strings.TrimSuffix(s.PublicURL, "/") + "/" + uriEncode(key, false)
+	}
+	signed, err := s.SignedURL(key, s.URLExpiry)
+	if err != nil {
+		return ""
+	}
+
+	return signed
+}
+
+// SignedURL membuat presigned GET URL; S3 membatasi masa berlaku maksimal 7 hari.
+func (s *S3) SignedURL(key string, expires time.Duration) (string, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return "", err
+	}
+	if expires <= 0 || expires > 7*24*time.Hour {
+		return "", fmt.Errorf("storage: expiry must be between 1s and 7 days")
+	}
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	s.signer().presign(req, expires, time.Now())
+
+	return req.URL.String(), nil
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	unsignedPayload = "UNSIGNED-PAYLOAD"
+	// selisih jam maksimal antara client dan server, sama seperti S3
+	sigV4MaxSkew = 15 * time.Minute
+)
+
+var (
+	ErrSignatureMismatch = errors.New("storage: signature does not match")
+	ErrRequestExpired    = errors.New("storage: request has expired")
+)
+
+// signer menandatangani request S3 dengan AWS Signature Version 4, lihat
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
+type signer struct {
+	AccessKey string
+	SecretKey string
+	Region    string
+	Service   string
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// uriEncode meng-encode sesuai aturan SigV4: semua kecuali A-Z a-z 0-9 - _ . ~,
+// dan "/" hanya dibiarkan untuk path.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+
+	return b.String()
+}
+
+func (s signer) scope(t time.Time) string {
+	return t.Format("20060102") + "/" + s.Region + "/" + s.Service + "/aws4_request"
+}
+
+func (s signer) signature(t time.Time, canonicalRequest string) string {
+	stringToSign := sigV4Algorithm + "\n" + t.Format(sigV4TimeFormat) + "\n" + s.scope(t) + "\n" + hashHex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.SecretKey), t.Format("20060102"))
+	key = hmacSHA256(key, s.Region)
+	key = hmacSHA256(key, s.Service)
+	key = hmacSHA256(key, "aws4_request")
+
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalRequest menyusun request kanonik. header berisi nilai header yang
+// ditandatangani (nama huruf kecil); X-Amz-Signature di query tidak ikut.
+func canonicalRequest(method string, escapedPath string, query url.Values, header func(string) string, signedHeaders []string, payloadHash string) string {
+	if escapedPath == "" {
+		escapedPath = "/"
+	}
+
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		if key != "X-Amz-Signature" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var params []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			params = append(params, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	var headers strings.Builder
+	for _, name := range signedHeaders {
+		headers.WriteString(name + ":" + strings.Join(strings.Fields(header(name)), " ") + "\n")
+	}
+
+	return strings.Join([]string{
+		method,
+		escapedPath,
+		strings.Join(params, "&"),
+		headers.String(),
+		strings.Join(signedHeaders, ";"),
+		payloadHash,
+	}, "\n")
+}
+
+// requestHeader membaca header request termasuk Host, yang di net/http tidak
+// ada di req.Header.
+func requestHeader(req *http.Request) func(string) string {
+	return func(name string) string {
+		if name == "host" {
+			if req.Host != "" {
+				return req.Host
+			}
+			return req.URL.Host
+		}
+		return strings.Join(req.Header.Values(name), ",")
+	}
+}
+
+// sign menambahkan header x-amz-date, x-amz-content-sha256 dan Authorization.
+// Host dan semua header yang sudah di-set ikut ditandatangani.
+func (s signer) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(sigV4TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := []string{"host"}
+	for name := range req.Header {
+		name = strings.ToLower(name)
+		if name != "authorization" && name != "user-agent" {
+			signedHeaders = append(signedHeaders, name)
+		}
+	}
+	sort.Strings(signedHeaders)
+
+	canonical := canonicalRequest(req.Method, req.URL.EscapedPath(), req.URL.Query(), requestHeader(req), signedHeaders, payloadHash)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, s.AccessKey, s.scope(now), strings.Join(signedHeaders, ";"), s.signature(now, canonical)))
+}
+
+// presign menambahkan parameter tanda tangan ke query URL (presigned URL).
+func (s signer) presign(req *http.Request, expires time.Duration, now time.Time) {
+	now = now.UTC()
+	query := req.URL.Query()
+	query.Set("X-Amz-Algorithm", sigV4Algorithm)
+	query.Set("X-Amz-Credential", s.AccessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format(sigV4TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonical := canonicalRequest(req.Method, req.URL.EscapedPath(), query, requestHeader(req), []string{"host"}, unsignedPayload)
+	query.Set("X-Amz-Signature", s.signature(now, canonical))
+	req.URL.RawQuery = strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// verify memeriksa tanda tangan request yang diterima, baik lewat header
+// Authorization maupun presigned URL. Dipakai StubServer.
+func (s signer) verify(req *http.Request, now time.Time) error {
+	query := req.URL.Query()
+
+	var credential, signedHeaders, signature, date, payloadHash string
+	var expires time.Duration
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		if !strings.HasPrefix(auth, sigV4Algorithm+" ") {
+			return ErrSignatureMismatch
+		}
+		for _, part := range strings.Split(strings.TrimPrefix(auth, sigV4Algorithm+" "), ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch name {
+			case "Credential":
+				credential = value
+			case "SignedHeaders":
+				signedHeaders = value
+			case "Signature":
+				signature = value
+			}
+		}
+		date = req.Header.Get("X-Amz-Date")
+		payloadHash = req.Header.Get("X-Amz-Content-Sha256")
+		expires = sigV4MaxSkew
+	} else if query.Get("X-Amz-Algorithm") == sigV4Algorithm {
+		credential = query.Get("X-Amz-Credential")
+		signedHeaders = query.Get("X-Amz-SignedHeaders")
+		signature = query.Get("X-Amz-Signature")
+		date = query.Get("X-Amz-Date")
+		payloadHash = unsignedPayload
+		seconds, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+		if err != nil || seconds <= 0 {
+			return ErrSignatureMismatch
+		}
+		expires = time.Duration(seconds) * time.Second
+	} else {
+		return ErrSignatureMismatch
+	}
+
+	signedAt, err := time.Parse(sigV4TimeFormat, date)
+	if err != nil {
+		return ErrSignatureMismatch
+	}
+	if now.Before(signedAt.Add(-sigV4MaxSkew)) || now.After(signedAt.Add(expires)) {
+		return ErrRequestExpired
+	}
+	if credential != s.AccessKey+"/"+s.scope(signedAt) || signedHeaders == "" || payloadHash == "" {
+		return ErrSignatureMismatch
+	}
+
+	canonical := canonicalRequest(req.Method, req.URL.EscapedPath(), query, requestHeader(req), strings.Split(signedHeaders, ";"), payloadHash)
+	if !hmac.Equal([]byte(s.signature(signedAt, canonical)), []byte(signature)) {
+		return ErrSignatureMismatch
+	}
+
+	return nil
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound   = errors.New("storage: file not found")
+	ErrInvalidKey = errors.New("storage: invalid key")
+)
+
+// Storage menyimpan file upload. Key adalah path relatif dengan "/" seperti
+// "uploads/images/ab/abcd.jpg"; path yang tersimpan di database (boleh
+// berawalan "/" atau "/public/") diubah ke key dengan KeyFromPath.
+type Storage interface {
+	Put(key string, body io.Reader, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Exists(key string) (bool, error)
+	Delete(key string) error
+	// URL adalah alamat file untuk halaman publik
+	URL(key string) string
+	// SignedURL adalah alamat sementara yang hanya berlaku selama expires
+	SignedURL(key string, expires time.Duration) (string, error)
+}
+
+// KeyFromPath mengubah path publik ("/public/uploads/x.jpg", "/uploads/x.jpg")
+// menjadi key storage ("uploads/x.jpg").
+func KeyFromPath(p string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(p, "/"), "public/")
+}
+
+// IsUploadKey menandai file upload; file tema seperti img/... tetap dibaca dari
+// disk dan tidak pernah dipindah atau dihapus lewat storage.
+func IsUploadKey(key string) bool {
+	return strings.HasPrefix(KeyFromPath(key), "uploads/")
+}
+
+func cleanKey(key string) (string, error) {
+	key = KeyFromPath(key)
+	if key == "" || strings.HasSuffix(key, "/") || path.Clean(key) != key || strings.HasPrefix(key, "../") {
+		return "", ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// ReadAll membaca seluruh isi file.
+func ReadAll(s Storage, key string) ([]byte, error) {
+	body, err := s.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+// NewFromEnv memilih backend dari STORAGE_DRIVER:
+//   - local (default): STORAGE_LOCAL_DIR (default public), kunci URL bertanda
+//     tangan STORAGE_SIGNING_KEY (default SESSION_KEY)
+//   - s3: STORAGE_S3_ENDPOINT, STORAGE_S3_REGION, STORAGE_S3_BUCKET,
+//     STORAGE_S3_ACCESS_KEY, STORAGE_S3_SECRET_KEY, opsional
+//     STORAGE_S3_PUBLIC_URL (bucket/CDN publik), STORAGE_S3_PATH_STYLE
+//     (default true) dan STORAGE_URL_TTL (menit, default 60)
+func NewFromEnv() (Storage, error) {
+	switch driver := strings.ToLower(os.Getenv("STORAGE_DRIVER")); driver {
+	case "", "local":
+		local := NewLocal(os.Getenv("STORAGE_LOCAL_DIR"))
+		local.SigningKey = []byte(os.Getenv("STORAGE_SIGNING_KEY"))
+		if len(local.SigningKey) == 0 {
+			local.SigningKey = []byte(os.Getenv("SESSION_KEY"))
+		}
+		return local, nil
+	case "s3":
+		s3 := NewS3(
+			os.Getenv("STORAGE_S3_ENDPOINT"),
+			os.Getenv("STORAGE_S3_REGION"),
+			os.Getenv("STORAGE_S3_BUCKET"),
+			os.Getenv("STORAGE_S3_ACCESS_KEY"),
+			os.Getenv("STORAGE_S3_SECRET_KEY"),
+		)
+		s3.PublicURL = os.Getenv("STORAGE_S3_PUBLIC_URL")
+		if v := os.Getenv("STORAGE_S3_PATH_STYLE"); v != "" {
+			s3.PathStyle = v == "true" || v == "1"
+		}
+		if v, err := strconv.Atoi(os.Getenv("STORAGE_URL_TTL")); err == nil && v > 0 {
+			s3.URLExpiry = time.Duration(v) * time.Minute
+		}
+		if s3.Endpoint == "" || s3.Bucket == "" || s3.AccessKey == "" || s3.SecretKey == "" {
+			return nil, errors.New("storage: STORAGE_S3_ENDPOINT, STORAGE_S3_BUCKET, STORAGE_S3_ACCESS_KEY and STORAGE_S3_SECRET_KEY are required")
+		}
+		return s3, nil
+	default:
+		return nil, fmt.Errorf("storage: unknown STORAGE_DRIVER %q", driver)
+	}
+}
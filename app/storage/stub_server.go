@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// StubServer meniru bagian API S3 yang dipakai backend S3 (PUT/GET/HEAD/DELETE
+// object, path-style /{bucket}/{key}) termasuk pemeriksaan SigV4 dan presigned
+// URL, seperti MinIO lokal untuk development. Object disimpan sebagai file di
+// Dir/{bucket}/{key}; bucket dibuat otomatis.
+type StubServer struct {
+	Dir    string
+	signer signer
+}
+
+func NewStubServer(dir string, region string, accessKey string, secretKey string) *StubServer {
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	return &StubServer{
+		Dir:    dir,
+		signer: signer{AccessKey: accessKey, SecretKey: secretKey, Region: region, Service: "s3"},
+	}
+}
+
+func writeStubError(w http.ResponseWriter, status int, code string, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_ = xml.NewEncoder(w).Encode(s3Error{Code: code, Message: message})
+}
+
+func (s *StubServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := s.signer.verify(r, time.Now().UTC()); err != nil {
+		code := "SignatureDoesNotMatch"
+		if errors.Is(err, ErrRequestExpired) {
+			code = "AccessDenied"
+		}
+		writeStubError(w, http.StatusForbidden, code, err.Error())
+		return
+	}
+
+	bucket, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	key, err := cleanKey(key)
+	if bucket == "" || strings.Contains(bucket, "..") || err != nil {
+		writeStubError(w, http.StatusBadRequest, "InvalidArgument", "path must be /{bucket}/{key}")
+		return
+	}
+	full := filepath.Join(s.Dir, bucket, filepath.FromSlash(key))
+
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			writeStubError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		// payload harus sama dengan hash yang ditandatangani
+		if hash := r.Header.Get("X-Amz-Content-Sha256"); hash != unsignedPayload && hash != hashHex(data) {
+			writeStubError(w, http.StatusBadRequest, "XAmzContentSHA256Mismatch", "payload hash does not match")
+			return
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			writeStubError(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		if err := os.WriteFile(full, data, 0644); err != nil {
+			writeStubError(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		w.Header().Set("ETag", `"`+hashHex(data)[:32]+`"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		f, err := os.Open(full)
+		if err != nil {
+			writeStubError(w, http.StatusNotFound, "NoSuchKey", "the specified key does not exist")
+			return
+		}
+		defer f.Close()
+		info, err := f.Stat()
+		if err != nil || info.IsDir() {
+			writeStubError(w, http.StatusNotFound, "NoSuchKey", "the specified key does not exist")
+			return
+		}
+		if contentType := mime.TypeByExtension(filepath.Ext(full)); contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		http.ServeContent(w, r, "", info.ModTime(), f)
+	case http.MethodDelete:
+		if err := os.Remove(full); err != nil && !os.IsNotExist(err) {
+			writeStubError(w, http.StatusInternalServerError, "InternalError", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeStubError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", "only object GET, HEAD, PUT and DELETE are supported")
+	}
+}
@@ -37,6 +37,12 @@ $(function () {
   // Panggil saat halaman load
   formatAllPricesOnLoad();
 
+  // Tampilkan pesan dari server (mis. RajaOngkir sedang tidak tersedia)
+  function showShippingMessage(xhr, fallback) {
+    let message = (xhr.responseJSON && xhr.responseJSON.message) || fallback;
+    $("#shipping-calculation-msg").html(`<div class="alert alert-warning">${message}</div>`);
+  }
+
   // ================== PROVINSI -> KOTA ====================
   $(".province_id").change(function () {
     let provinceID = $(this).val();
@@ -52,16 +58,23 @@ $(function () {
         });
         $(".city_id").prop("disabled", false);
       },
-      error: function () {
+      error: function (xhr) {
         console.error("Gagal mengambil data kota");
+        showShippingMessage(xhr, "Gagal mengambil data kota");
       },
     });
   });
 
   // ================== KOTA -> ONGKIR ====================
-  $(".city_id").change(function () {
-    let cityID = $(this).val();
+  // courier "all" membandingkan semua kurir aktif sekaligus
+  function calculateShipping() {
+    let cityID = $(".city_id").val();
     let courier = $(".courier").val();
+    let sort = $(".shipping_sort").val();
+    if (!cityID) return;
+
+    $("#shipping-calculation-msg").empty();
+    $(".selected_courier").val("");
 
     $.ajax({
       url: "/carts/calculate-shipping",
@@ -69,35 +82,52 @@ $(function () {
       contentType: "application/json",
       data: JSON.stringify({
         city_id: cityID,
+        province_id: $(".province_id").val(),
         courier: courier,
+        sort: sort,
       }),
       success: function (result) {
         $(".shipping_fee_options").empty().append('<option value="">Pilih Paket</option>');
+        $(".shipping_origin").text(result.origin && result.origin.name ? `Dikirim dari ${result.origin.name}` : "");
 
         if (result.data && result.data.length > 0) {
           $.each(result.data, function (i, shipping_fee_option) {
+            let etd = shipping_fee_option.etd ? ` (${shipping_fee_option.etd})` : "";
             $(".shipping_fee_options").append(
-              `<option value="${shipping_fee_option.service}-${shipping_fee_option.fee}">
-                ${shipping_fee_option.service} - ${formatRupiah(shipping_fee_option.fee)}
+              `<option value="${shipping_fee_option.service}-${shipping_fee_option.fee}" data-courier="${shipping_fee_option.courier}">
+                ${shipping_fee_option.courier.toUpperCase()} ${shipping_fee_option.service} - ${formatRupiah(shipping_fee_option.fee)}${etd}
               </option>`,
             );
           });
         } else {
           $(".shipping_fee_options").append('<option value="">Tidak ada paket tersedia</option>');
         }
+
+        // kurir yang gagal tidak menggagalkan kurir lain, cukup diberi tahu
+        if (result.errors && Object.keys(result.errors).length > 0) {
+          let failed = $.map(result.errors, function (msg, code) {
+            return `${code.toUpperCase()}: ${msg}`;
+          });
+          $("#shipping-calculation-msg").html(`<div class="alert alert-warning">${failed.join("<br />")}</div>`);
+        }
       },
       error: function (xhr) {
         console.error("AJAX Error:", xhr.responseText);
+        showShippingMessage(xhr, "Perhitungan ongkir gagal!");
       },
     });
-  });
+  }
+
+  $(".city_id").change(calculateShipping);
+  $(".courier, .shipping_sort").change(calculateShipping);
 
   // ================== APPLY ONGKIR & UPDATE TOTAL ====================
   $(".shipping_fee_options").change(function () {
     let cityID = $(".city_id").val();
-    let courier = $(".courier").val();
+    let courier = $(this).find(":selected").data("courier") || $(".courier").val();
     let shippingFeeData = $(this).val();
     if (!shippingFeeData) return;
+    $(".selected_courier").val(courier);
 
     let shippingPackage = shippingFeeData.split("-")[0];
     let shippingFee = parseInt(shippingFeeData.split("-")[1]);
@@ -109,6 +139,7 @@ $(function () {
       data: JSON.stringify({
         shipping_package: shippingPackage,
         city_id: cityID,
+        province_id: $(".province_id").val(),
         courier: courier,
       }),
       success: function (result) {
@@ -125,8 +156,8 @@ $(function () {
         $("#cart-tax").text(formatRupiah(tax));
         $("#grand-total").text(formatRupiah(grandTotal));
       },
-      error: function () {
-        $("#shipping-calculation-msg").html(`<div class="alert alert-warning">Pemilihan paket ongkir gagal!</div>`);
+      error: function (xhr) {
+        showShippingMessage(xhr, "Pemilihan paket ongkir gagal!");
       },
     });
   });